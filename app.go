@@ -6,17 +6,21 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/http/httptrace"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -25,6 +29,31 @@ type App struct {
 	ctx         context.Context
 	storagePath string
 	configPath  string
+
+	envMu sync.Mutex // guards the HTTP environments file
+
+	sessionMu sync.Mutex // guards sessions
+	sessions  map[string]*httpSession
+
+	cookieMu sync.Mutex // guards cookies and the cookies file
+	cookies  map[string][]HTTPCookie
+
+	oauth2Mu     sync.Mutex // guards OAuth2 tokens, their file and oauth2Flows
+	oauth2Tokens map[string]map[string]OAuth2Token
+	oauth2Flows  map[string]chan struct{} // one slot per cached token
+
+	historyMu sync.Mutex // guards history and the history folder
+	history   []HTTPHistorySummary
+
+	transportMu sync.Mutex // guards transports
+	transports  map[string]cachedTransport
+
+	inFlightMu sync.Mutex // guards inFlight and streams
+	inFlight   map[string]context.CancelFunc
+	streams    map[string]context.CancelFunc
+
+	webSocketMu sync.Mutex // guards webSockets
+	webSockets  map[string]*webSocketConn
 }
 
 // AppConfig stores user preferences
@@ -36,6 +65,9 @@ type AppConfig struct {
 	EditorFontSize   int    `json:"editorFontSize,omitempty"`
 	EditorFontFamily string `json:"editorFontFamily,omitempty"`
 	KeepLongestJson  bool   `json:"keepLongestJson,omitempty"`
+
+	HTTPSettings HTTPTransportSettings `json:"httpSettings,omitempty"`
+	HTTPHistory  HTTPHistorySettings   `json:"httpHistory,omitempty"`
 }
 
 // NewApp creates a new App application struct
@@ -95,7 +127,7 @@ func (a *App) saveConfig(config AppConfig) error {
 
 // initStorageDirectories creates the storage directory structure
 func (a *App) initStorageDirectories() {
-	dirs := []string{"json", "xml", "base64", "http"}
+	dirs := []string{"json", "xml", "base64", "http", "websocket"}
 	for _, dir := range dirs {
 		os.MkdirAll(filepath.Join(a.storagePath, dir), 0755)
 	}
@@ -200,6 +232,15 @@ func (a *App) ShowMessageDialog(title string, message string) (string, error) {
 	})
 }
 
+// emitEvent sends an event to the frontend. It is a no-op until the Wails
+// runtime has started, so background work can't crash the app.
+func (a *App) emitEvent(name string, data interface{}) {
+	if a.ctx == nil || a.ctx.Value("events") == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, name, data)
+}
+
 // ========== JSON Tools ==========
 
 type JSONFormatRequest struct {
@@ -468,24 +509,146 @@ func isValidUTF8(data []byte) bool {
 
 // ========== HTTP Tools ==========
 
+// maxBodySize limits the response body kept in memory to 10MB; larger
+// bodies are truncated and flagged
+const maxBodySize = 10 * 1024 * 1024
+
 type HTTPRequest struct {
 	Method  string            `json:"method"`
 	URL     string            `json:"url"`
 	Headers map[string]string `json:"headers"`
 	Body    string            `json:"body"`
+	// StructuredBody replaces Body with a JSON, form or multipart body
+	StructuredBody *HTTPBody `json:"structuredBody,omitempty"`
+	// Auth authenticates or signs the request as it is sent
+	Auth *HTTPAuth `json:"auth,omitempty"`
+	// Environment selects the variables for {{name}} placeholders; empty
+	// means the active environment
+	Environment string `json:"environment,omitempty"`
+	// Name lets later requests in the session reference this one, e.g.
+	// {{login.response.body.$.token}}
+	Name string `json:"name,omitempty"`
+	// Session groups requests sharing captured variables; empty means
+	// the default session
+	Session    string          `json:"session,omitempty"`
+	Captures   []HTTPCapture   `json:"captures,omitempty"`
+	Assertions []HTTPAssertion `json:"assertions,omitempty"`
+	// Settings overrides the global transport settings for this request
+	Settings *HTTPTransportSettings `json:"settings,omitempty"`
+	// ID lets the caller cancel the request with CancelHTTPRequest and
+	// match status events; one is generated if empty
+	ID string `json:"id,omitempty"`
+	// SaveTo streams the response body to this file in storage instead of
+	// returning it; an existing file is not overwritten
+	SaveTo string `json:"saveTo,omitempty"`
+	// Stream pushes the body to the frontend as it arrives, parsing
+	// server-sent events and reconnecting when the server closes them,
+	// until StopHTTPStream; the read timeout only applies to the headers
+	Stream bool `json:"stream,omitempty"`
+	// LastEventID resumes an event stream after the given event
+	LastEventID string `json:"lastEventId,omitempty"`
 }
 
 type HTTPResponse struct {
+	// ID identifies the request for CancelHTTPRequest and status events
+	ID         string            `json:"id,omitempty"`
 	Status     string            `json:"status"`
 	StatusCode int               `json:"statusCode"`
 	Headers    map[string]string `json:"headers"`
 	Body       string            `json:"body"`
 	Error      string            `json:"error"`
 	Duration   int64             `json:"duration"` // Duration in milliseconds
+	Cancelled  bool              `json:"cancelled,omitempty"`
+	// HeaderList keeps repeated headers such as Set-Cookie apart, sorted
+	// by name with values in received order
+	HeaderList []HTTPHeader `json:"headerList"`
+	// Binary bodies are returned in BodyBase64 instead of Body
+	Binary     bool   `json:"binary,omitempty"`
+	BodyBase64 string `json:"bodyBase64,omitempty"`
+	Size       int64  `json:"size"` // body bytes after decompression
+	Truncated  bool   `json:"truncated,omitempty"`
+	// ContentEncoding is the coding the body was decompressed from, and
+	// CompressedSize the bytes received on the wire
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+	CompressedSize  int64  `json:"compressedSize"`
+	// Charset is the declared charset the body was transcoded from
+	Charset   string `json:"charset,omitempty"`
+	SavedPath string `json:"savedPath,omitempty"`
+	// Redirects lists the hops followed before the final response
+	Redirects []HTTPRedirect `json:"redirects,omitempty"`
+	Timing    *HTTPTiming    `json:"timing,omitempty"`
+	// Captured holds the values extracted by the request's captures
+	Captured      map[string]string `json:"captured,omitempty"`
+	CaptureErrors []string          `json:"captureErrors,omitempty"`
+	// Passed and Failed hold the results of the request's assertions
+	Passed []HTTPAssertionResult `json:"passed,omitempty"`
+	Failed []HTTPAssertionResult `json:"failed,omitempty"`
+	// Stopped is set when StopHTTPStream ended a stream. Reconnects counts
+	// the times an event stream was resumed, from LastEventID.
+	Stopped     bool   `json:"stopped,omitempty"`
+	Reconnects  int    `json:"reconnects,omitempty"`
+	LastEventID string `json:"lastEventId,omitempty"`
 }
 
-// SendHTTPRequest sends HTTP request and returns response
+// SendHTTPRequest resolves variables, sends HTTP request and returns response.
+// Captured values are stored in the request's session for later requests,
+// and relative schema paths in assertions resolve against the http folder.
 func (a *App) SendHTTPRequest(request HTTPRequest) HTTPResponse {
+	resolver, err := a.newVariableResolver(request.Environment, "")
+	if err != nil {
+		return HTTPResponse{ID: request.ID, Error: fmt.Sprintf("Environment error: %v", err)}
+	}
+	resolver.session = a.session(request.Session)
+	resolved, err := resolver.resolveRequest(request)
+	if err != nil {
+		return HTTPResponse{ID: request.ID, Error: fmt.Sprintf("Variable error: %v", err)}
+	}
+
+	if resolved, err = a.resolveUploadPaths(resolved); err != nil {
+		return HTTPResponse{ID: request.ID, Error: err.Error()}
+	}
+
+	response := a.executeHTTPRequest(resolved)
+	applyCaptures(request.Captures, &response)
+	a.evaluateAssertions(resolved.Assertions, &response, filepath.Join(a.storagePath, "http"))
+	resolver.session.record(request.Name, resolved, response)
+	a.recordHistory(HTTPHistoryEntry{
+		HTTPHistorySummary: HTTPHistorySummary{Source: "request", Name: request.Name, Environment: resolver.environment},
+		Request:            resolved,
+		Original:           &request,
+		Response:           response,
+	})
+	return response
+}
+
+// executeHTTPRequest sends a fully resolved request under a cancellable
+// context registered by its ID, emitting status events as it runs
+func (a *App) executeHTTPRequest(request HTTPRequest) HTTPResponse {
+	if request.ID == "" {
+		request.ID = uuid.NewString()
+	}
+	ctx, done, err := a.beginHTTPRequest(request.ID)
+	if err != nil {
+		return HTTPResponse{ID: request.ID, Error: err.Error()}
+	}
+	defer done()
+
+	reporter := a.newHTTPStatusReporter(request)
+	reporter.emit("started")
+	var response HTTPResponse
+	if request.Stream {
+		response = a.streamHTTPRequest(ctx, request, reporter)
+	} else {
+		response = a.doHTTPRequest(ctx, request, reporter, nil)
+	}
+	response.ID = request.ID
+	reporter.finish(response)
+	return response
+}
+
+// doHTTPRequest performs the exchange for executeHTTPRequest. With a
+// stream, the body is passed to it as it arrives.
+func (a *App) doHTTPRequest(ctx context.Context, request HTTPRequest, reporter *httpStatusReporter, stream *httpStream) HTTPResponse {
 	// Record start time
 	startTime := time.Now()
 
@@ -497,13 +660,40 @@ func (a *App) SendHTTPRequest(request HTTPRequest) HTTPResponse {
 		}
 	}
 
-	// Create HTTP client with timeout
+	// Create HTTP client from the transport settings, with the
+	// environment's cookie jar
+	settings := a.loadConfig().HTTPSettings.merge(request.Settings)
+	transport, err := a.transport(settings)
+	if err != nil {
+		return HTTPResponse{
+			Error:    fmt.Sprintf("Invalid transport settings: %v", err),
+			Duration: 0,
+		}
+	}
+	var redirects []HTTPRedirect
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Transport:     transport,
+		Jar:           a.cookieJar(request.Environment),
+		CheckRedirect: settings.checkRedirect(&redirects),
+	}
+
+	// Prepare the body; multipart files are streamed as it is sent
+	request, err = flattenBody(request)
+	if err != nil {
+		return HTTPResponse{Error: fmt.Sprintf("Invalid body: %v", err)}
+	}
+	payload, err := newRequestPayload(request)
+	if err != nil {
+		return HTTPResponse{Error: fmt.Sprintf("Invalid body: %v", err)}
 	}
 
+	// The read timeout bounds each wait for the server rather than the
+	// whole exchange, so long downloads are not cut off
+	requestCtx, deadline := withReadDeadline(ctx, settings.readTimeout())
+	defer deadline.release()
+
 	// Create request
-	req, err := http.NewRequest(request.Method, request.URL, strings.NewReader(request.Body))
+	req, err := http.NewRequestWithContext(requestCtx, request.Method, request.URL, nil)
 	if err != nil {
 		duration := time.Since(startTime).Milliseconds()
 		return HTTPResponse{
@@ -511,78 +701,151 @@ func (a *App) SendHTTPRequest(request HTTPRequest) HTTPResponse {
 			Duration: duration,
 		}
 	}
+	if settings.forcesHTTP2() && req.URL.Scheme != "https" {
+		return HTTPResponse{Error: "HTTP/2 needs an https:// URL: cleartext HTTP/2 (h2c) is not supported"}
+	}
 
 	// Add headers
 	for key, value := range request.Headers {
 		req.Header.Set(key, value)
 	}
+	if err := payload.attach(req); err != nil {
+		return HTTPResponse{Error: fmt.Sprintf("Invalid body: %v", err)}
+	}
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	// OAuth 2.0 tokens come from the cache or a new flow, through the
+	// same transport but without the request's cookies or redirect policy
+	tokenClient := &http.Client{Transport: transport, Timeout: settings.readTimeout()}
+	if err := a.authorizeOAuth2(ctx, req, request, tokenClient); err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return HTTPResponse{Error: "Request cancelled", Cancelled: true, Duration: time.Since(startTime).Milliseconds()}
+		}
+		return HTTPResponse{Error: fmt.Sprintf("OAuth2 error: %v", err)}
+	}
 
-	// Send request
+	// Sign last, once every header is in place
+	if err := applyAuth(req, request.Auth, payload); err != nil {
+		return HTTPResponse{Error: fmt.Sprintf("Auth error: %v", err)}
+	}
+
+	// Trace connection phases for the timing breakdown
+	trace := newRequestTrace(startTime)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+
+	// Send request, answering a Digest challenge if there is one
+	deadline.start()
 	resp, err := client.Do(req)
-	duration := time.Since(startTime).Milliseconds()
+	if err == nil {
+		resp, err = answerAuthChallenge(client, req, resp, request.Auth, payload)
+	}
 
 	if err != nil {
+		duration := time.Since(startTime).Milliseconds()
+
 		// Provide more specific error messages
 		errMsg := err.Error()
-		if strings.Contains(errMsg, "timeout") {
-			return HTTPResponse{
-				Error:    "Request timeout: server did not respond within 30 seconds",
-				Duration: duration,
-			}
-		}
-		if strings.Contains(errMsg, "connection refused") {
-			return HTTPResponse{
-				Error:    fmt.Sprintf("Connection refused: unable to connect to %s", request.URL),
-				Duration: duration,
-			}
-		}
-		if strings.Contains(errMsg, "no such host") {
+		message := fmt.Sprintf("Request failed: %v", err)
+		switch {
+		case errors.Is(context.Cause(requestCtx), errReadTimeout):
+			message = fmt.Sprintf("Request timeout: server did not respond within %v", settings.readTimeout())
+		case errors.Is(err, context.Canceled):
 			return HTTPResponse{
-				Error:    fmt.Sprintf("DNS error: host not found for %s", request.URL),
-				Duration: duration,
+				Error:     "Request cancelled",
+				Cancelled: true,
+				Duration:  duration,
+				Redirects: redirects,
+				Timing:    trace.timing(time.Now(), nil),
 			}
+		case strings.Contains(strings.ToLower(errMsg), "timeout"):
+			message = fmt.Sprintf("Request timeout: server did not respond within %v", settings.readTimeout())
+		case strings.Contains(errMsg, "connection refused"):
+			message = fmt.Sprintf("Connection refused: unable to connect to %s", request.URL)
+		case strings.Contains(errMsg, "no such host"):
+			message = fmt.Sprintf("DNS error: host not found for %s", request.URL)
+		case strings.Contains(errMsg, "x509:"):
+			message = fmt.Sprintf("TLS error: %v (add a CA bundle or skip verification in the transport settings)", err)
 		}
 		return HTTPResponse{
-			Error:    fmt.Sprintf("Request failed: %v", err),
-			Duration: duration,
+			Error:     message,
+			Duration:  duration,
+			Redirects: redirects,
+			Timing:    trace.timing(time.Now(), nil),
 		}
 	}
 	defer resp.Body.Close()
-
-	// Limit response body size to 10MB to prevent memory issues
-	const maxBodySize = 10 * 1024 * 1024
-	limitedReader := io.LimitReader(resp.Body, maxBodySize)
-
-	body, err := io.ReadAll(limitedReader)
-	if err != nil {
-		return HTTPResponse{
-			Error:    fmt.Sprintf("Failed to read response: %v", err),
-			Duration: duration,
-		}
-	}
-
-	// Check if response was truncated
-	if len(body) == maxBodySize {
-		return HTTPResponse{
-			Error:    "Response body exceeds 10MB limit and was truncated",
-			Duration: duration,
-		}
+	reporter.headers(resp.StatusCode)
+	if stream != nil {
+		// A stream may go quiet for as long as the server likes
+		deadline.stop()
 	}
 
-	// Extract response headers
+	// Extract response headers. The map joins repeated values and is kept
+	// for simple lookups; HeaderList keeps every value.
 	headers := make(map[string]string)
 	for key, values := range resp.Header {
 		headers[key] = strings.Join(values, ", ")
 	}
-
-	return HTTPResponse{
+	response := HTTPResponse{
 		Status:     resp.Status,
 		StatusCode: resp.StatusCode,
 		Headers:    headers,
-		Body:       string(body),
-		Error:      "",
-		Duration:   duration,
+		HeaderList: headerList(resp.Header),
+		Redirects:  redirects,
+
+		ContentEncoding: resp.Header.Get("Content-Encoding"),
+	}
+
+	// Undo Content-Encoding here rather than in the transport so both the
+	// compressed and decompressed sizes can be reported
+	wire := &countingReader{r: io.TeeReader(deadline.reader(resp.Body), reporter)}
+	bodyReader, releaseDecoder, err := decodeContentEncoding(response.ContentEncoding, wire)
+	defer releaseDecoder()
+
+	if err != nil {
+		err = fmt.Errorf("Failed to decode response: %v", err)
+	} else if stream != nil {
+		// Pass the body on as it arrives; the stream keeps it
+		err = stream.read(resp, bodyReader)
+		if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+			err = fmt.Errorf("Failed to read stream: %v", err)
+		}
+	} else if request.SaveTo != "" {
+		// Stream the body straight to a file in storage
+		response.SavedPath, response.Size, err = a.saveResponseBody(request.SaveTo, bodyReader)
+		if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+			err = fmt.Errorf("Failed to save response: %v", err)
+		}
+	} else {
+		var body []byte
+		body, err = io.ReadAll(io.LimitReader(bodyReader, maxBodySize+1))
+		if len(body) > maxBodySize {
+			body = body[:maxBodySize]
+			response.Truncated = true
+		}
+		response.Size = int64(len(body))
+		setResponseBody(&response, resp.Header.Get("Content-Type"), body)
+		if err != nil {
+			err = fmt.Errorf("Failed to read response: %v", err)
+		}
 	}
+	response.CompressedSize = wire.n
+	if err != nil && errors.Is(context.Cause(requestCtx), errReadTimeout) {
+		err = fmt.Errorf("Read timeout: no data received for %v", settings.readTimeout())
+	}
+
+	endTime := time.Now()
+	response.Duration = endTime.Sub(startTime).Milliseconds()
+	response.Timing = trace.timing(endTime, resp)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		response.Error = "Request cancelled"
+		response.Cancelled = true
+	} else if err != nil {
+		response.Error = err.Error()
+	}
+	return response
 }
 
 // ========== System Info ==========
@@ -943,6 +1206,21 @@ func (a *App) RenameItem(itemPath string, newName string) FileSystemResponse {
 	}
 }
 
+// resolveStoragePath returns the absolute form of itemPath, refusing paths
+// outside the storage directory
+func (a *App) resolveStoragePath(itemPath string) (string, error) {
+	absPath, err := filepath.Abs(itemPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+
+	absStorage, _ := filepath.Abs(a.storagePath)
+	if !strings.HasPrefix(absPath, absStorage) {
+		return "", fmt.Errorf("access denied: path outside storage directory")
+	}
+	return absPath, nil
+}
+
 // ReadFileContent reads the content of a file
 func (a *App) ReadFileContent(filePath string) FileSystemResponse {
 	// Security check
@@ -1288,7 +1566,7 @@ func (a *App) GlobalSearch(query string) []SearchResult {
 	// Case-insensitive search
 	query = strings.ToLower(query)
 
-	dirs := []string{"json", "xml", "base64", "http"}
+	dirs := []string{"json", "xml", "base64", "http", "websocket"}
 	for _, tool := range dirs {
 		toolPath := filepath.Join(a.storagePath, tool)
 
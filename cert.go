@@ -0,0 +1,589 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ========== Certificate Tools ==========
+
+// CertificateExtension is a single X.509 extension
+type CertificateExtension struct {
+	OID      string `json:"oid"`
+	Name     string `json:"name"`
+	Critical bool   `json:"critical"`
+}
+
+// CertificateInfo describes one decoded certificate, CSR or key
+type CertificateInfo struct {
+	Kind               string                 `json:"kind"` // "certificate", "csr", "public key" or "private key"
+	Subject            string                 `json:"subject,omitempty"`
+	Issuer             string                 `json:"issuer,omitempty"`
+	SerialNumber       string                 `json:"serialNumber,omitempty"`
+	NotBefore          string                 `json:"notBefore,omitempty"`
+	NotAfter           string                 `json:"notAfter,omitempty"`
+	Status             string                 `json:"status,omitempty"` // "valid", "expired" or "not yet valid"
+	DaysRemaining      int                    `json:"daysRemaining,omitempty"`
+	SANs               []string               `json:"sans,omitempty"`
+	KeyAlgorithm       string                 `json:"keyAlgorithm,omitempty"`
+	KeySize            int                    `json:"keySize,omitempty"`
+	SignatureAlgorithm string                 `json:"signatureAlgorithm,omitempty"`
+	IsCA               bool                   `json:"isCA,omitempty"`
+	SelfSigned         bool                   `json:"selfSigned,omitempty"`
+	KeyUsage           []string               `json:"keyUsage,omitempty"`
+	ExtKeyUsage        []string               `json:"extKeyUsage,omitempty"`
+	FingerprintSHA1    string                 `json:"fingerprintSha1,omitempty"`
+	FingerprintSHA256  string                 `json:"fingerprintSha256,omitempty"`
+	Extensions         []CertificateExtension `json:"extensions,omitempty"`
+}
+
+// CertificateChainCheck reports whether certificates are in leaf-to-root order
+type CertificateChainCheck struct {
+	Ordered bool     `json:"ordered"`
+	Issues  []string `json:"issues,omitempty"`
+}
+
+// CertificateDecodeResponse is the result of decoding PEM or DER input
+type CertificateDecodeResponse struct {
+	Items []CertificateInfo      `json:"items"`
+	Chain *CertificateChainCheck `json:"chain,omitempty"`
+	Error string                 `json:"error"`
+}
+
+// CertificateGenerateRequest describes a self-signed certificate or CSR
+type CertificateGenerateRequest struct {
+	CommonName   string   `json:"commonName"`
+	Organization string   `json:"organization,omitempty"`
+	Country      string   `json:"country,omitempty"`
+	Hosts        []string `json:"hosts,omitempty"`   // DNS names, IP addresses, e-mails or URIs
+	KeyType      string   `json:"keyType,omitempty"` // "rsa2048" (default), "rsa4096", "ecdsa-p256", "ecdsa-p384" or "ed25519"
+	ValidDays    int      `json:"validDays,omitempty"`
+	IsCA         bool     `json:"isCA,omitempty"`
+}
+
+// CertificateGenerateResponse carries the generated PEM documents
+type CertificateGenerateResponse struct {
+	Certificate string `json:"certificate,omitempty"`
+	CSR         string `json:"csr,omitempty"`
+	PrivateKey  string `json:"privateKey"`
+	Error       string `json:"error"`
+}
+
+// DecodeCertificate decodes PEM blocks, or Base64-encoded DER, from text
+func (a *App) DecodeCertificate(content string) CertificateDecodeResponse {
+	data := []byte(strings.TrimSpace(content))
+	if len(data) == 0 {
+		return CertificateDecodeResponse{Error: "Input is empty"}
+	}
+
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+		if err != nil {
+			return CertificateDecodeResponse{Error: fmt.Sprintf("Input is neither PEM nor Base64 DER: %v", err)}
+		}
+		data = der
+	}
+	return decodeCertificateData(data)
+}
+
+// DecodeCertificateFile decodes a PEM or DER file from the storage directory
+func (a *App) DecodeCertificateFile(filePath string) CertificateDecodeResponse {
+	absPath, err := a.resolveStoragePath(filePath)
+	if err != nil {
+		return CertificateDecodeResponse{Error: err.Error()}
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return CertificateDecodeResponse{Error: fmt.Sprintf("Failed to read file: %v", err)}
+	}
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		// Files may hold Base64 DER as well as raw DER
+		if der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), "")); err == nil {
+			data = der
+		}
+	}
+	return decodeCertificateData(data)
+}
+
+// GenerateSelfSignedCertificate creates a key pair and a self-signed certificate
+func (a *App) GenerateSelfSignedCertificate(request CertificateGenerateRequest) CertificateGenerateResponse {
+	key, keyPEM, err := generateCertificateKey(request.KeyType)
+	if err != nil {
+		return CertificateGenerateResponse{Error: err.Error()}
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return CertificateGenerateResponse{Error: fmt.Sprintf("Failed to generate serial number: %v", err)}
+	}
+
+	validDays := request.ValidDays
+	if validDays <= 0 {
+		validDays = 365
+	}
+	notBefore := time.Now().Add(-time.Minute)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               certificateSubject(request),
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(0, 0, validDays),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  request.IsCA,
+	}
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	if request.IsCA {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	template.DNSNames, template.IPAddresses, template.EmailAddresses, template.URIs = splitCertificateHosts(request.Hosts)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return CertificateGenerateResponse{Error: fmt.Sprintf("Failed to create certificate: %v", err)}
+	}
+
+	return CertificateGenerateResponse{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		PrivateKey:  keyPEM,
+	}
+}
+
+// GenerateCSR creates a key pair and a certificate signing request
+func (a *App) GenerateCSR(request CertificateGenerateRequest) CertificateGenerateResponse {
+	key, keyPEM, err := generateCertificateKey(request.KeyType)
+	if err != nil {
+		return CertificateGenerateResponse{Error: err.Error()}
+	}
+
+	template := &x509.CertificateRequest{
+		Subject: certificateSubject(request),
+	}
+	template.DNSNames, template.IPAddresses, template.EmailAddresses, template.URIs = splitCertificateHosts(request.Hosts)
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return CertificateGenerateResponse{Error: fmt.Sprintf("Failed to create CSR: %v", err)}
+	}
+
+	return CertificateGenerateResponse{
+		CSR:        string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})),
+		PrivateKey: keyPEM,
+	}
+}
+
+// decodeCertificateData decodes every PEM block in data, or data as one DER object
+func decodeCertificateData(data []byte) CertificateDecodeResponse {
+	var items []CertificateInfo
+	var certs []*x509.Certificate
+
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		item, cert, err := decodeDER(data)
+		if err != nil {
+			return CertificateDecodeResponse{Error: err.Error()}
+		}
+		return CertificateDecodeResponse{Items: []CertificateInfo{item}, Chain: checkCertificateChain(nonNil(cert))}
+	}
+
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		item, cert, err := decodePEMBlock(block)
+		if err != nil {
+			return CertificateDecodeResponse{Items: items, Error: fmt.Sprintf("Block %d (%s): %v", len(items)+1, block.Type, err)}
+		}
+		items = append(items, item)
+		if cert != nil {
+			certs = append(certs, cert)
+		}
+	}
+
+	if len(items) == 0 {
+		return CertificateDecodeResponse{Error: "Invalid PEM: no PEM block found"}
+	}
+	return CertificateDecodeResponse{Items: items, Chain: checkCertificateChain(certs)}
+}
+
+// nonNil wraps a single certificate in a slice, if present
+func nonNil(cert *x509.Certificate) []*x509.Certificate {
+	if cert == nil {
+		return nil
+	}
+	return []*x509.Certificate{cert}
+}
+
+// decodePEMBlock decodes a single PEM block by its type
+func decodePEMBlock(block *pem.Block) (CertificateInfo, *x509.Certificate, error) {
+	switch block.Type {
+	case "CERTIFICATE", "TRUSTED CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return CertificateInfo{}, nil, err
+		}
+		return certificateInfo(cert), cert, nil
+	case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			return CertificateInfo{}, nil, err
+		}
+		return csrInfo(csr), nil, nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return CertificateInfo{}, nil, err
+		}
+		return keyInfo("public key", key, block.Bytes), nil, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return CertificateInfo{}, nil, err
+		}
+		return keyInfo("public key", key, block.Bytes), nil, nil
+	case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+		key, err := parsePrivateKeyPEM(pem.EncodeToMemory(block))
+		if err != nil {
+			return CertificateInfo{}, nil, err
+		}
+		return keyInfo("private key", key, block.Bytes), nil, nil
+	case "ENCRYPTED PRIVATE KEY":
+		return CertificateInfo{}, nil, errors.New("encrypted private keys are not supported")
+	}
+	return CertificateInfo{}, nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+}
+
+// decodeDER tries each DER structure in turn
+func decodeDER(der []byte) (CertificateInfo, *x509.Certificate, error) {
+	if cert, err := x509.ParseCertificate(der); err == nil {
+		return certificateInfo(cert), cert, nil
+	}
+	if csr, err := x509.ParseCertificateRequest(der); err == nil {
+		return csrInfo(csr), nil, nil
+	}
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return keyInfo("public key", key, der), nil, nil
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return keyInfo("private key", key, der), nil, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return keyInfo("private key", key, der), nil, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return keyInfo("private key", key, der), nil, nil
+	}
+	return CertificateInfo{}, nil, errors.New("DER data is not a certificate, CSR or key")
+}
+
+// certificateInfo extracts the displayed fields of a certificate
+func certificateInfo(cert *x509.Certificate) CertificateInfo {
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+
+	info := CertificateInfo{
+		Kind:               "certificate",
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       formatFingerprint(cert.SerialNumber.Bytes()),
+		NotBefore:          cert.NotBefore.Local().Format("2006-01-02 15:04:05 MST"),
+		NotAfter:           cert.NotAfter.Local().Format("2006-01-02 15:04:05 MST"),
+		SANs:               subjectAltNames(cert.DNSNames, cert.IPAddresses, cert.EmailAddresses, cert.URIs),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+		SelfSigned:         bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil,
+		KeyUsage:           keyUsageNames(cert.KeyUsage),
+		ExtKeyUsage:        extKeyUsageNames(cert.ExtKeyUsage),
+		FingerprintSHA1:    formatFingerprint(sha1Sum[:]),
+		FingerprintSHA256:  formatFingerprint(sha256Sum[:]),
+		Extensions:         certificateExtensions(cert.Extensions),
+	}
+	info.KeyAlgorithm, info.KeySize = publicKeyDetails(cert.PublicKey)
+
+	now := time.Now()
+	switch {
+	case now.Before(cert.NotBefore):
+		info.Status = "not yet valid"
+	case now.After(cert.NotAfter):
+		info.Status = "expired"
+	default:
+		info.Status = "valid"
+		info.DaysRemaining = int(cert.NotAfter.Sub(now).Hours() / 24)
+	}
+
+	return info
+}
+
+// csrInfo extracts the displayed fields of a certificate request
+func csrInfo(csr *x509.CertificateRequest) CertificateInfo {
+	sha256Sum := sha256.Sum256(csr.Raw)
+
+	info := CertificateInfo{
+		Kind:               "csr",
+		Subject:            csr.Subject.String(),
+		SANs:               subjectAltNames(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, csr.URIs),
+		SignatureAlgorithm: csr.SignatureAlgorithm.String(),
+		FingerprintSHA256:  formatFingerprint(sha256Sum[:]),
+		Extensions:         certificateExtensions(csr.Extensions),
+	}
+	if csr.CheckSignature() != nil {
+		info.Status = "invalid signature"
+	}
+	info.KeyAlgorithm, info.KeySize = publicKeyDetails(csr.PublicKey)
+	return info
+}
+
+// keyInfo describes a bare public or private key
+func keyInfo(kind string, key interface{}, der []byte) CertificateInfo {
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+
+	info := CertificateInfo{Kind: kind}
+	info.KeyAlgorithm, info.KeySize = publicKeyDetails(key)
+
+	// Fingerprint the SubjectPublicKeyInfo so keys can be matched to certificates
+	if spki, err := x509.MarshalPKIXPublicKey(key); err == nil {
+		der = spki
+	}
+	sha256Sum := sha256.Sum256(der)
+	info.FingerprintSHA256 = formatFingerprint(sha256Sum[:])
+	return info
+}
+
+// publicKeyDetails returns the algorithm name and key size in bits
+func publicKeyDetails(key interface{}) (string, int) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return "RSA", k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name, k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	}
+	return fmt.Sprintf("%T", key), 0
+}
+
+// checkCertificateChain verifies each certificate is signed by the next one
+func checkCertificateChain(certs []*x509.Certificate) *CertificateChainCheck {
+	if len(certs) < 2 {
+		return nil
+	}
+
+	check := &CertificateChainCheck{Ordered: true}
+	for i := 0; i < len(certs)-1; i++ {
+		child, parent := certs[i], certs[i+1]
+		if !bytes.Equal(child.RawIssuer, parent.RawSubject) {
+			check.Ordered = false
+			check.Issues = append(check.Issues, fmt.Sprintf("Certificate %d issuer %q does not match certificate %d subject %q", i+1, child.Issuer, i+2, parent.Subject))
+			continue
+		}
+		if err := child.CheckSignatureFrom(parent); err != nil {
+			check.Ordered = false
+			check.Issues = append(check.Issues, fmt.Sprintf("Certificate %d is not signed by certificate %d: %v", i+1, i+2, err))
+		}
+	}
+
+	now := time.Now()
+	for i, cert := range certs {
+		if now.After(cert.NotAfter) {
+			check.Issues = append(check.Issues, fmt.Sprintf("Certificate %d (%s) expired on %s", i+1, cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02")))
+		}
+		if i > 0 && !cert.IsCA {
+			check.Issues = append(check.Issues, fmt.Sprintf("Certificate %d (%s) is used as an issuer but is not a CA", i+1, cert.Subject.CommonName))
+		}
+	}
+
+	return check
+}
+
+// subjectAltNames flattens the SAN fields with a type prefix
+func subjectAltNames(dns []string, ips []net.IP, emails []string, uris []*url.URL) []string {
+	var sans []string
+	for _, name := range dns {
+		sans = append(sans, "DNS:"+name)
+	}
+	for _, ip := range ips {
+		sans = append(sans, "IP:"+ip.String())
+	}
+	for _, email := range emails {
+		sans = append(sans, "email:"+email)
+	}
+	for _, uri := range uris {
+		sans = append(sans, "URI:"+uri.String())
+	}
+	return sans
+}
+
+// formatFingerprint renders bytes as colon separated uppercase hex
+func formatFingerprint(data []byte) string {
+	encoded := strings.ToUpper(hex.EncodeToString(data))
+	var parts []string
+	for i := 0; i < len(encoded); i += 2 {
+		parts = append(parts, encoded[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// keyUsageNames lists the set key usage bits
+func keyUsageNames(usage x509.KeyUsage) []string {
+	names := []string{
+		"Digital Signature", "Content Commitment", "Key Encipherment", "Data Encipherment",
+		"Key Agreement", "Certificate Sign", "CRL Sign", "Encipher Only", "Decipher Only",
+	}
+
+	var result []string
+	for i, name := range names {
+		if usage&(1<<uint(i)) != 0 {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// extKeyUsageNames lists the extended key usages by name
+func extKeyUsageNames(usages []x509.ExtKeyUsage) []string {
+	names := map[x509.ExtKeyUsage]string{
+		x509.ExtKeyUsageAny:             "Any",
+		x509.ExtKeyUsageServerAuth:      "TLS Web Server Authentication",
+		x509.ExtKeyUsageClientAuth:      "TLS Web Client Authentication",
+		x509.ExtKeyUsageCodeSigning:     "Code Signing",
+		x509.ExtKeyUsageEmailProtection: "E-mail Protection",
+		x509.ExtKeyUsageTimeStamping:    "Time Stamping",
+		x509.ExtKeyUsageOCSPSigning:     "OCSP Signing",
+	}
+
+	var result []string
+	for _, usage := range usages {
+		if name, ok := names[usage]; ok {
+			result = append(result, name)
+		} else {
+			result = append(result, fmt.Sprintf("Unknown (%d)", usage))
+		}
+	}
+	return result
+}
+
+// certificateExtensionNames maps well-known extension OIDs to names
+var certificateExtensionNames = map[string]string{
+	"2.5.29.14":               "Subject Key Identifier",
+	"2.5.29.15":               "Key Usage",
+	"2.5.29.17":               "Subject Alternative Name",
+	"2.5.29.19":               "Basic Constraints",
+	"2.5.29.30":               "Name Constraints",
+	"2.5.29.31":               "CRL Distribution Points",
+	"2.5.29.32":               "Certificate Policies",
+	"2.5.29.35":               "Authority Key Identifier",
+	"2.5.29.37":               "Extended Key Usage",
+	"1.3.6.1.5.5.7.1.1":       "Authority Information Access",
+	"1.3.6.1.4.1.11129.2.4.2": "Signed Certificate Timestamps",
+}
+
+// certificateExtensions lists extensions with readable names where known
+func certificateExtensions(extensions []pkix.Extension) []CertificateExtension {
+	var result []CertificateExtension
+	for _, ext := range extensions {
+		oid := ext.Id.String()
+		name := certificateExtensionNames[oid]
+		if name == "" {
+			name = "Unknown"
+		}
+		result = append(result, CertificateExtension{OID: oid, Name: name, Critical: ext.Critical})
+	}
+	return result
+}
+
+// certificateSubject builds the distinguished name for generation
+func certificateSubject(request CertificateGenerateRequest) pkix.Name {
+	subject := pkix.Name{CommonName: request.CommonName}
+	if request.Organization != "" {
+		subject.Organization = []string{request.Organization}
+	}
+	if request.Country != "" {
+		subject.Country = []string{request.Country}
+	}
+	return subject
+}
+
+// splitCertificateHosts sorts host entries into the SAN categories
+func splitCertificateHosts(hosts []string) ([]string, []net.IP, []string, []*url.URL) {
+	var dns, emails []string
+	var ips []net.IP
+	var uris []*url.URL
+
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		} else if strings.Contains(host, "://") {
+			if u, err := url.Parse(host); err == nil {
+				uris = append(uris, u)
+			}
+		} else if strings.Contains(host, "@") {
+			emails = append(emails, host)
+		} else {
+			dns = append(dns, host)
+		}
+	}
+	return dns, ips, emails, uris
+}
+
+// generateCertificateKey creates a private key and its PKCS#8 PEM encoding
+func generateCertificateKey(keyType string) (crypto.Signer, string, error) {
+	var key crypto.Signer
+	var err error
+
+	switch strings.ToLower(keyType) {
+	case "", "rsa", "rsa2048":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "rsa4096":
+		key, err = rsa.GenerateKey(rand.Reader, 4096)
+	case "ecdsa", "ecdsa-p256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ecdsa-p384":
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ed25519":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, "", fmt.Errorf("Unsupported key type %q", keyType)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to generate key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to encode key: %v", err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ========== Binary Payload Tools ==========
+
+// ProtobufDecodeRequest describes a protobuf payload to decode. Without a
+// SchemaPath the wire format is decoded schemalessly.
+type ProtobufDecodeRequest struct {
+	Data        string `json:"data"`
+	Encoding    string `json:"encoding,omitempty"`    // "base64" (default) or "hex"
+	FilePath    string `json:"filePath,omitempty"`    // raw payload file in storage, instead of Data
+	SchemaPath  string `json:"schemaPath,omitempty"`  // .proto file or descriptor set in storage
+	MessageType string `json:"messageType,omitempty"` // fully-qualified message name
+}
+
+// ProtobufField is one field of a schemaless decode
+type ProtobufField struct {
+	Field    protowire.Number `json:"field"`
+	WireType string           `json:"wireType"`
+	As       string           `json:"as,omitempty"` // interpretation of length-delimited data
+	Value    interface{}      `json:"value"`
+	Signed   *int64           `json:"signed,omitempty"` // zigzag decoded varint
+	Float    *float64         `json:"float,omitempty"`  // fixed32/fixed64 read as IEEE 754
+}
+
+// DecodeProtobuf turns protobuf wire data into annotated or typed JSON
+func (a *App) DecodeProtobuf(request ProtobufDecodeRequest) JSONFormatResponse {
+	data, err := a.binaryInput(request.Data, request.Encoding, request.FilePath)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+
+	if request.SchemaPath == "" {
+		fields, err := decodeProtobufFields(data, 0)
+		if err != nil {
+			return JSONFormatResponse{Error: fmt.Sprintf("Invalid protobuf: %v", err)}
+		}
+		return marshalIndentResponse(fields)
+	}
+
+	messages, err := a.loadProtobufSchema(request.SchemaPath)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+	descriptor, err := selectProtobufMessage(messages, request.MessageType)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(data, message); err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("Failed to decode %s: %v", descriptor.FullName(), err)}
+	}
+
+	out, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	if err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("JSON marshal error: %v", err)}
+	}
+	// protojson output is deliberately unstable, so indent it ourselves
+	return JSONFormatResponse{Result: indentJSONBytes(out)}
+}
+
+// ListProtobufMessages returns the message types defined in a schema file
+func (a *App) ListProtobufMessages(schemaPath string) FileSystemResponse {
+	messages, err := a.loadProtobufSchema(schemaPath)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+
+	names := make([]string, 0, len(messages))
+	for name := range messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return FileSystemResponse{Success: true, Data: names}
+}
+
+// MessagePackToJSON decodes a Base64 or hex MessagePack payload into JSON
+func (a *App) MessagePackToJSON(content string, encoding string) JSONFormatResponse {
+	data, err := decodeBinaryText(content, encoding)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+
+	var value interface{}
+	if err := msgpack.Unmarshal(data, &value); err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("Invalid MessagePack: %v", err)}
+	}
+	return marshalIndentResponse(jsonCompatible(value))
+}
+
+// JSONToMessagePack encodes JSON as MessagePack, returned as Base64 or hex
+func (a *App) JSONToMessagePack(content string, encoding string) JSONFormatResponse {
+	value, err := decodeJSONValue(content)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetSortMapKeys(true)
+	if err := encoder.Encode(value); err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("MessagePack encode error: %v", err)}
+	}
+	return JSONFormatResponse{Result: encodeBinaryText(buf.Bytes(), encoding)}
+}
+
+// CBORToJSON decodes a Base64 or hex CBOR payload into JSON
+func (a *App) CBORToJSON(content string, encoding string) JSONFormatResponse {
+	data, err := decodeBinaryText(content, encoding)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+
+	var value interface{}
+	if err := cbor.Unmarshal(data, &value); err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("Invalid CBOR: %v", err)}
+	}
+	return marshalIndentResponse(jsonCompatible(value))
+}
+
+// JSONToCBOR encodes JSON as canonical CBOR, returned as Base64 or hex
+func (a *App) JSONToCBOR(content string, encoding string) JSONFormatResponse {
+	value, err := decodeJSONValue(content)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+
+	mode, err := cbor.CanonicalEncOptions().EncMode()
+	if err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("CBOR encode error: %v", err)}
+	}
+	data, err := mode.Marshal(value)
+	if err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("CBOR encode error: %v", err)}
+	}
+	return JSONFormatResponse{Result: encodeBinaryText(data, encoding)}
+}
+
+// marshalIndentResponse renders a value as indented JSON
+func marshalIndentResponse(value interface{}) JSONFormatResponse {
+	out, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("JSON marshal error: %v", err)}
+	}
+	return JSONFormatResponse{Result: string(out)}
+}
+
+// binaryInput reads a payload from text or from a file in storage
+func (a *App) binaryInput(content string, encoding string, filePath string) ([]byte, error) {
+	if filePath == "" {
+		return decodeBinaryText(content, encoding)
+	}
+
+	absPath, err := a.resolveStoragePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read file: %v", err)
+	}
+	return data, nil
+}
+
+// decodeBinaryText decodes Base64 (standard or URL-safe) or hex text
+func decodeBinaryText(content string, encoding string) ([]byte, error) {
+	content = strings.Join(strings.Fields(content), "")
+	if content == "" {
+		return nil, errors.New("Input is empty")
+	}
+
+	if strings.EqualFold(encoding, "hex") {
+		data, err := hex.DecodeString(strings.TrimPrefix(content, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid hex: %v", err)
+		}
+		return data, nil
+	}
+
+	content = strings.TrimRight(content, "=")
+	if strings.ContainsAny(content, "-_") {
+		data, err := base64.RawURLEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Base64: %v", err)
+		}
+		return data, nil
+	}
+	data, err := base64.RawStdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Base64: %v", err)
+	}
+	return data, nil
+}
+
+// encodeBinaryText renders bytes as Base64 (default) or hex
+func encodeBinaryText(data []byte, encoding string) string {
+	if strings.EqualFold(encoding, "hex") {
+		return hex.EncodeToString(data)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// decodeJSONValue parses JSON keeping integers exact
+func decodeJSONValue(content string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("Invalid JSON: %v", err)
+	}
+	return normalizeJSONNumbers(value), nil
+}
+
+// normalizeJSONNumbers converts json.Number into int64, uint64 or float64
+func normalizeJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeJSONNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeJSONNumbers(item)
+		}
+	}
+	return value
+}
+
+// jsonCompatible converts decoded MessagePack/CBOR values into types
+// encoding/json can represent: non-string map keys are stringified, byte
+// strings become Base64 and CBOR tags become {"tag": n, "value": ...}
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonCompatible(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return result
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+		return v
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case cbor.Tag:
+		return map[string]interface{}{"tag": v.Number, "value": jsonCompatible(v.Content)}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Sprint(v)
+		}
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return fmt.Sprint(v)
+		}
+	}
+	return value
+}
+
+// maxProtobufDepth bounds nested message guessing and group nesting
+const maxProtobufDepth = 32
+
+// decodeProtobufFields decodes wire format without a schema, guessing
+// whether length-delimited fields are nested messages, strings or bytes
+func decodeProtobufFields(data []byte, depth int) ([]ProtobufField, error) {
+	var fields []ProtobufField
+
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		field := ProtobufField{Field: number}
+		switch wireType {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			field.WireType = "varint"
+			field.Value = v
+			if signed := protowire.DecodeZigZag(v); signed < 0 {
+				field.Signed = &signed
+			}
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			f := float64(math.Float32frombits(v))
+			field.WireType = "fixed32"
+			field.Value = v
+			field.Float = &f
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			f := math.Float64frombits(v)
+			field.WireType = "fixed64"
+			field.Value = v
+			field.Float = &f
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			field.WireType = "len"
+			field.As, field.Value = guessLengthDelimited(v, depth)
+		case protowire.StartGroupType:
+			if depth >= maxProtobufDepth {
+				return nil, fmt.Errorf("groups are nested more than %d levels deep", maxProtobufDepth)
+			}
+			v, n := protowire.ConsumeGroup(number, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			nested, err := decodeProtobufFields(v, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			field.WireType = "group"
+			field.Value = nested
+		default:
+			return nil, fmt.Errorf("unexpected wire type %d for field %d", wireType, number)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// guessLengthDelimited picks the most plausible reading of a len field
+func guessLengthDelimited(data []byte, depth int) (string, interface{}) {
+	if len(data) == 0 {
+		return "string", ""
+	}
+	if isPrintableText(data) {
+		return "string", string(data)
+	}
+	if depth < maxProtobufDepth {
+		if nested, err := decodeProtobufFields(data, depth+1); err == nil && len(nested) > 0 {
+			return "message", nested
+		}
+	}
+	if packed, ok := decodePackedVarints(data); ok {
+		return "packed varint", packed
+	}
+	return "bytes", base64.StdEncoding.EncodeToString(data)
+}
+
+// isPrintableText reports whether data is UTF-8 without control characters
+func isPrintableText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodePackedVarints reads data as a packed repeated varint field
+func decodePackedVarints(data []byte) ([]uint64, bool) {
+	var values []uint64
+	for len(data) > 0 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, false
+		}
+		values = append(values, v)
+		data = data[n:]
+	}
+	return values, true
+}
+
+// loadProtobufSchema compiles a .proto file, or reads a binary descriptor
+// set, and indexes its message types by full name
+func (a *App) loadProtobufSchema(schemaPath string) (map[string]protoreflect.MessageDescriptor, error) {
+	absPath, err := a.resolveStoragePath(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []protoreflect.FileDescriptor
+	if strings.EqualFold(filepath.Ext(absPath), ".proto") {
+		// Imports resolve relative to the schema's folder, then the storage root
+		compiler := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+				ImportPaths: []string{filepath.Dir(absPath), a.storagePath},
+			}),
+		}
+		compiled, err := compiler.Compile(context.Background(), filepath.Base(absPath))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to compile schema: %v", err)
+		}
+		for _, file := range compiled {
+			files = append(files, file)
+		}
+	} else {
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read schema: %v", err)
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &set); err != nil {
+			return nil, fmt.Errorf("Invalid descriptor set: %v", err)
+		}
+		registry, err := protodesc.NewFiles(&set)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid descriptor set: %v", err)
+		}
+		registry.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+			files = append(files, file)
+			return true
+		})
+	}
+
+	messages := make(map[string]protoreflect.MessageDescriptor)
+	for _, file := range files {
+		collectProtobufMessages(file.Messages(), messages)
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("Schema defines no message types")
+	}
+	return messages, nil
+}
+
+// collectProtobufMessages walks nested message declarations
+func collectProtobufMessages(list protoreflect.MessageDescriptors, into map[string]protoreflect.MessageDescriptor) {
+	for i := 0; i < list.Len(); i++ {
+		message := list.Get(i)
+		if message.IsMapEntry() {
+			continue
+		}
+		into[string(message.FullName())] = message
+		collectProtobufMessages(message.Messages(), into)
+	}
+}
+
+// selectProtobufMessage finds the requested message type. The name may be
+// fully qualified or a unique short name; it may be omitted if the schema
+// has a single message.
+func selectProtobufMessage(messages map[string]protoreflect.MessageDescriptor, name string) (protoreflect.MessageDescriptor, error) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), ".")
+	if message, ok := messages[name]; ok {
+		return message, nil
+	}
+
+	var candidates []protoreflect.MessageDescriptor
+	for fullName, message := range messages {
+		if name == "" || strings.HasSuffix(fullName, "."+name) || string(message.Name()) == name {
+			candidates = append(candidates, message)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	if name == "" {
+		return nil, errors.New("Schema has several message types, please choose one")
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("Message type %q not found in schema", name)
+	}
+	return nil, fmt.Errorf("Message type %q is ambiguous, use the fully-qualified name", name)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/sha3"
+)
+
+// ========== Hash Tools ==========
+
+// hashAlgorithm describes a supported digest
+type hashAlgorithm struct {
+	name  string
+	new   func() hash.Hash
+	keyed bool // can be used with HMAC
+}
+
+// hashAlgorithms lists the supported digests in display order
+var hashAlgorithms = []hashAlgorithm{
+	{name: "md5", new: md5.New, keyed: true},
+	{name: "sha1", new: sha1.New, keyed: true},
+	{name: "sha224", new: sha256.New224, keyed: true},
+	{name: "sha256", new: sha256.New, keyed: true},
+	{name: "sha384", new: sha512.New384, keyed: true},
+	{name: "sha512", new: sha512.New, keyed: true},
+	{name: "sha512-224", new: sha512.New512_224, keyed: true},
+	{name: "sha512-256", new: sha512.New512_256, keyed: true},
+	{name: "sha3-224", new: sha3.New224, keyed: true},
+	{name: "sha3-256", new: sha3.New256, keyed: true},
+	{name: "sha3-384", new: sha3.New384, keyed: true},
+	{name: "sha3-512", new: sha3.New512, keyed: true},
+	{name: "blake2b-256", new: mustHash(blake2b.New256), keyed: true},
+	{name: "blake2b-384", new: mustHash(blake2b.New384), keyed: true},
+	{name: "blake2b-512", new: mustHash(blake2b.New512), keyed: true},
+	{name: "blake2s-256", new: mustHash(blake2s.New256), keyed: true},
+	{name: "crc32", new: func() hash.Hash { return crc32.NewIEEE() }},
+	{name: "xxhash64", new: func() hash.Hash { return xxhash.New() }},
+}
+
+// hashAliases maps common alternative spellings to algorithm names
+var hashAliases = map[string]string{
+	"sha-1":   "sha1",
+	"sha-224": "sha224",
+	"sha-256": "sha256",
+	"sha-384": "sha384",
+	"sha-512": "sha512",
+	"blake2b": "blake2b-512",
+	"blake2s": "blake2s-256",
+	"xxhash":  "xxhash64",
+	"xxh64":   "xxhash64",
+}
+
+// mustHash adapts the unkeyed BLAKE2 constructors, which only fail for bad keys
+func mustHash(newFn func([]byte) (hash.Hash, error)) func() hash.Hash {
+	return func() hash.Hash {
+		h, _ := newFn(nil)
+		return h
+	}
+}
+
+// HashRequest describes what to hash and how to present the result.
+// Either Text or FilePath is hashed; an empty Algorithms list means all.
+type HashRequest struct {
+	Algorithms []string `json:"algorithms"`
+	Text       string   `json:"text,omitempty"`
+	FilePath   string   `json:"filePath,omitempty"` // FileItem.Path of a stored file
+	HMACKey    string   `json:"hmacKey,omitempty"`
+	Encoding   string   `json:"encoding,omitempty"` // "hex" (default) or "base64"
+	Expected   string   `json:"expected,omitempty"` // digest to compare against, hex or Base64
+}
+
+// HashDigest is the result for one algorithm
+type HashDigest struct {
+	Algorithm string `json:"algorithm"`
+	Digest    string `json:"digest"`
+	Matches   bool   `json:"matches,omitempty"`
+}
+
+// HashResponse is the result of a hash request
+type HashResponse struct {
+	Digests []HashDigest `json:"digests"`
+	Size    int64        `json:"size"`
+	// Matched is the algorithm whose digest equals Expected, if any
+	Matched string `json:"matched,omitempty"`
+	Error   string `json:"error"`
+}
+
+// GetHashAlgorithms returns the supported algorithm names
+func (a *App) GetHashAlgorithms() []string {
+	names := make([]string, 0, len(hashAlgorithms))
+	for _, alg := range hashAlgorithms {
+		names = append(names, alg.name)
+	}
+	return names
+}
+
+// ComputeHash hashes text or a stored file with one or more algorithms.
+// Files are streamed through every selected hash in a single pass.
+func (a *App) ComputeHash(request HashRequest) HashResponse {
+	selected, err := selectHashAlgorithms(request.Algorithms)
+	if err != nil {
+		return HashResponse{Error: err.Error()}
+	}
+
+	hashes := make([]hash.Hash, len(selected))
+	writers := make([]io.Writer, len(selected))
+	for i, alg := range selected {
+		if request.HMACKey != "" {
+			if !alg.keyed {
+				return HashResponse{Error: fmt.Sprintf("HMAC is not supported for %s", alg.name)}
+			}
+			hashes[i] = hmac.New(alg.new, []byte(request.HMACKey))
+		} else {
+			hashes[i] = alg.new()
+		}
+		writers[i] = hashes[i]
+	}
+
+	var source io.Reader = strings.NewReader(request.Text)
+	if request.FilePath != "" {
+		absPath, err := a.resolveStoragePath(request.FilePath)
+		if err != nil {
+			return HashResponse{Error: err.Error()}
+		}
+		file, err := os.Open(absPath)
+		if err != nil {
+			return HashResponse{Error: fmt.Sprintf("Failed to open file: %v", err)}
+		}
+		defer file.Close()
+		source = file
+	}
+
+	size, err := io.Copy(io.MultiWriter(writers...), source)
+	if err != nil {
+		return HashResponse{Error: fmt.Sprintf("Failed to read input: %v", err)}
+	}
+
+	var expected [][]byte
+	if request.Expected != "" {
+		expected = decodeDigestCandidates(request.Expected)
+		if len(expected) == 0 {
+			return HashResponse{Error: "Expected digest is neither hex nor Base64"}
+		}
+	}
+
+	response := HashResponse{Size: size}
+	for i, h := range hashes {
+		sum := h.Sum(nil)
+		d := HashDigest{
+			Algorithm: selected[i].name,
+			Digest:    encodeDigest(sum, request.Encoding),
+		}
+		for _, candidate := range expected {
+			if hmac.Equal(sum, candidate) {
+				d.Matches = true
+				if response.Matched == "" {
+					response.Matched = d.Algorithm
+				}
+			}
+		}
+		response.Digests = append(response.Digests, d)
+	}
+
+	return response
+}
+
+// selectHashAlgorithms resolves algorithm names, defaulting to all of them
+func selectHashAlgorithms(names []string) ([]hashAlgorithm, error) {
+	if len(names) == 0 {
+		return hashAlgorithms, nil
+	}
+
+	var selected []hashAlgorithm
+	for _, name := range names {
+		normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-")
+		if alias, ok := hashAliases[normalized]; ok {
+			normalized = alias
+		}
+
+		found := false
+		for _, alg := range hashAlgorithms {
+			if alg.name == normalized {
+				selected = append(selected, alg)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unsupported algorithm %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// encodeDigest renders a digest as lowercase hex or standard Base64
+func encodeDigest(sum []byte, encoding string) string {
+	if strings.EqualFold(encoding, "base64") {
+		return base64.StdEncoding.EncodeToString(sum)
+	}
+	return hex.EncodeToString(sum)
+}
+
+// decodeDigestCandidates interprets an expected digest as hex and as Base64.
+// A "sha256=" style prefix, as used by webhook signature headers, is ignored.
+func decodeDigestCandidates(expected string) [][]byte {
+	expected = strings.TrimSpace(expected)
+	if i := strings.Index(expected, "="); i > 0 && i < len(expected)-2 {
+		expected = expected[i+1:]
+	}
+
+	var candidates [][]byte
+	if decoded, err := hex.DecodeString(expected); err == nil {
+		candidates = append(candidates, decoded)
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(expected); err == nil {
+			duplicate := false
+			for _, c := range candidates {
+				if bytes.Equal(c, decoded) {
+					duplicate = true
+				}
+			}
+			if !duplicate {
+				candidates = append(candidates, decoded)
+			}
+			break
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ========== HTTP Assertions ==========
+
+// HTTPAssertion is a declarative check of a response
+type HTTPAssertion struct {
+	// Type is "status", "header", "jsonpath", "schema" or "latency"
+	Type string `json:"type"`
+	// Target is the header name or JSONPath expression; for schema
+	// assertions an optional status or class such as 201 or 2xx, limiting
+	// the check to those responses
+	Target string `json:"target,omitempty"`
+	// Operator is one of == != < <= > >= contains matches exists
+	Operator string `json:"operator,omitempty"`
+	// Expected is the value to compare with; for schema assertions an
+	// inline JSON Schema or a path to one
+	Expected string `json:"expected,omitempty"`
+}
+
+// HTTPAssertionResult is the outcome of one assertion
+type HTTPAssertionResult struct {
+	Assertion   HTTPAssertion `json:"assertion"`
+	Description string        `json:"description"`
+	Message     string        `json:"message,omitempty"`
+}
+
+// assertionOperators lists the operators in the order they are matched
+// when parsing, longest first
+var assertionOperators = []string{"==", "!=", "<=", ">=", "<", ">", "=~", "contains", "matches", "exists"}
+
+// statusClassPattern matches status classes like 2xx
+var statusClassPattern = regexp.MustCompile(`^[1-5][xX][xX]$`)
+
+// statusTargetPattern matches the status codes and classes that schema
+// assertions can be limited to
+var statusTargetPattern = regexp.MustCompile(`^[1-5](?:[0-9][0-9]|[xX][xX])$`)
+
+// evaluateAssertions checks a response and fills response.Passed and
+// response.Failed. Relative schema paths are resolved against baseDir.
+func (a *App) evaluateAssertions(assertions []HTTPAssertion, response *HTTPResponse, baseDir string) {
+	for _, assertion := range assertions {
+		result := HTTPAssertionResult{Assertion: assertion, Description: describeAssertion(assertion)}
+
+		var err error
+		if response.Error != "" {
+			err = fmt.Errorf("request failed: %s", response.Error)
+		} else {
+			err = a.checkAssertion(assertion, *response, baseDir)
+		}
+
+		if err != nil {
+			result.Message = err.Error()
+			response.Failed = append(response.Failed, result)
+		} else {
+			response.Passed = append(response.Passed, result)
+		}
+	}
+}
+
+// checkAssertion returns nil if the assertion holds, otherwise the reason
+func (a *App) checkAssertion(assertion HTTPAssertion, response HTTPResponse, baseDir string) error {
+	op := assertion.Operator
+	switch strings.ToLower(assertion.Type) {
+	case "status":
+		if op == "" {
+			op = "=="
+		}
+		if statusClassPattern.MatchString(assertion.Expected) && (op == "==" || op == "!=") {
+			inClass := strconv.Itoa(response.StatusCode)[:1] == assertion.Expected[:1]
+			if inClass != (op == "==") {
+				return fmt.Errorf("expected status %s %s, got %d", op, assertion.Expected, response.StatusCode)
+			}
+			return nil
+		}
+		return compareAssertion("status", response.StatusCode, op, assertion.Expected)
+
+	case "header":
+		value, found := lookupHeader(response.Headers, assertion.Target)
+		if op == "" || op == "exists" {
+			if !found {
+				return fmt.Errorf("header %s is missing", assertion.Target)
+			}
+			return nil
+		}
+		if !found {
+			return fmt.Errorf("header %s is missing, expected %s %s", assertion.Target, op, assertion.Expected)
+		}
+		return compareAssertion("header "+assertion.Target, value, op, assertion.Expected)
+
+	case "jsonpath", "body":
+		matches, err := evalJSONPathString(response.Body, assertion.Target)
+		if err != nil {
+			return err
+		}
+		if op == "" || op == "exists" {
+			if len(matches) == 0 {
+				return fmt.Errorf("%s matched nothing", assertion.Target)
+			}
+			return nil
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("%s matched nothing, expected %s %s", assertion.Target, op, assertion.Expected)
+		}
+		return compareAssertion(assertion.Target, matches[0], op, assertion.Expected)
+
+	case "schema":
+		if assertion.Target != "" && !statusMatches(response.StatusCode, assertion.Target) {
+			return nil
+		}
+		schema, err := a.compileJSONSchema(assertion.Expected, baseDir)
+		if err != nil {
+			return err
+		}
+		if problems := validateJSONSchema(schema, response.Body); len(problems) > 0 {
+			return fmt.Errorf("body does not match schema: %s", strings.Join(problems, "; "))
+		}
+		return nil
+
+	case "latency", "duration":
+		if op == "" {
+			op = "<"
+		}
+		expected := strings.TrimSuffix(strings.TrimSpace(assertion.Expected), "ms")
+		return compareAssertion("latency", response.Duration, op, expected)
+	}
+	return fmt.Errorf("unknown assertion type %q", assertion.Type)
+}
+
+// statusMatches reports whether a status is the code or in the class
+func statusMatches(status int, target string) bool {
+	code := strconv.Itoa(status)
+	if statusClassPattern.MatchString(target) {
+		return code[:1] == target[:1]
+	}
+	return code == target
+}
+
+// compareAssertion compares actual with the expected text and describes
+// a mismatch
+func compareAssertion(subject string, actual interface{}, op string, expected string) error {
+	var ok bool
+	var err error
+	switch op {
+	case "contains":
+		ok = strings.Contains(jsonValueString(actual), expected)
+	case "matches", "=~":
+		ok, err = matchRegex(expected, jsonValueString(actual))
+	default:
+		ok, err = compareJSONValues(actual, op, parseJSONLiteral(expected))
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("expected %s %s %s, got %s", subject, op, expected, jsonValueString(actual))
+	}
+	return nil
+}
+
+// describeAssertion renders an assertion the way it is written in .http files
+func describeAssertion(assertion HTTPAssertion) string {
+	parts := []string{assertion.Type}
+	for _, part := range []string{assertion.Target, assertion.Operator, assertion.Expected} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// compileJSONSchema compiles an inline schema or one stored in a file,
+// optionally at a JSON pointer as in "./api.schema.json#/definitions/Pet";
+// $refs to sibling files resolve relative to it
+func (a *App) compileJSONSchema(source string, baseDir string) (*jsonschema.Schema, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("schema is empty")
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if strings.HasPrefix(source, "{") {
+		if err := compiler.AddResource("inline.json", strings.NewReader(source)); err != nil {
+			return nil, fmt.Errorf("invalid schema: %v", err)
+		}
+		schema, err := compiler.Compile("inline.json")
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema: %v", err)
+		}
+		return schema, nil
+	}
+
+	fragment := ""
+	if i := strings.Index(source, "#"); i >= 0 {
+		source, fragment = source[:i], source[i:]
+	}
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(baseDir, source)
+	}
+	absPath, err := a.resolveStoragePath(source)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return nil, fmt.Errorf("failed to read schema: %v", err)
+	}
+	schema, err := compiler.Compile(absPath + fragment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	return schema, nil
+}
+
+// validateJSONSchema validates a JSON document and returns one line per
+// violation
+func validateJSONSchema(schema *jsonschema.Schema, body string) []string {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return []string{fmt.Sprintf("body is not JSON: %v", err)}
+	}
+
+	err := schema.Validate(doc)
+	if err == nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+	var collect func(*jsonschema.ValidationError)
+	collect = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			location := ve.InstanceLocation
+			if location == "" {
+				location = "/"
+			}
+			problems = append(problems, fmt.Sprintf("%s: %s", location, ve.Message))
+		}
+		for _, cause := range ve.Causes {
+			collect(cause)
+		}
+	}
+	collect(validationErr)
+	return problems
+}
+
+// parseAssertAnnotation parses the text after "@assert" in a .http file:
+// "status == 200", "status 2xx", "header Content-Type contains json",
+// "header ETag exists", "jsonpath $.id == 1", "schema ./user.schema.json",
+// "schema 201 ./created.schema.json" or "latency < 500"
+func parseAssertAnnotation(text string) (HTTPAssertion, bool) {
+	kind, rest, _ := strings.Cut(strings.TrimSpace(text), " ")
+	assertion := HTTPAssertion{Type: strings.ToLower(kind)}
+	rest = strings.TrimSpace(rest)
+
+	switch assertion.Type {
+	case "schema":
+		if status, schema, ok := strings.Cut(rest, " "); ok && statusTargetPattern.MatchString(status) {
+			assertion.Target, rest = status, strings.TrimSpace(schema)
+		}
+		assertion.Expected = rest
+		return assertion, rest != ""
+	case "header", "jsonpath", "body":
+		assertion.Target, rest = cutAssertionTarget(rest)
+		if assertion.Target == "" {
+			return assertion, false
+		}
+	case "status", "latency", "duration":
+		if rest == "" {
+			return assertion, false
+		}
+	default:
+		return assertion, false
+	}
+
+	for _, op := range assertionOperators {
+		if strings.HasPrefix(rest, op) {
+			assertion.Operator = op
+			rest = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+	assertion.Expected = rest
+	return assertion, true
+}
+
+// cutAssertionTarget splits off the first word, keeping spaces inside
+// JSONPath brackets such as [?(@.ok == true)]
+func cutAssertionTarget(text string) (string, string) {
+	depth := 0
+	for i, c := range text {
+		switch {
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == ' ' && depth <= 0:
+			return text[:i], strings.TrimSpace(text[i:])
+		}
+	}
+	return text, ""
+}
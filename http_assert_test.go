@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runAssertions sends one request of a .http file, with its assertions,
+// to a local server answering like a user API
+func runAssertions(t *testing.T, asserts ...string) HTTPResponse {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 7, "name": "Ada", "tags": ["admin"]}`))
+	}))
+	defer server.Close()
+
+	a := &App{storagePath: t.TempDir()}
+	dir := filepath.Join(a.storagePath, "http", "users")
+	if err := os.MkdirAll(filepath.Join(dir, "schemas"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	schema := `{"type": "object", "required": ["id", "name"], "properties": {"id": {"type": "integer"}, "name": {"type": "string"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "schemas", "user.json"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	b.WriteString("### create\n")
+	for _, assert := range asserts {
+		b.WriteString("# @assert " + assert + "\n")
+	}
+	b.WriteString("POST " + server.URL + "/users\n")
+	filePath := filepath.Join(dir, "users.http")
+	if err := os.WriteFile(filePath, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := a.RunHTTPFile(filePath, "")
+	if result.Error != "" || len(result.Results) != 1 {
+		t.Fatalf("run failed: %+v", result)
+	}
+	response := result.Results[0].Response
+	if response.Error != "" {
+		t.Fatalf("request failed: %s", response.Error)
+	}
+	return response
+}
+
+func TestAssertionsPass(t *testing.T) {
+	asserts := []string{
+		"status == 201",
+		"status 2xx",
+		"status != 4xx",
+		"header Content-Type contains json",
+		"header ETag exists",
+		"jsonpath $.id == 7",
+		"jsonpath $.name matches ^A",
+		"jsonpath $.tags[0] == admin",
+		`schema {"type": "object", "required": ["id"]}`,
+		"schema ./schemas/user.json",
+		"schema 201 ./schemas/user.json",
+		// Only checked for 404 responses
+		`schema 404 {"type": "array"}`,
+		"latency < 10000",
+	}
+	response := runAssertions(t, asserts...)
+	for _, failed := range response.Failed {
+		t.Errorf("%s failed: %s", failed.Description, failed.Message)
+	}
+	if len(response.Passed) != len(asserts) {
+		t.Errorf("%d assertions passed, want %d", len(response.Passed), len(asserts))
+	}
+}
+
+func TestAssertionFailureMessages(t *testing.T) {
+	tests := []struct {
+		assert  string
+		message string
+	}{
+		{"status == 200", "expected status == 200, got 201"},
+		{"status 4xx", "expected status == 4xx, got 201"},
+		{"header X-Missing exists", "header X-Missing is missing"},
+		{"header Content-Type == text/html", "expected header Content-Type == text/html, got application/json"},
+		{"jsonpath $.id > 10", "expected $.id > 10, got 7"},
+		{"jsonpath $.email exists", "$.email matched nothing"},
+		{`schema {"type": "object", "required": ["email"]}`, "body does not match schema"},
+		{"schema ./schemas/missing.json", "failed to read schema"},
+		{`schema 2xx {"type": "array"}`, "body does not match schema"},
+		{"latency > 100000", "expected latency > 100000, got"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.assert, func(t *testing.T) {
+			response := runAssertions(t, tt.assert)
+			if len(response.Failed) != 1 {
+				t.Fatalf("assertion did not fail: %+v", response.Passed)
+			}
+			if message := response.Failed[0].Message; !strings.Contains(message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", message, tt.message)
+			}
+		})
+	}
+}
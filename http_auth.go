@@ -0,0 +1,995 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========== HTTP Authentication ==========
+
+// HTTPAuth authenticates or signs a request as it is sent. Type is
+// "basic", "bearer", "digest", "aws" (Signature V4), "oauth1", "oauth2" or
+// "hmac"; each reads its own group of fields.
+type HTTPAuth struct {
+	Type string `json:"type"`
+
+	// Basic, Digest and the OAuth 2.0 password grant
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// Token is the Bearer token, or the OAuth 1.0a access token
+	Token string `json:"token,omitempty"`
+
+	// AWS Signature V4. Region and Service are told from an amazonaws.com
+	// host when empty.
+	AccessKey    string `json:"accessKey,omitempty"`
+	SecretKey    string `json:"secretKey,omitempty"`
+	SessionToken string `json:"sessionToken,omitempty"`
+	Region       string `json:"region,omitempty"`
+	Service      string `json:"service,omitempty"`
+
+	// OAuth 1.0a. SignatureMethod is HMAC-SHA1 (default), HMAC-SHA256,
+	// HMAC-SHA512 or PLAINTEXT; Placement is "header" (default) or "query".
+	ConsumerKey     string `json:"consumerKey,omitempty"`
+	ConsumerSecret  string `json:"consumerSecret,omitempty"`
+	TokenSecret     string `json:"tokenSecret,omitempty"`
+	SignatureMethod string `json:"signatureMethod,omitempty"`
+	Realm           string `json:"realm,omitempty"`
+	Callback        string `json:"callback,omitempty"`
+	Verifier        string `json:"verifier,omitempty"`
+	Placement       string `json:"placement,omitempty"`
+
+	// OAuth 2.0. GrantType is client_credentials (default), password,
+	// refresh_token, device_code or authorization_code, which uses PKCE
+	// and a loopback RedirectURI (default http://127.0.0.1:<free
+	// port>/callback). ClientAuth sends the client's credentials as a
+	// Basic "header" (default) or in the "body".
+	GrantType     string `json:"grantType,omitempty"`
+	TokenURL      string `json:"tokenUrl,omitempty"`
+	AuthURL       string `json:"authUrl,omitempty"`
+	DeviceAuthURL string `json:"deviceAuthUrl,omitempty"`
+	ClientID      string `json:"clientId,omitempty"`
+	ClientSecret  string `json:"clientSecret,omitempty"`
+	ClientAuth    string `json:"clientAuth,omitempty"`
+	Scope         string `json:"scope,omitempty"`
+	Audience      string `json:"audience,omitempty"`
+	RefreshToken  string `json:"refreshToken,omitempty"`
+	RedirectURI   string `json:"redirectUri,omitempty"`
+
+	// HMAC signs CanonicalString, a template such as
+	// "{method}\n{path}\n{timestamp}\n{bodySha256}", with Secret and puts
+	// the signature in Header (default Authorization) using the Value
+	// template (default "{signature}"). See expandSigningTemplate for the
+	// placeholders.
+	KeyID           string `json:"keyId,omitempty"`
+	Secret          string `json:"secret,omitempty"`
+	SecretEncoding  string `json:"secretEncoding,omitempty"` // "", "base64" or "hex"
+	Algorithm       string `json:"algorithm,omitempty"`      // sha256 (default), sha1, sha384, sha512 or md5
+	CanonicalString string `json:"canonicalString,omitempty"`
+	Header          string `json:"header,omitempty"`
+	Value           string `json:"value,omitempty"`
+	Encoding        string `json:"encoding,omitempty"` // hex (default), base64 or base64url
+	// TimestampFormat is unix (default), unixms, rfc1123, iso8601 or a Go
+	// time layout
+	TimestampFormat string `json:"timestampFormat,omitempty"`
+	// Headers are set before signing, so the canonical string can include
+	// them; values are templates, e.g. "X-Timestamp": "{timestamp}"
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// authFields names the text fields of HTTPAuth, in the order @auth
+// annotations write them
+var authFields = []struct {
+	name  string
+	field func(*HTTPAuth) *string
+}{
+	{"username", func(a *HTTPAuth) *string { return &a.Username }},
+	{"password", func(a *HTTPAuth) *string { return &a.Password }},
+	{"token", func(a *HTTPAuth) *string { return &a.Token }},
+	{"accessKey", func(a *HTTPAuth) *string { return &a.AccessKey }},
+	{"secretKey", func(a *HTTPAuth) *string { return &a.SecretKey }},
+	{"sessionToken", func(a *HTTPAuth) *string { return &a.SessionToken }},
+	{"region", func(a *HTTPAuth) *string { return &a.Region }},
+	{"service", func(a *HTTPAuth) *string { return &a.Service }},
+	{"consumerKey", func(a *HTTPAuth) *string { return &a.ConsumerKey }},
+	{"consumerSecret", func(a *HTTPAuth) *string { return &a.ConsumerSecret }},
+	{"tokenSecret", func(a *HTTPAuth) *string { return &a.TokenSecret }},
+	{"signatureMethod", func(a *HTTPAuth) *string { return &a.SignatureMethod }},
+	{"realm", func(a *HTTPAuth) *string { return &a.Realm }},
+	{"callback", func(a *HTTPAuth) *string { return &a.Callback }},
+	{"verifier", func(a *HTTPAuth) *string { return &a.Verifier }},
+	{"placement", func(a *HTTPAuth) *string { return &a.Placement }},
+	{"grantType", func(a *HTTPAuth) *string { return &a.GrantType }},
+	{"tokenUrl", func(a *HTTPAuth) *string { return &a.TokenURL }},
+	{"authUrl", func(a *HTTPAuth) *string { return &a.AuthURL }},
+	{"deviceAuthUrl", func(a *HTTPAuth) *string { return &a.DeviceAuthURL }},
+	{"clientId", func(a *HTTPAuth) *string { return &a.ClientID }},
+	{"clientSecret", func(a *HTTPAuth) *string { return &a.ClientSecret }},
+	{"clientAuth", func(a *HTTPAuth) *string { return &a.ClientAuth }},
+	{"scope", func(a *HTTPAuth) *string { return &a.Scope }},
+	{"audience", func(a *HTTPAuth) *string { return &a.Audience }},
+	{"refreshToken", func(a *HTTPAuth) *string { return &a.RefreshToken }},
+	{"redirectUri", func(a *HTTPAuth) *string { return &a.RedirectURI }},
+	{"keyId", func(a *HTTPAuth) *string { return &a.KeyID }},
+	{"secret", func(a *HTTPAuth) *string { return &a.Secret }},
+	{"secretEncoding", func(a *HTTPAuth) *string { return &a.SecretEncoding }},
+	{"algorithm", func(a *HTTPAuth) *string { return &a.Algorithm }},
+	{"canonicalString", func(a *HTTPAuth) *string { return &a.CanonicalString }},
+	{"header", func(a *HTTPAuth) *string { return &a.Header }},
+	{"value", func(a *HTTPAuth) *string { return &a.Value }},
+	{"encoding", func(a *HTTPAuth) *string { return &a.Encoding }},
+	{"timestampFormat", func(a *HTTPAuth) *string { return &a.TimestampFormat }},
+}
+
+// resolveAuth expands variables in every field of an auth block
+func (r *variableResolver) resolveAuth(auth *HTTPAuth) (*HTTPAuth, error) {
+	resolved := *auth
+	var err error
+	for _, f := range authFields {
+		s := f.field(&resolved)
+		if *s, err = r.resolve(*s); err != nil {
+			return nil, err
+		}
+	}
+	if auth.Headers != nil {
+		resolved.Headers = make(map[string]string, len(auth.Headers))
+		for name, value := range auth.Headers {
+			if resolved.Headers[name], err = r.resolve(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &resolved, nil
+}
+
+// applyAuth authenticates req once its headers and body are in place.
+// Digest auth is left to answerAuthChallenge, as it needs the server's
+// nonce, and OAuth 2.0 to authorizeOAuth2, as it needs the token cache.
+func applyAuth(req *http.Request, auth *HTTPAuth, payload *requestPayload) error {
+	if auth == nil {
+		return nil
+	}
+	return signRequest(req, auth, payload, time.Now(), newAuthNonce())
+}
+
+// signRequest applies auth at the given time with the given nonce
+func signRequest(req *http.Request, auth *HTTPAuth, payload *requestPayload, now time.Time, nonce string) error {
+	switch strings.ToLower(auth.Type) {
+	case "", "none", "digest", "oauth2":
+		return nil
+	case "basic":
+		req.SetBasicAuth(auth.Username, auth.Password)
+		return nil
+	case "bearer":
+		if auth.Token == "" {
+			return fmt.Errorf("bearer auth needs a token")
+		}
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+		return nil
+	case "aws":
+		return signAWS(req, auth, payload, now)
+	case "oauth1":
+		return signOAuth1(req, auth, payload, now, nonce)
+	case "hmac":
+		return signHMAC(req, auth, payload, now, nonce)
+	}
+	return fmt.Errorf("unknown auth type %q", auth.Type)
+}
+
+// newAuthNonce returns a random hex nonce
+func newAuthNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// payloadHash hashes the request body, streaming any uploaded files
+func payloadHash(payload *requestPayload, newHash func() hash.Hash) ([]byte, error) {
+	h := newHash()
+	if payload != nil && payload.length > 0 {
+		body, err := payload.open()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		if _, err := io.Copy(h, body); err != nil {
+			return nil, err
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+// payloadText reads the whole request body
+func payloadText(payload *requestPayload) (string, error) {
+	if payload == nil || payload.length == 0 {
+		return "", nil
+	}
+	body, err := payload.open()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	return string(data), err
+}
+
+// sortedQueryPairs returns query parameters percent-encoded and sorted by
+// name, then value, as both AWS and OAuth 1.0a normalise them
+func sortedQueryPairs(values url.Values) []string {
+	pairs := make([][2]string, 0, len(values))
+	for key, list := range values {
+		for _, value := range list {
+			pairs = append(pairs, [2]string{percentEncode(key), percentEncode(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	joined := make([]string, len(pairs))
+	for i, pair := range pairs {
+		joined[i] = pair[0] + "=" + pair[1]
+	}
+	return joined
+}
+
+// ========== Digest ==========
+
+// digestHashes maps Digest algorithms, without any -sess suffix, to
+// their hashes
+var digestHashes = map[string]func() hash.Hash{
+	"MD5":         md5.New,
+	"SHA-256":     sha256.New,
+	"SHA-512-256": sha512.New512_256,
+}
+
+// answerAuthChallenge repeats a request that got a Digest challenge in a
+// 401 response, this time with credentials. Other responses are returned
+// as they are.
+func answerAuthChallenge(client *http.Client, req *http.Request, resp *http.Response, auth *HTTPAuth, payload *requestPayload) (*http.Response, error) {
+	if auth == nil || !strings.EqualFold(auth.Type, "digest") || resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	challenge, ok := digestChallenge(resp.Header)
+	if !ok {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	retry.Body = http.NoBody
+	if payload.length > 0 {
+		body, err := payload.open()
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		retry.Body = body
+	}
+	authorization, err := digestAuthorization(retry, auth, challenge, payload, newAuthNonce())
+	if err != nil {
+		retry.Body.Close()
+		resp.Body.Close()
+		return nil, fmt.Errorf("digest auth: %v", err)
+	}
+	retry.Header.Set("Authorization", authorization)
+
+	// Drain the challenge so its connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	return client.Do(retry)
+}
+
+// digestChallenge finds the parameters of a Digest challenge among the
+// WWW-Authenticate headers
+func digestChallenge(header http.Header) (map[string]string, bool) {
+	for _, value := range header.Values("WWW-Authenticate") {
+		lower := strings.ToLower(value)
+		for i := 0; i < len(lower); {
+			j := strings.Index(lower[i:], "digest ")
+			if j < 0 {
+				break
+			}
+			j += i
+			// A scheme starts the header or follows a comma
+			if prefix := strings.TrimRight(lower[:j], " \t"); prefix == "" || strings.HasSuffix(prefix, ",") {
+				params := parseAuthParams(value[j+len("digest "):])
+				if params["nonce"] != "" {
+					return params, true
+				}
+			}
+			i = j + len("digest ")
+		}
+	}
+	return nil, false
+}
+
+// parseAuthParams reads name=value and name="quoted value" parameters of
+// a challenge, stopping at the next scheme
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return params
+		}
+		key := strings.TrimSpace(s[:eq])
+		if strings.ContainsAny(key, " \t,") {
+			return params
+		}
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value, s = b.String(), s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = strings.TrimSpace(s[:end]), s[end:]
+		}
+		params[strings.ToLower(key)] = value
+	}
+}
+
+// digestAuthorization answers a Digest challenge (RFC 7616), preferring
+// qop=auth over auth-int
+func digestAuthorization(req *http.Request, auth *HTTPAuth, challenge map[string]string, payload *requestPayload, cnonce string) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	base, sess := strings.CutSuffix(strings.ToUpper(algorithm), "-SESS")
+	newHash, ok := digestHashes[base]
+	if !ok {
+		return "", fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+	h := func(s string) string {
+		sum := newHash()
+		io.WriteString(sum, s)
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	qop := ""
+	for _, option := range strings.Split(challenge["qop"], ",") {
+		switch option = strings.TrimSpace(option); option {
+		case "auth":
+			qop = option
+		case "auth-int":
+			if qop == "" {
+				qop = option
+			}
+		}
+	}
+	if challenge["qop"] != "" && qop == "" {
+		return "", fmt.Errorf("unsupported qop %s", challenge["qop"])
+	}
+
+	realm, nonce := challenge["realm"], challenge["nonce"]
+	uri := req.URL.RequestURI()
+	nc := "00000001"
+
+	ha1 := h(auth.Username + ":" + realm + ":" + auth.Password)
+	if sess {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(req.Method + ":" + uri)
+	if qop == "auth-int" {
+		sum, err := payloadHash(payload, newHash)
+		if err != nil {
+			return "", err
+		}
+		ha2 = h(req.Method + ":" + uri + ":" + hex.EncodeToString(sum))
+	}
+	response := h(ha1 + ":" + nonce + ":" + ha2)
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, nonce, nc, cnonce, qop, ha2}, ":"))
+	}
+
+	parts := []string{
+		fmt.Sprintf(`username="%s"`, escapeQuotes(auth.Username)),
+		fmt.Sprintf(`realm="%s"`, escapeQuotes(realm)),
+		fmt.Sprintf(`nonce="%s"`, escapeQuotes(nonce)),
+		fmt.Sprintf(`uri="%s"`, escapeQuotes(uri)),
+		"algorithm=" + algorithm,
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if qop != "" {
+		parts = append(parts, "qop="+qop, "nc="+nc, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	if opaque, ok := challenge["opaque"]; ok {
+		parts = append(parts, fmt.Sprintf(`opaque="%s"`, escapeQuotes(opaque)))
+	}
+	return "Digest " + strings.Join(parts, ", "), nil
+}
+
+// ========== AWS Signature V4 ==========
+
+// awsRegionPattern matches region labels such as us-east-1 and
+// us-gov-west-1
+var awsRegionPattern = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d+$`)
+
+// awsScopeFromHost tells the region and service from an AWS endpoint such
+// as dynamodb.eu-west-1.amazonaws.com or bucket.s3.amazonaws.com. Global
+// endpoints are in us-east-1.
+func awsScopeFromHost(host string) (region string, service string) {
+	host = strings.ToLower(host)
+	trimmed := strings.TrimSuffix(strings.TrimSuffix(host, ".cn"), ".amazonaws.com")
+	if trimmed == strings.TrimSuffix(host, ".cn") {
+		return "", ""
+	}
+	labels := strings.Split(trimmed, ".")
+	for i, label := range labels {
+		if awsRegionPattern.MatchString(label) {
+			if i > 0 {
+				service = labels[i-1]
+			}
+			return label, service
+		}
+		// Legacy s3-us-west-2.amazonaws.com style hosts
+		if rest, ok := strings.CutPrefix(label, "s3-"); ok && awsRegionPattern.MatchString(rest) {
+			return rest, "s3"
+		}
+	}
+	return "us-east-1", labels[len(labels)-1]
+}
+
+// signAWS adds AWS Signature V4 headers. The path and query are rewritten
+// in the canonical encoding so what is sent matches what is signed.
+func signAWS(req *http.Request, auth *HTTPAuth, payload *requestPayload, now time.Time) error {
+	if auth.AccessKey == "" || auth.SecretKey == "" {
+		return fmt.Errorf("aws auth needs an access key and a secret key")
+	}
+	region, service := auth.Region, auth.Service
+	if region == "" || service == "" {
+		hostRegion, hostService := awsScopeFromHost(req.URL.Hostname())
+		if region == "" {
+			region = hostRegion
+		}
+		if service == "" {
+			service = hostService
+		}
+	}
+	if region == "" || service == "" {
+		return fmt.Errorf("aws auth needs a region and a service for %s", req.URL.Hostname())
+	}
+
+	sum, err := payloadHash(payload, sha256.New)
+	if err != nil {
+		return err
+	}
+	bodyHash := hex.EncodeToString(sum)
+	t := now.UTC()
+	amzDate, date := t.Format("20060102T150405Z"), t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if auth.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", auth.SessionToken)
+	}
+	if service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", bodyHash)
+	}
+
+	// Paths are encoded once for S3 and twice for other services
+	segments := strings.Split(req.URL.Path, "/")
+	for i, segment := range segments {
+		segments[i] = percentEncode(segment)
+	}
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	req.URL.RawPath = path
+	canonicalPath := path
+	if service != "s3" {
+		for i, segment := range segments {
+			segments[i] = percentEncode(segment)
+		}
+		if canonicalPath = strings.Join(segments, "/"); canonicalPath == "" {
+			canonicalPath = "/"
+		}
+	}
+	query, _ := url.ParseQuery(req.URL.RawQuery)
+	req.URL.RawQuery = strings.Join(sortedQueryPairs(query), "&")
+
+	// Sign every header set so far; the transport only adds User-Agent
+	// and Content-Length
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for name, list := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "authorization" || lower == "user-agent" || lower == "content-length" {
+			continue
+		}
+		trimmed := make([]string, len(list))
+		for i, value := range list {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		values[lower] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, canonicalPath, req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, bodyHash,
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + auth.SecretKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSum(sha256.New, key, part)
+	}
+	signature := hex.EncodeToString(hmacSum(sha256.New, key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		auth.AccessKey, scope, signedHeaders, signature))
+	return nil
+}
+
+// hmacSum returns the HMAC of message under key
+func hmacSum(newHash func() hash.Hash, key []byte, message string) []byte {
+	mac := hmac.New(newHash, key)
+	io.WriteString(mac, message)
+	return mac.Sum(nil)
+}
+
+// ========== OAuth 1.0a ==========
+
+// oauth1Hashes maps OAuth 1.0a HMAC signature methods to their hashes
+var oauth1Hashes = map[string]func() hash.Hash{
+	"HMAC-SHA1":   sha1.New,
+	"HMAC-SHA256": sha256.New,
+	"HMAC-SHA512": sha512.New,
+}
+
+// signOAuth1 signs a request as RFC 5849 describes, including query and
+// URL-encoded form parameters in the signature base string
+func signOAuth1(req *http.Request, auth *HTTPAuth, payload *requestPayload, now time.Time, nonce string) error {
+	if auth.ConsumerKey == "" {
+		return fmt.Errorf("oauth1 auth needs a consumer key")
+	}
+	method := strings.ToUpper(auth.SignatureMethod)
+	if method == "" {
+		method = "HMAC-SHA1"
+	}
+	newHash, ok := oauth1Hashes[method]
+	if !ok && method != "PLAINTEXT" {
+		return fmt.Errorf("unsupported OAuth signature method %s", auth.SignatureMethod)
+	}
+
+	oauth := url.Values{}
+	oauth.Set("oauth_consumer_key", auth.ConsumerKey)
+	oauth.Set("oauth_nonce", nonce)
+	oauth.Set("oauth_signature_method", method)
+	oauth.Set("oauth_timestamp", strconv.FormatInt(now.Unix(), 10))
+	oauth.Set("oauth_version", "1.0")
+	if auth.Token != "" {
+		oauth.Set("oauth_token", auth.Token)
+	}
+	if auth.Callback != "" {
+		oauth.Set("oauth_callback", auth.Callback)
+	}
+	if auth.Verifier != "" {
+		oauth.Set("oauth_verifier", auth.Verifier)
+	}
+
+	params, _ := url.ParseQuery(req.URL.RawQuery)
+	if mediaType, _, _ := strings.Cut(req.Header.Get("Content-Type"), ";"); strings.EqualFold(strings.TrimSpace(mediaType), "application/x-www-form-urlencoded") {
+		body, err := payloadText(payload)
+		if err != nil {
+			return err
+		}
+		form, _ := url.ParseQuery(body)
+		for key, list := range form {
+			params[key] = append(params[key], list...)
+		}
+	}
+	for key, list := range oauth {
+		params[key] = append(params[key], list...)
+	}
+
+	key := percentEncode(auth.ConsumerSecret) + "&" + percentEncode(auth.TokenSecret)
+	signature := key
+	if method != "PLAINTEXT" {
+		baseURL := url.URL{Scheme: strings.ToLower(req.URL.Scheme), Host: strings.ToLower(req.URL.Host), Path: req.URL.Path, RawPath: req.URL.RawPath}
+		if port := baseURL.Port(); port == "80" && baseURL.Scheme == "http" || port == "443" && baseURL.Scheme == "https" {
+			baseURL.Host = baseURL.Hostname()
+		}
+		base := strings.Join([]string{
+			req.Method,
+			percentEncode(baseURL.String()),
+			percentEncode(strings.Join(sortedQueryPairs(params), "&")),
+		}, "&")
+		signature = base64.StdEncoding.EncodeToString(hmacSum(newHash, []byte(key), base))
+	}
+	oauth.Set("oauth_signature", signature)
+
+	if strings.EqualFold(auth.Placement, "query") {
+		pairs := sortedQueryPairs(oauth)
+		if req.URL.RawQuery != "" {
+			pairs = append([]string{req.URL.RawQuery}, pairs...)
+		}
+		req.URL.RawQuery = strings.Join(pairs, "&")
+		return nil
+	}
+
+	var parts []string
+	if auth.Realm != "" {
+		parts = append(parts, fmt.Sprintf(`realm="%s"`, percentEncode(auth.Realm)))
+	}
+	for _, pair := range sortedQueryPairs(oauth) {
+		name, value, _ := strings.Cut(pair, "=")
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, name, value))
+	}
+	req.Header.Set("Authorization", "OAuth "+strings.Join(parts, ", "))
+	return nil
+}
+
+// ========== HMAC ==========
+
+// hmacHashes maps the algorithms of generic HMAC auth to their hashes
+var hmacHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// signingPlaceholderPattern matches {name} and {header:Name} placeholders
+var signingPlaceholderPattern = regexp.MustCompile(`\{([A-Za-z0-9]+)(?::([^{}]+))?\}`)
+
+// signHMAC signs a request with a partner-specific HMAC scheme
+func signHMAC(req *http.Request, auth *HTTPAuth, payload *requestPayload, now time.Time, nonce string) error {
+	algorithm := strings.ToLower(strings.ReplaceAll(auth.Algorithm, "-", ""))
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	newHash, ok := hmacHashes[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported HMAC algorithm %s", auth.Algorithm)
+	}
+	if auth.CanonicalString == "" {
+		return fmt.Errorf("hmac auth needs a canonical string")
+	}
+
+	var secret []byte
+	var err error
+	switch strings.ToLower(auth.SecretEncoding) {
+	case "":
+		secret = []byte(auth.Secret)
+	case "base64":
+		secret, err = base64.StdEncoding.DecodeString(auth.Secret)
+	case "hex":
+		secret, err = hex.DecodeString(auth.Secret)
+	default:
+		return fmt.Errorf("unknown secret encoding %s", auth.SecretEncoding)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s secret: %v", auth.SecretEncoding, err)
+	}
+
+	values := map[string]string{
+		"timestamp": formatAuthTimestamp(now, auth.TimestampFormat),
+		"nonce":     nonce,
+		"keyId":     auth.KeyID,
+	}
+	names := make([]string, 0, len(auth.Headers))
+	for name := range auth.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := expandSigningTemplate(auth.Headers[name], req, payload, values)
+		if err != nil {
+			return fmt.Errorf("header %s: %v", name, err)
+		}
+		req.Header.Set(name, value)
+	}
+
+	canonical, err := expandSigningTemplate(auth.CanonicalString, req, payload, values)
+	if err != nil {
+		return fmt.Errorf("canonical string: %v", err)
+	}
+	sum := hmacSum(newHash, secret, canonical)
+	switch strings.ToLower(auth.Encoding) {
+	case "", "hex":
+		values["signature"] = hex.EncodeToString(sum)
+	case "base64":
+		values["signature"] = base64.StdEncoding.EncodeToString(sum)
+	case "base64url":
+		values["signature"] = base64.RawURLEncoding.EncodeToString(sum)
+	default:
+		return fmt.Errorf("unknown signature encoding %s", auth.Encoding)
+	}
+
+	header, template := auth.Header, auth.Value
+	if header == "" {
+		header = "Authorization"
+	}
+	if template == "" {
+		template = "{signature}"
+	}
+	value, err := expandSigningTemplate(template, req, payload, values)
+	if err != nil {
+		return fmt.Errorf("header %s: %v", header, err)
+	}
+	req.Header.Set(header, value)
+	return nil
+}
+
+// formatAuthTimestamp writes a signing timestamp in the named format, or
+// with format as a Go time layout
+func formatAuthTimestamp(now time.Time, format string) string {
+	switch strings.ToLower(format) {
+	case "", "unix":
+		return strconv.FormatInt(now.Unix(), 10)
+	case "unixms":
+		return strconv.FormatInt(now.UnixMilli(), 10)
+	case "rfc1123":
+		return now.UTC().Format(http.TimeFormat)
+	case "iso8601":
+		return now.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return now.UTC().Format(format)
+}
+
+// expandSigningTemplate fills the placeholders of an HMAC template:
+// {method}, {url}, {scheme}, {host}, {path}, {query}, {target} (path and
+// query), {sortedQuery}, {contentType}, {body}, {bodySha256},
+// {bodySha256Base64}, {bodyMd5}, {bodyMd5Base64}, {header:Name},
+// {timestamp}, {nonce}, {keyId} and, in the header value, {signature}
+func expandSigningTemplate(template string, req *http.Request, payload *requestPayload, values map[string]string) (string, error) {
+	var failed error
+	result := signingPlaceholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		m := signingPlaceholderPattern.FindStringSubmatch(match)
+		value, err := signingPlaceholder(m[1], m[2], req, payload, values)
+		if err != nil && failed == nil {
+			failed = err
+		}
+		return value
+	})
+	return result, failed
+}
+
+// signingPlaceholder returns the value of one template placeholder
+func signingPlaceholder(name string, arg string, req *http.Request, payload *requestPayload, values map[string]string) (string, error) {
+	if value, ok := values[name]; ok && arg == "" {
+		return value, nil
+	}
+	hashBody := func(newHash func() hash.Hash, encode func([]byte) string) (string, error) {
+		sum, err := payloadHash(payload, newHash)
+		if err != nil {
+			return "", err
+		}
+		return encode(sum), nil
+	}
+
+	switch name {
+	case "method":
+		return req.Method, nil
+	case "url":
+		return req.URL.String(), nil
+	case "scheme":
+		return req.URL.Scheme, nil
+	case "host":
+		if req.Host != "" {
+			return req.Host, nil
+		}
+		return req.URL.Host, nil
+	case "path":
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "query":
+		return req.URL.RawQuery, nil
+	case "target":
+		return req.URL.RequestURI(), nil
+	case "sortedQuery":
+		query, _ := url.ParseQuery(req.URL.RawQuery)
+		return strings.Join(sortedQueryPairs(query), "&"), nil
+	case "contentType":
+		return req.Header.Get("Content-Type"), nil
+	case "header":
+		return strings.Join(req.Header.Values(arg), ","), nil
+	case "body":
+		return payloadText(payload)
+	case "bodySha256":
+		return hashBody(sha256.New, hex.EncodeToString)
+	case "bodySha256Base64":
+		return hashBody(sha256.New, base64.StdEncoding.EncodeToString)
+	case "bodyMd5":
+		return hashBody(md5.New, hex.EncodeToString)
+	case "bodyMd5Base64":
+		return hashBody(md5.New, base64.StdEncoding.EncodeToString)
+	}
+	return "", fmt.Errorf("unknown placeholder {%s}", name)
+}
+
+// ========== Export ==========
+
+// exportAuth turns a request's auth block into the headers it would send,
+// for code that cannot sign requests itself. Signatures are computed now,
+// so they expire as the server's clock allows. With native set, Digest
+// and AWS auth are kept for cURL, which does both itself. OAuth 2.0 auth
+// must already have been replaced by its token.
+func exportAuth(request HTTPRequest, native bool) (HTTPRequest, error) {
+	auth := request.Auth
+	if auth == nil {
+		return request, nil
+	}
+	switch strings.ToLower(auth.Type) {
+	case "digest":
+		if native {
+			return request, nil
+		}
+		return request, fmt.Errorf("Digest auth can only be exported to cURL")
+	case "aws":
+		if native {
+			return request, nil
+		}
+	}
+
+	flat, err := flattenBody(request)
+	if err != nil {
+		return request, fmt.Errorf("Invalid body: %v", err)
+	}
+	payload, err := newRequestPayload(flat)
+	if err != nil {
+		return request, fmt.Errorf("Invalid body: %v", err)
+	}
+	method := strings.ToUpper(flat.Method)
+	if method == "" {
+		method = "GET"
+	}
+	req, err := http.NewRequest(method, flat.URL, nil)
+	if err != nil {
+		return request, fmt.Errorf("Invalid request: %v", err)
+	}
+	for key, value := range flat.Headers {
+		req.Header.Set(key, value)
+	}
+	if err := applyAuth(req, auth, payload); err != nil {
+		return request, fmt.Errorf("Auth error: %v", err)
+	}
+
+	headers := make(map[string]string, len(request.Headers)+2)
+	for key, value := range request.Headers {
+		headers[key] = value
+	}
+	for name := range req.Header {
+		value := req.Header.Get(name)
+		if current, ok := lookupHeader(headers, name); ok && current == value {
+			continue
+		}
+		deleteHeader(headers, name)
+		headers[name] = value
+	}
+	request.Headers = headers
+	request.URL = req.URL.String()
+	request.Auth = nil
+	return request, nil
+}
+
+// ========== .http Annotations ==========
+
+// parseAuthAnnotation parses the text after "@auth" in a .http file: the
+// type followed by name=value fields, e.g.
+// `aws accessKey={{key}} secretKey={{secret}} region=eu-west-1`. Values
+// with spaces are double-quoted with Go escapes, and header.Name=value
+// sets an HMAC header.
+func parseAuthAnnotation(text string) (*HTTPAuth, bool) {
+	text = strings.TrimSpace(text)
+	authType, rest, _ := strings.Cut(text, " ")
+	if authType == "" {
+		return nil, false
+	}
+	auth := &HTTPAuth{Type: strings.ToLower(authType)}
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		name, value, ok := strings.Cut(rest, "=")
+		if !ok || strings.ContainsAny(name, " \t") {
+			return nil, false
+		}
+		rest = value
+		if strings.HasPrefix(rest, `"`) {
+			end := 1
+			for end < len(rest) && rest[end] != '"' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rest) {
+				return nil, false
+			}
+			unquoted, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			value, rest = unquoted, rest[end+1:]
+		} else {
+			value, rest, _ = strings.Cut(rest, " ")
+		}
+
+		if header, ok := strings.CutPrefix(name, "header."); ok {
+			if auth.Headers == nil {
+				auth.Headers = make(map[string]string)
+			}
+			auth.Headers[header] = value
+			continue
+		}
+		for _, f := range authFields {
+			if strings.EqualFold(f.name, name) {
+				*f.field(auth) = value
+				break
+			}
+		}
+	}
+	return auth, true
+}
+
+// formatAuthAnnotation writes an auth block as parseAuthAnnotation reads it
+func formatAuthAnnotation(auth *HTTPAuth) string {
+	quote := func(value string) string {
+		if value != "" && !strings.ContainsAny(value, " \t\"\\") && strconv.Quote(value) == `"`+value+`"` {
+			return value
+		}
+		return strconv.Quote(value)
+	}
+
+	parts := []string{auth.Type}
+	for _, f := range authFields {
+		if value := *f.field(auth); value != "" {
+			parts = append(parts, f.name+"="+quote(value))
+		}
+	}
+	names := make([]string, 0, len(auth.Headers))
+	for name := range auth.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, "header."+name+"="+quote(auth.Headers[name]))
+	}
+	return strings.Join(parts, " ")
+}
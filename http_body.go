@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ========== HTTP Response Bodies ==========
+
+// HTTPHeader is one header line; repeated headers appear once per value
+type HTTPHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// headerList flattens headers, sorted by name with values in order
+func headerList(header http.Header) []HTTPHeader {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := []HTTPHeader{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			list = append(list, HTTPHeader{Name: name, Value: value})
+		}
+	}
+	return list
+}
+
+// setResponseBody stores body as text, or as Base64 if it is binary. Text
+// in a declared non-UTF-8 charset is converted to UTF-8; if that fails the
+// bytes are kept as they are.
+func setResponseBody(response *HTTPResponse, contentType string, body []byte) {
+	if decoded, charset, err := transcodeCharset(contentType, body); err == nil && charset != "" {
+		if isTextBody(contentType, decoded, response.Truncated) {
+			response.Body = string(decoded)
+			response.Charset = charset
+			return
+		}
+	}
+	if isTextBody(contentType, body, response.Truncated) {
+		response.Body = string(body)
+		return
+	}
+	response.Binary = true
+	response.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+}
+
+// responseBodyBytes returns the raw body of a response
+func responseBodyBytes(response HTTPResponse) []byte {
+	if response.Binary {
+		data, _ := base64.StdEncoding.DecodeString(response.BodyBase64)
+		return data
+	}
+	return []byte(response.Body)
+}
+
+// isTextBody decides from the content type, or by sniffing the bytes when
+// the type is missing or unknown. A truncated body may end mid-character.
+func isTextBody(contentType string, body []byte, truncated bool) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch {
+		case strings.HasPrefix(mediaType, "text/"),
+			strings.HasSuffix(mediaType, "+json"),
+			strings.HasSuffix(mediaType, "+xml"),
+			strings.HasSuffix(mediaType, "+yaml"):
+			return true
+		case strings.HasPrefix(mediaType, "image/"),
+			strings.HasPrefix(mediaType, "audio/"),
+			strings.HasPrefix(mediaType, "video/"),
+			strings.HasPrefix(mediaType, "font/"):
+			return false
+		}
+		switch mediaType {
+		case "application/json", "application/xml", "application/javascript",
+			"application/ecmascript", "application/x-www-form-urlencoded",
+			"application/graphql", "application/yaml", "application/x-yaml",
+			"application/toml", "application/sql", "application/x-ndjson":
+			return true
+		case "application/octet-stream", "application/pdf", "application/zip",
+			"application/gzip", "application/x-protobuf", "application/protobuf",
+			"application/msgpack", "application/x-msgpack", "application/cbor":
+			return false
+		}
+	}
+
+	if truncated {
+		// Drop a partial rune at the cut
+		for i := 0; i < utf8.UTFMax && len(body) > 0 && !utf8.Valid(body); i++ {
+			body = body[:len(body)-1]
+		}
+	}
+	return utf8.Valid(body) && !strings.ContainsRune(string(body), 0)
+}
+
+// saveResponseBody streams a body to a file in storage, never overwriting
+// an existing file. Relative targets are relative to the storage root.
+func (a *App) saveResponseBody(target string, body io.Reader) (string, int64, error) {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(a.storagePath, target)
+	}
+	absPath, err := a.resolveStoragePath(target)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return "", 0, err
+	}
+
+	absPath = uniquePath(absPath)
+	file, err := os.Create(absPath)
+	if err != nil {
+		return "", 0, err
+	}
+	n, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return absPath, n, err
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ========== HTTP Captures and Sessions ==========
+
+// defaultHTTPSession is used when a request doesn't name a session
+const defaultHTTPSession = "default"
+
+// HTTPCapture extracts a value from a response into a session variable
+type HTTPCapture struct {
+	Name string `json:"name"`
+	// Source is "body" (JSONPath), "header", "regex" (on the body) or "status"
+	Source     string `json:"source"`
+	Expression string `json:"expression"`
+}
+
+// httpExchange is a named request and its response, kept for
+// {{name.response.body.$.path}} style references
+type httpExchange struct {
+	request  HTTPRequest
+	response HTTPResponse
+}
+
+// httpSession holds the variables captured by requests sharing a session
+type httpSession struct {
+	mu        sync.Mutex
+	variables map[string]string
+	exchanges map[string]httpExchange
+}
+
+// session returns the named session, creating it on first use
+func (a *App) session(id string) *httpSession {
+	if id == "" {
+		id = defaultHTTPSession
+	}
+
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	if a.sessions == nil {
+		a.sessions = make(map[string]*httpSession)
+	}
+	s, ok := a.sessions[id]
+	if !ok {
+		s = &httpSession{
+			variables: make(map[string]string),
+			exchanges: make(map[string]httpExchange),
+		}
+		a.sessions[id] = s
+	}
+	return s
+}
+
+// ListHTTPSessions returns the IDs of sessions holding variables
+func (a *App) ListHTTPSessions() []string {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+
+	ids := make([]string, 0, len(a.sessions))
+	for id := range a.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// GetHTTPSessionVariables returns the variables captured in a session.
+// Runs of a .http file use the file's ID (its path relative to storage).
+func (a *App) GetHTTPSessionVariables(session string) map[string]string {
+	s := a.session(session)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	variables := make(map[string]string, len(s.variables))
+	for key, value := range s.variables {
+		variables[key] = value
+	}
+	return variables
+}
+
+// SetHTTPSessionVariable sets or, with an empty value, removes a variable
+func (a *App) SetHTTPSessionVariable(session string, key string, value string) FileSystemResponse {
+	if strings.TrimSpace(key) == "" {
+		return FileSystemResponse{Success: false, Error: "Variable name is required"}
+	}
+
+	s := a.session(session)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == "" {
+		delete(s.variables, key)
+	} else {
+		s.variables[key] = value
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// ClearHTTPSession drops all variables and responses of a session
+func (a *App) ClearHTTPSession(session string) FileSystemResponse {
+	if session == "" {
+		session = defaultHTTPSession
+	}
+
+	a.sessionMu.Lock()
+	delete(a.sessions, session)
+	a.sessionMu.Unlock()
+	return FileSystemResponse{Success: true}
+}
+
+// lookup returns a captured variable
+func (s *httpSession) lookup(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.variables[name]
+	return value, ok
+}
+
+// record stores captured values and, for named requests, the exchange
+func (s *httpSession) record(name string, request HTTPRequest, response HTTPResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, value := range response.Captured {
+		s.variables[key] = value
+	}
+	if name != "" {
+		// Keep JSON and form bodies referenceable as text
+		if flat, err := flattenBody(request); err == nil {
+			request = flat
+		}
+		s.exchanges[name] = httpExchange{request: request, response: response}
+	}
+}
+
+// requestReferencePattern matches name.response.body.<path> and
+// name.request.headers.<name> style references
+var requestReferencePattern = regexp.MustCompile(`^([\w\-]+)\.(request|response)\.(body|headers)(?:\.(.+))?$`)
+
+// reference resolves a {{name.response...}} or {{name.request...}} variable
+func (s *httpSession) reference(expr string) (string, bool, error) {
+	m := requestReferencePattern.FindStringSubmatch(expr)
+	if m == nil {
+		return "", false, nil
+	}
+
+	s.mu.Lock()
+	exchange, ok := s.exchanges[m[1]]
+	s.mu.Unlock()
+	if !ok {
+		return "", false, fmt.Errorf("request %q has not been sent in this session", m[1])
+	}
+
+	var headers map[string]string
+	var body string
+	if m[2] == "request" {
+		headers, body = exchange.request.Headers, exchange.request.Body
+	} else {
+		headers, body = exchange.response.Headers, exchange.response.Body
+	}
+
+	if m[3] == "headers" {
+		value, found := lookupHeader(headers, m[4])
+		if !found {
+			return "", false, fmt.Errorf("header %q not found in %s %s", m[4], m[1], m[2])
+		}
+		return value, true, nil
+	}
+
+	path := m[4]
+	if path == "" || path == "*" {
+		return body, true, nil
+	}
+	matches, err := evalJSONPathString(body, path)
+	if err != nil {
+		return "", false, err
+	}
+	if len(matches) == 0 {
+		return "", false, fmt.Errorf("%s matched nothing in %s %s", path, m[1], m[2])
+	}
+	return jsonValueString(matches[0]), true, nil
+}
+
+// lookupHeader finds a header case-insensitively
+func lookupHeader(headers map[string]string, name string) (string, bool) {
+	if value, ok := headers[textproto.CanonicalMIMEHeaderKey(name)]; ok {
+		return value, true
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// applyCaptures evaluates the captures of a request against its response,
+// filling response.Captured and response.CaptureErrors
+func applyCaptures(captures []HTTPCapture, response *HTTPResponse) {
+	if len(captures) == 0 || response.Error != "" {
+		return
+	}
+
+	response.Captured = make(map[string]string)
+	for _, capture := range captures {
+		value, err := evaluateCapture(capture, *response)
+		if err != nil {
+			response.CaptureErrors = append(response.CaptureErrors, fmt.Sprintf("%s: %v", capture.Name, err))
+			continue
+		}
+		response.Captured[capture.Name] = value
+	}
+}
+
+// evaluateCapture extracts a single value
+func evaluateCapture(capture HTTPCapture, response HTTPResponse) (string, error) {
+	switch strings.ToLower(capture.Source) {
+	case "", "body", "jsonpath":
+		matches, err := evalJSONPathString(response.Body, capture.Expression)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("%s matched nothing", capture.Expression)
+		}
+		return jsonValueString(matches[0]), nil
+	case "header":
+		value, ok := lookupHeader(response.Headers, capture.Expression)
+		if !ok {
+			return "", fmt.Errorf("header %q not found", capture.Expression)
+		}
+		return value, nil
+	case "regex":
+		re, err := regexp.Compile(capture.Expression)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex: %v", err)
+		}
+		m := re.FindStringSubmatch(response.Body)
+		if m == nil {
+			return "", fmt.Errorf("regex %q matched nothing", capture.Expression)
+		}
+		if len(m) > 1 {
+			return m[1], nil
+		}
+		return m[0], nil
+	case "status":
+		return strconv.Itoa(response.StatusCode), nil
+	}
+	return "", fmt.Errorf("unknown capture source %q", capture.Source)
+}
+
+// parseCaptureAnnotation parses the text after "@capture" in a .http file:
+// "token = $.data.token", "etag = header ETag", "id = regex id=(\d+)" or
+// "code = status"
+func parseCaptureAnnotation(text string) (HTTPCapture, bool) {
+	name, rest, ok := strings.Cut(text, "=")
+	if !ok {
+		return HTTPCapture{}, false
+	}
+	capture := HTTPCapture{Name: strings.TrimSpace(name), Source: "body"}
+	rest = strings.TrimSpace(rest)
+
+	source, expression, _ := strings.Cut(rest, " ")
+	switch strings.ToLower(source) {
+	case "body", "header", "regex", "status":
+		capture.Source = strings.ToLower(source)
+		capture.Expression = strings.TrimSpace(expression)
+	default:
+		capture.Expression = rest
+	}
+
+	if capture.Name == "" || (capture.Expression == "" && capture.Source != "status") {
+		return HTTPCapture{}, false
+	}
+	return capture, true
+}
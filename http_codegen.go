@@ -0,0 +1,919 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ========== HTTP Code Generation ==========
+
+// CodeGenerator is a target language for GenerateHTTPCode
+type CodeGenerator struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// codeGenerators lists the targets in the order they are offered
+var codeGenerators = []struct {
+	CodeGenerator
+	generate func(r codeRequest) string
+}{
+	{CodeGenerator{"curl", "cURL"}, func(r codeRequest) string { return curlCommand(r.request, r.settings) }},
+	{CodeGenerator{"go", "Go (net/http)"}, generateGo},
+	{CodeGenerator{"python", "Python (requests)"}, generatePython},
+	{CodeGenerator{"javascript", "JavaScript (fetch)"}, generateFetch},
+	{CodeGenerator{"axios", "Node.js (axios)"}, generateAxios},
+	{CodeGenerator{"java", "Java (HttpClient)"}, generateJava},
+	{CodeGenerator{"httpie", "HTTPie"}, generateHTTPie},
+}
+
+// ListCodeGenerators returns the languages GenerateHTTPCode supports
+func (a *App) ListCodeGenerators() []CodeGenerator {
+	generators := make([]CodeGenerator, len(codeGenerators))
+	for i, g := range codeGenerators {
+		generators[i] = g.CodeGenerator
+	}
+	return generators
+}
+
+// GenerateHTTPCode returns code sending the request in the given language,
+// with its variables resolved in its environment and session
+func (a *App) GenerateHTTPCode(request HTTPRequest, language string) JSONFormatResponse {
+	resolved, err := a.resolveRequestForExport(request)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+	return a.generateHTTPCode(resolved, language)
+}
+
+// GenerateHTTPFileCode returns code sending the named request of a .http
+// file, with its variables and body file resolved
+func (a *App) GenerateHTTPFileCode(filePath string, name string, environment string, language string) JSONFormatResponse {
+	resolved, err := a.resolveHTTPFileRequestForExport(filePath, name, environment)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+	return a.generateHTTPCode(resolved, language)
+}
+
+// generateHTTPCode runs the generator for language on a resolved request
+func (a *App) generateHTTPCode(request HTTPRequest, language string) JSONFormatResponse {
+	for _, g := range codeGenerators {
+		if g.ID == language {
+			request, err := a.exportOAuth2(request)
+			if err != nil {
+				return JSONFormatResponse{Error: err.Error()}
+			}
+			request, err = exportAuth(request, g.ID == "curl")
+			if err != nil {
+				return JSONFormatResponse{Error: err.Error()}
+			}
+			settings := a.loadConfig().HTTPSettings.merge(request.Settings)
+			return JSONFormatResponse{Result: g.generate(newCodeRequest(request, settings))}
+		}
+	}
+	return JSONFormatResponse{Error: fmt.Sprintf("Unknown language %q", language)}
+}
+
+// resolveRequestForExport resolves a request's variables as sending it would
+func (a *App) resolveRequestForExport(request HTTPRequest) (HTTPRequest, error) {
+	resolver, err := a.newVariableResolver(request.Environment, "")
+	if err != nil {
+		return request, fmt.Errorf("Environment error: %v", err)
+	}
+	resolver.session = a.session(request.Session)
+	resolved, err := resolver.resolveRequest(request)
+	if err != nil {
+		return request, fmt.Errorf("Variable error: %v", err)
+	}
+	return a.resolveUploadPaths(resolved)
+}
+
+// resolveHTTPFileRequestForExport finds the named request of a .http file
+// and resolves it as running it would
+func (a *App) resolveHTTPFileRequestForExport(filePath string, name string, environment string) (HTTPRequest, error) {
+	file, err := a.loadHTTPFile(filePath)
+	if err != nil {
+		return HTTPRequest{}, err
+	}
+	for _, request := range file.Requests {
+		if request.Name != name {
+			continue
+		}
+		resolver, err := a.newFileVariableResolver(file, environment)
+		if err != nil {
+			return HTTPRequest{}, fmt.Errorf("Environment error: %v", err)
+		}
+		return a.prepareHTTPFileRequest(file, request, resolver)
+	}
+	return HTTPRequest{}, fmt.Errorf("Request %q not found in %s", name, filepath.Base(filePath))
+}
+
+// codeRequest is a resolved request analysed for the generators: JSON and
+// form bodies are recognised from Content-Type, and Basic and Bearer
+// credentials from Authorization
+type codeRequest struct {
+	request  HTTPRequest
+	settings HTTPTransportSettings
+
+	Method  string
+	URL     string
+	Headers []HTTPHeader // sorted, without Content-Length
+	Body    string
+	JSON    bool        // Body is valid JSON declared as JSON
+	Form    [][2]string // Body decoded as form fields, in order
+	// Multipart holds the parts of a multipart body; Headers then leave
+	// out Content-Type, which carries a generated boundary
+	Multipart []HTTPBodyField
+	// Basic holds the user and password of a Basic Authorization header
+	Basic  *[2]string
+	Bearer string
+}
+
+func newCodeRequest(request HTTPRequest, settings HTTPTransportSettings) codeRequest {
+	if flat, err := flattenBody(request); err == nil {
+		request = flat
+	}
+	r := codeRequest{
+		request:  request,
+		settings: settings,
+		Method:   strings.ToUpper(request.Method),
+		URL:      request.URL,
+		Body:     request.Body,
+	}
+	if r.Method == "" {
+		r.Method = "GET"
+	}
+
+	if request.StructuredBody != nil && request.StructuredBody.Type == "multipart" {
+		r.Multipart = request.StructuredBody.Fields
+	}
+	for name, value := range request.Headers {
+		if strings.EqualFold(name, "Content-Length") || r.Multipart != nil && strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		r.Headers = append(r.Headers, HTTPHeader{Name: name, Value: value})
+	}
+	sort.Slice(r.Headers, func(i, j int) bool { return r.Headers[i].Name < r.Headers[j].Name })
+
+	if auth, ok := r.header("Authorization"); ok {
+		scheme, credentials, _ := strings.Cut(auth, " ")
+		switch strings.ToLower(scheme) {
+		case "basic":
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(credentials))
+			if user, password, ok := strings.Cut(string(decoded), ":"); err == nil && ok {
+				r.Basic = &[2]string{user, password}
+			}
+		case "bearer":
+			r.Bearer = strings.TrimSpace(credentials)
+		}
+	}
+
+	contentType, _ := r.header("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case r.Body == "":
+	case (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) && json.Valid([]byte(r.Body)):
+		r.JSON = true
+	case mediaType == "application/x-www-form-urlencoded":
+		r.Form = parseFormBody(r.Body)
+	}
+	return r
+}
+
+// header returns a header's value whatever the case of its name
+func (r codeRequest) header(name string) (string, bool) {
+	for _, h := range r.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value, true
+		}
+	}
+	return "", false
+}
+
+// headersWithout returns the headers minus the named ones
+func (r codeRequest) headersWithout(names ...string) []HTTPHeader {
+	var headers []HTTPHeader
+	for _, h := range r.Headers {
+		skip := false
+		for _, name := range names {
+			skip = skip || strings.EqualFold(h.Name, name)
+		}
+		if !skip {
+			headers = append(headers, h)
+		}
+	}
+	return headers
+}
+
+// jsonObject reports whether the body is a JSON object
+func (r codeRequest) jsonObject() bool {
+	return r.JSON && strings.HasPrefix(strings.TrimSpace(r.Body), "{")
+}
+
+// compactJSON returns the JSON body without insignificant whitespace
+func (r codeRequest) compactJSON() string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, []byte(r.Body)); err != nil {
+		return r.Body
+	}
+	return b.String()
+}
+
+// parseFormBody decodes "a=1&b=2" keeping field order, or returns nil if
+// the body is not valid form encoding
+func parseFormBody(body string) [][2]string {
+	fields := [][2]string{}
+	for _, pair := range strings.Split(body, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(key)
+		if err != nil {
+			return nil
+		}
+		if value, err = url.QueryUnescape(value); err != nil {
+			return nil
+		}
+		fields = append(fields, [2]string{key, value})
+	}
+	return fields
+}
+
+// jsonSyntax describes a language whose literals look like JSON
+type jsonSyntax struct {
+	null, yes, no string
+	quote         func(string) string
+	indent        string
+}
+
+var (
+	pythonSyntax = jsonSyntax{null: "None", yes: "True", no: "False", quote: strconv.Quote, indent: "    "}
+	jsSyntax     = jsonSyntax{null: "null", yes: "true", no: "false", quote: jsQuote, indent: "  "}
+)
+
+// jsonLiteral re-renders a JSON document as a literal of syntax, keeping key
+// order. Lines after the first are indented depth levels.
+func jsonLiteral(body string, syntax jsonSyntax, depth int) (string, error) {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var b strings.Builder
+	if err := writeJSONLiteral(decoder, &b, syntax, depth); err != nil {
+		return "", err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return "", fmt.Errorf("unexpected data after JSON value")
+	}
+	return b.String(), nil
+}
+
+// writeJSONLiteral writes the next JSON value from decoder
+func writeJSONLiteral(decoder *json.Decoder, b *strings.Builder, syntax jsonSyntax, depth int) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		b.WriteRune(rune(t))
+		count := 0
+		for decoder.More() {
+			if count > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n" + strings.Repeat(syntax.indent, depth+1))
+			if t == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				b.WriteString(syntax.quote(key.(string)) + ": ")
+			}
+			if err := writeJSONLiteral(decoder, b, syntax, depth+1); err != nil {
+				return err
+			}
+			count++
+		}
+		closing, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			b.WriteString("\n" + strings.Repeat(syntax.indent, depth))
+		}
+		b.WriteRune(rune(closing.(json.Delim)))
+	case string:
+		b.WriteString(syntax.quote(t))
+	case json.Number:
+		b.WriteString(t.String())
+	case bool:
+		if t {
+			b.WriteString(syntax.yes)
+		} else {
+			b.WriteString(syntax.no)
+		}
+	case nil:
+		b.WriteString(syntax.null)
+	}
+	return nil
+}
+
+// jsQuote quotes s as a JavaScript string
+func jsQuote(s string) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// javaQuote quotes s as a Java string
+func javaQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// goQuote quotes s as a Go string, preferring a raw string for multi-line
+// text
+func goQuote(s string) string {
+	if strings.Contains(s, "\n") && !strings.ContainsAny(s, "`\r") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// generateGo writes a Go program using net/http
+func generateGo(r codeRequest) string {
+	imports := []string{"fmt", "io", "net/http"}
+	var body strings.Builder
+	bodyArg := "nil"
+	switch {
+	case r.Multipart != nil:
+		imports = append(imports, "bytes", "mime/multipart")
+		body.WriteString("\tbody := &bytes.Buffer{}\n\twriter := multipart.NewWriter(body)\n")
+		for _, field := range r.Multipart {
+			if field.File == "" && field.ContentType == "" {
+				fmt.Fprintf(&body, "\twriter.WriteField(%s, %s)\n", strconv.Quote(field.Name), strconv.Quote(field.Value))
+				continue
+			}
+			body.WriteString("\t{\n")
+			if field.File != "" {
+				if !containsString(imports, "os") {
+					imports = append(imports, "os")
+				}
+				fmt.Fprintf(&body, "\t\tfile, err := os.Open(%s)\n\t\tif err != nil {\n\t\t\tpanic(err)\n\t\t}\n\t\tdefer file.Close()\n", strconv.Quote(field.File))
+			}
+			fileName := field.FileName
+			if fileName == "" && field.File != "" {
+				fileName = filepath.Base(field.File)
+			}
+			if field.ContentType == "" {
+				fmt.Fprintf(&body, "\t\tpart, err := writer.CreateFormFile(%s, %s)\n", strconv.Quote(field.Name), strconv.Quote(fileName))
+			} else {
+				if !containsString(imports, "net/textproto") {
+					imports = append(imports, "net/textproto")
+				}
+				disposition := fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(field.Name))
+				if field.File != "" {
+					disposition += fmt.Sprintf(`; filename="%s"`, escapeQuotes(fileName))
+				}
+				body.WriteString("\t\theader := make(textproto.MIMEHeader)\n")
+				fmt.Fprintf(&body, "\t\theader.Set(\"Content-Disposition\", %s)\n", strconv.Quote(disposition))
+				fmt.Fprintf(&body, "\t\theader.Set(\"Content-Type\", %s)\n", strconv.Quote(field.ContentType))
+				body.WriteString("\t\tpart, err := writer.CreatePart(header)\n")
+			}
+			body.WriteString("\t\tif err != nil {\n\t\t\tpanic(err)\n\t\t}\n")
+			if field.File != "" {
+				body.WriteString("\t\tif _, err := io.Copy(part, file); err != nil {\n\t\t\tpanic(err)\n\t\t}\n")
+			} else {
+				fmt.Fprintf(&body, "\t\tio.WriteString(part, %s)\n", strconv.Quote(field.Value))
+			}
+			body.WriteString("\t}\n")
+		}
+		body.WriteString("\twriter.Close()\n\n")
+		bodyArg = "body"
+	case r.Form != nil:
+		imports = append(imports, "net/url")
+		body.WriteString("\tform := url.Values{}\n")
+		for _, field := range r.Form {
+			fmt.Fprintf(&body, "\tform.Add(%s, %s)\n", strconv.Quote(field[0]), strconv.Quote(field[1]))
+		}
+		body.WriteString("\tbody := strings.NewReader(form.Encode())\n\n")
+		bodyArg = "body"
+	case r.Body != "":
+		content := r.Body
+		if r.JSON {
+			var indented bytes.Buffer
+			if json.Indent(&indented, []byte(r.Body), "", "\t") == nil {
+				content = indented.String()
+			}
+		}
+		fmt.Fprintf(&body, "\tbody := strings.NewReader(%s)\n\n", goQuote(content))
+		bodyArg = "body"
+	}
+	if bodyArg != "nil" && r.Multipart == nil {
+		imports = append(imports, "strings")
+	}
+	sort.Strings(imports)
+
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n")
+	for _, pkg := range imports {
+		fmt.Fprintf(&b, "\t%q\n", pkg)
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+	b.WriteString(body.String())
+	fmt.Fprintf(&b, "\treq, err := http.NewRequest(%q, %q, %s)\n", r.Method, r.URL, bodyArg)
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+
+	headers := r.Headers
+	if r.Basic != nil {
+		headers = r.headersWithout("Authorization")
+	}
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, "Host") {
+			fmt.Fprintf(&b, "\treq.Host = %s\n", strconv.Quote(h.Value))
+			continue
+		}
+		fmt.Fprintf(&b, "\treq.Header.Set(%s, %s)\n", strconv.Quote(h.Name), strconv.Quote(h.Value))
+	}
+	if r.Basic != nil {
+		fmt.Fprintf(&b, "\treq.SetBasicAuth(%s, %s)\n", strconv.Quote(r.Basic[0]), strconv.Quote(r.Basic[1]))
+	}
+	if r.Multipart != nil {
+		b.WriteString("\treq.Header.Set(\"Content-Type\", writer.FormDataContentType())\n")
+	}
+
+	b.WriteString(`
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(resp.Status)
+	fmt.Println(string(data))
+}
+`)
+	return b.String()
+}
+
+// generatePython writes a script using the requests library
+func generatePython(r codeRequest) string {
+	var b strings.Builder
+	b.WriteString("import requests\n\n")
+	fmt.Fprintf(&b, "url = %s\n", strconv.Quote(r.URL))
+	args := []string{"url"}
+
+	headers := r.Headers
+	if r.Basic != nil {
+		headers = r.headersWithout("Authorization")
+	}
+	if contentType, _ := r.header("Content-Type"); r.JSON && contentType == "application/json" {
+		// requests sets this Content-Type for json=
+		headers = codeRequest{Headers: headers}.headersWithout("Content-Type")
+	}
+	if len(headers) > 0 {
+		b.WriteString("headers = {\n")
+		for _, h := range headers {
+			fmt.Fprintf(&b, "    %s: %s,\n", strconv.Quote(h.Name), strconv.Quote(h.Value))
+		}
+		b.WriteString("}\n")
+		args = append(args, "headers=headers")
+	}
+
+	switch {
+	case r.JSON:
+		if literal, err := jsonLiteral(r.Body, pythonSyntax, 0); err == nil {
+			fmt.Fprintf(&b, "payload = %s\n", literal)
+			args = append(args, "json=payload")
+		}
+	case r.Form != nil:
+		b.WriteString("payload = [\n")
+		for _, field := range r.Form {
+			fmt.Fprintf(&b, "    (%s, %s),\n", strconv.Quote(field[0]), strconv.Quote(field[1]))
+		}
+		b.WriteString("]\n")
+		args = append(args, "data=payload")
+	case r.Multipart != nil:
+		// Text parts go in files= too, with no file name, so the body is
+		// always sent as multipart
+		b.WriteString("files = [\n")
+		for _, field := range r.Multipart {
+			parts := []string{"None", strconv.Quote(field.Value)}
+			if field.File != "" {
+				fileName := field.FileName
+				if fileName == "" {
+					fileName = filepath.Base(field.File)
+				}
+				parts = []string{strconv.Quote(fileName), fmt.Sprintf("open(%s, \"rb\")", strconv.Quote(field.File))}
+			}
+			if field.ContentType != "" {
+				parts = append(parts, strconv.Quote(field.ContentType))
+			}
+			fmt.Fprintf(&b, "    (%s, (%s)),\n", strconv.Quote(field.Name), strings.Join(parts, ", "))
+		}
+		b.WriteString("]\n")
+		args = append(args, "files=files")
+	case r.Body != "":
+		fmt.Fprintf(&b, "payload = %s\n", strconv.Quote(r.Body))
+		args = append(args, "data=payload.encode()")
+	}
+	if r.Basic != nil {
+		args = append(args, fmt.Sprintf("auth=(%s, %s)", strconv.Quote(r.Basic[0]), strconv.Quote(r.Basic[1])))
+	}
+
+	call := "requests." + strings.ToLower(r.Method)
+	switch r.Method {
+	case "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS":
+	default:
+		call = "requests.request"
+		args = append([]string{strconv.Quote(r.Method)}, args...)
+	}
+	fmt.Fprintf(&b, "\nresponse = %s(%s)\n", call, strings.Join(args, ", "))
+	b.WriteString("print(response.status_code)\nprint(response.text)\n")
+	return b.String()
+}
+
+// jsHeaders writes a headers object literal at the given depth, with a
+// Basic Authorization header built by expr if set
+func jsHeaders(b *strings.Builder, headers []HTTPHeader, depth int, authExpr string) {
+	indent := strings.Repeat("  ", depth)
+	b.WriteString("{\n")
+	for _, h := range headers {
+		value := jsQuote(h.Value)
+		if authExpr != "" && strings.EqualFold(h.Name, "Authorization") {
+			value = authExpr
+		}
+		fmt.Fprintf(b, "%s  %s: %s,\n", indent, jsQuote(h.Name), value)
+	}
+	b.WriteString(indent + "}")
+}
+
+// jsFormParams writes form fields as a URLSearchParams expression
+func jsFormParams(fields [][2]string, depth int) string {
+	indent := strings.Repeat("  ", depth)
+	var b strings.Builder
+	b.WriteString("new URLSearchParams([\n")
+	for _, field := range fields {
+		fmt.Fprintf(&b, "%s  [%s, %s],\n", indent, jsQuote(field[0]), jsQuote(field[1]))
+	}
+	b.WriteString(indent + "])")
+	return b.String()
+}
+
+// jsFormData writes statements building a FormData named form. Files are
+// read with readFileSync, which the caller imports if hasFiles says so.
+func jsFormData(fields []HTTPBodyField) (code string, hasFiles bool) {
+	var b strings.Builder
+	b.WriteString("const form = new FormData();\n")
+	for _, field := range fields {
+		if field.File == "" {
+			fmt.Fprintf(&b, "form.append(%s, %s);\n", jsQuote(field.Name), jsQuote(field.Value))
+			continue
+		}
+		hasFiles = true
+		fileName := field.FileName
+		if fileName == "" {
+			fileName = filepath.Base(field.File)
+		}
+		options := ""
+		if field.ContentType != "" {
+			options = fmt.Sprintf(", { type: %s }", jsQuote(field.ContentType))
+		}
+		fmt.Fprintf(&b, "form.append(%s, new Blob([readFileSync(%s)]%s), %s);\n",
+			jsQuote(field.Name), jsQuote(field.File), options, jsQuote(fileName))
+	}
+	return b.String(), hasFiles
+}
+
+// generateFetch writes JavaScript using fetch
+func generateFetch(r codeRequest) string {
+	var b strings.Builder
+	if r.Multipart != nil {
+		form, hasFiles := jsFormData(r.Multipart)
+		if hasFiles {
+			b.WriteString("import { readFileSync } from \"node:fs\";\n\n")
+		}
+		b.WriteString(form + "\n")
+	}
+	fmt.Fprintf(&b, "const response = await fetch(%s, {\n", jsQuote(r.URL))
+	fmt.Fprintf(&b, "  method: %s,\n", jsQuote(r.Method))
+
+	if len(r.Headers) > 0 {
+		authExpr := ""
+		if r.Basic != nil {
+			authExpr = `"Basic " + btoa(` + jsQuote(r.Basic[0]+":"+r.Basic[1]) + ")"
+		}
+		b.WriteString("  headers: ")
+		jsHeaders(&b, r.Headers, 1, authExpr)
+		b.WriteString(",\n")
+	}
+
+	switch {
+	case r.JSON:
+		if literal, err := jsonLiteral(r.Body, jsSyntax, 1); err == nil {
+			fmt.Fprintf(&b, "  body: JSON.stringify(%s),\n", literal)
+		}
+	case r.Form != nil:
+		fmt.Fprintf(&b, "  body: %s,\n", jsFormParams(r.Form, 1))
+	case r.Multipart != nil:
+		b.WriteString("  body: form,\n")
+	case r.Body != "":
+		fmt.Fprintf(&b, "  body: %s,\n", jsQuote(r.Body))
+	}
+	b.WriteString("});\n\nconsole.log(response.status);\nconsole.log(await response.text());\n")
+	return b.String()
+}
+
+// generateAxios writes a Node.js script using axios
+func generateAxios(r codeRequest) string {
+	var b strings.Builder
+	b.WriteString("const axios = require(\"axios\");\n")
+	form, hasFiles := jsFormData(r.Multipart)
+	if hasFiles {
+		b.WriteString("const { readFileSync } = require(\"node:fs\");\n")
+	}
+	if r.Multipart != nil {
+		b.WriteString("\n" + form)
+	}
+	b.WriteString("\naxios({\n")
+	fmt.Fprintf(&b, "  method: %s,\n", jsQuote(strings.ToLower(r.Method)))
+	fmt.Fprintf(&b, "  url: %s,\n", jsQuote(r.URL))
+
+	headers := r.Headers
+	if r.Basic != nil {
+		headers = r.headersWithout("Authorization")
+	}
+	if len(headers) > 0 {
+		b.WriteString("  headers: ")
+		jsHeaders(&b, headers, 1, "")
+		b.WriteString(",\n")
+	}
+	if r.Basic != nil {
+		fmt.Fprintf(&b, "  auth: {\n    username: %s,\n    password: %s,\n  },\n", jsQuote(r.Basic[0]), jsQuote(r.Basic[1]))
+	}
+
+	switch {
+	case r.JSON:
+		if literal, err := jsonLiteral(r.Body, jsSyntax, 1); err == nil {
+			fmt.Fprintf(&b, "  data: %s,\n", literal)
+		}
+	case r.Form != nil:
+		fmt.Fprintf(&b, "  data: %s,\n", jsFormParams(r.Form, 1))
+	case r.Multipart != nil:
+		b.WriteString("  data: form,\n")
+	case r.Body != "":
+		fmt.Fprintf(&b, "  data: %s,\n", jsQuote(r.Body))
+	}
+	b.WriteString(`})
+  .then((response) => {
+    console.log(response.status);
+    console.log(response.data);
+  })
+  .catch((error) => {
+    console.error(error);
+  });
+`)
+	return b.String()
+}
+
+// javaRestrictedHeaders are set by HttpClient itself, which rejects them
+var javaRestrictedHeaders = []string{"Connection", "Content-Length", "Expect", "Host", "Upgrade"}
+
+// generateJava writes a Java 11 program using java.net.http.HttpClient
+func generateJava(r codeRequest) string {
+	imports := []string{"java.net.URI", "java.net.http.HttpClient", "java.net.http.HttpRequest", "java.net.http.HttpResponse"}
+	if r.Basic != nil {
+		imports = append(imports, "java.nio.charset.StandardCharsets", "java.util.Base64")
+	}
+	if r.Multipart != nil {
+		imports = append(imports, "java.nio.file.Files", "java.nio.file.Path", "java.util.ArrayList", "java.util.List")
+		if r.Basic == nil {
+			imports = append(imports, "java.nio.charset.StandardCharsets")
+		}
+	}
+	sort.Strings(imports)
+
+	var b strings.Builder
+	for _, pkg := range imports {
+		fmt.Fprintf(&b, "import %s;\n", pkg)
+	}
+	b.WriteString("\npublic class Main {\n")
+	b.WriteString("    public static void main(String[] args) throws Exception {\n")
+	b.WriteString("        HttpClient client = HttpClient.newHttpClient();\n\n")
+
+	// HttpClient has no multipart support, so the body is put together
+	// from byte arrays
+	if r.Multipart != nil {
+		b.WriteString("        String boundary = \"JavaFormBoundary\" + System.currentTimeMillis();\n")
+		b.WriteString("        List<byte[]> parts = new ArrayList<>();\n")
+		for _, field := range r.Multipart {
+			disposition := fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(field.Name))
+			if field.File != "" {
+				fileName := field.FileName
+				if fileName == "" {
+					fileName = filepath.Base(field.File)
+				}
+				disposition += fmt.Sprintf(`; filename="%s"`, escapeQuotes(fileName))
+			}
+			header := "\r\nContent-Disposition: " + disposition + "\r\n"
+			if field.ContentType != "" {
+				header += "Content-Type: " + field.ContentType + "\r\n"
+			}
+			header += "\r\n"
+			if field.File == "" {
+				header += field.Value + "\r\n"
+			}
+			fmt.Fprintf(&b, "        parts.add((\"--\" + boundary + %s).getBytes(StandardCharsets.UTF_8));\n", javaQuote(header))
+			if field.File != "" {
+				fmt.Fprintf(&b, "        parts.add(Files.readAllBytes(Path.of(%s)));\n", javaQuote(field.File))
+				b.WriteString("        parts.add(\"\\r\\n\".getBytes(StandardCharsets.UTF_8));\n")
+			}
+		}
+		b.WriteString("        parts.add((\"--\" + boundary + \"--\\r\\n\").getBytes(StandardCharsets.UTF_8));\n\n")
+	}
+
+	b.WriteString("        HttpRequest request = HttpRequest.newBuilder()\n")
+	fmt.Fprintf(&b, "            .uri(URI.create(%s))\n", javaQuote(r.URL))
+
+	for _, h := range r.headersWithout(javaRestrictedHeaders...) {
+		value := javaQuote(h.Value)
+		if r.Basic != nil && strings.EqualFold(h.Name, "Authorization") {
+			value = `"Basic " + Base64.getEncoder().encodeToString(` + javaQuote(r.Basic[0]+":"+r.Basic[1]) + ".getBytes(StandardCharsets.UTF_8))"
+		}
+		fmt.Fprintf(&b, "            .header(%s, %s)\n", javaQuote(h.Name), value)
+	}
+
+	if r.Multipart != nil {
+		b.WriteString("            .header(\"Content-Type\", \"multipart/form-data; boundary=\" + boundary)\n")
+	}
+
+	body := r.Body
+	if r.JSON {
+		body = r.compactJSON()
+	}
+	switch {
+	case r.Multipart != nil:
+		fmt.Fprintf(&b, "            .method(%s, HttpRequest.BodyPublishers.ofByteArrays(parts))\n", javaQuote(r.Method))
+	case r.Method == "GET" && body == "":
+		b.WriteString("            .GET()\n")
+	case body == "":
+		fmt.Fprintf(&b, "            .method(%s, HttpRequest.BodyPublishers.noBody())\n", javaQuote(r.Method))
+	default:
+		fmt.Fprintf(&b, "            .method(%s, HttpRequest.BodyPublishers.ofString(%s))\n", javaQuote(r.Method), javaQuote(body))
+	}
+	b.WriteString("            .build();\n\n")
+	b.WriteString("        HttpResponse<String> response = client.send(request, HttpResponse.BodyHandlers.ofString());\n")
+	b.WriteString("        System.out.println(response.statusCode());\n")
+	b.WriteString("        System.out.println(response.body());\n")
+	b.WriteString("    }\n}\n")
+	return b.String()
+}
+
+// httpieItemKey reports whether a field name can be written as an HTTPie
+// request item without escaping
+func httpieItemKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, `=:@\`)
+}
+
+// generateHTTPie writes an HTTPie command line. Flat JSON objects and forms
+// become request items; anything else is sent with --raw.
+func generateHTTPie(r codeRequest) string {
+	var options, items []string
+	headers := r.Headers
+	switch {
+	case r.Basic != nil:
+		options = append(options, "-a "+shellQuote(r.Basic[0]+":"+r.Basic[1]))
+		headers = r.headersWithout("Authorization")
+	case r.Bearer != "":
+		options = append(options, "-A bearer -a "+shellQuote(r.Bearer))
+		headers = r.headersWithout("Authorization")
+	}
+
+	raw := r.Body != ""
+	switch {
+	case r.jsonObject():
+		var fields []string
+		decoder := json.NewDecoder(strings.NewReader(r.Body))
+		decoder.UseNumber()
+		decoder.Token()
+		for raw = false; decoder.More(); {
+			key, _ := decoder.Token()
+			var value json.RawMessage
+			if decoder.Decode(&value) != nil || !httpieItemKey(key.(string)) {
+				raw = true
+				break
+			}
+			var text string
+			if json.Unmarshal(value, &text) == nil {
+				fields = append(fields, shellQuote(key.(string)+"="+text))
+			} else {
+				fields = append(fields, shellQuote(key.(string)+":="+string(value)))
+			}
+		}
+		if !raw {
+			// HTTPie sends items as JSON with this Content-Type already
+			items = fields
+			if contentType, _ := r.header("Content-Type"); contentType == "application/json" {
+				headers = codeRequest{Headers: headers}.headersWithout("Content-Type")
+			}
+		}
+	case r.Form != nil:
+		raw = false
+		for _, field := range r.Form {
+			if !httpieItemKey(field[0]) {
+				raw = true
+				items = nil
+				break
+			}
+			items = append(items, shellQuote(field[0]+"="+field[1]))
+		}
+		if !raw {
+			options = append(options, "--form")
+			headers = codeRequest{Headers: headers}.headersWithout("Content-Type")
+		}
+	case r.Multipart != nil:
+		options = append(options, "--multipart")
+		for _, field := range r.Multipart {
+			if field.File == "" {
+				items = append(items, shellQuote(field.Name+"="+field.Value))
+				continue
+			}
+			item := field.Name + "@" + field.File
+			if field.ContentType != "" {
+				item += ";type=" + field.ContentType
+			}
+			items = append(items, shellQuote(item))
+		}
+	}
+	if raw {
+		options = append(options, "--raw "+shellQuote(r.Body))
+	}
+
+	var headerItems []string
+	for _, h := range headers {
+		if h.Value == "" {
+			// "Name;" sends an empty header
+			headerItems = append(headerItems, shellQuote(h.Name+";"))
+		} else {
+			headerItems = append(headerItems, shellQuote(h.Name+":"+h.Value))
+		}
+	}
+	items = append(headerItems, items...)
+
+	first := "http"
+	if len(options) > 0 {
+		first += " " + strings.Join(options, " ")
+	}
+	lines := []string{first + " " + r.Method + " " + shellQuote(r.URL)}
+	lines = append(lines, items...)
+	return strings.Join(lines, " \\\n  ") + "\n"
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// ========== HTTP Cookie Jar ==========
+
+// HTTPCookie is a stored cookie. Cookies without an expiry are session
+// cookies; they are kept on disk too so logins survive restarts.
+type HTTPCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires"`
+	Session  bool      `json:"session"`
+	Secure   bool      `json:"secure"`
+	HttpOnly bool      `json:"httpOnly"`
+	SameSite string    `json:"sameSite,omitempty"`
+	// HostOnly cookies are sent to Domain exactly, not to its subdomains
+	HostOnly bool      `json:"hostOnly"`
+	Created  time.Time `json:"created"`
+}
+
+// cookiesPath returns the cookie file, keyed by environment name
+func (a *App) cookiesPath() string {
+	return filepath.Join(a.storagePath, "http", ".cookies.json")
+}
+
+// loadCookies reads the cookie file once; callers hold cookieMu
+func (a *App) loadCookies() error {
+	if a.cookies != nil {
+		return nil
+	}
+
+	a.cookies = make(map[string][]HTTPCookie)
+	data, err := os.ReadFile(a.cookiesPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &a.cookies); err != nil {
+		return fmt.Errorf("invalid cookies file: %w", err)
+	}
+	return nil
+}
+
+// saveCookies writes the cookie file, dropping expired cookies; callers
+// hold cookieMu
+func (a *App) saveCookies() error {
+	now := time.Now()
+	for env, cookies := range a.cookies {
+		kept := cookies[:0]
+		for _, c := range cookies {
+			if c.Session || c.Expires.After(now) {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) == 0 {
+			delete(a.cookies, env)
+		} else {
+			a.cookies[env] = kept
+		}
+	}
+
+	data, err := json.MarshalIndent(a.cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.cookiesPath()), 0755); err != nil {
+		return err
+	}
+	// Cookies carry session tokens, keep them private to the user
+	return os.WriteFile(a.cookiesPath(), data, 0600)
+}
+
+// cookieEnvironment resolves an empty name to the active environment
+func (a *App) cookieEnvironment(environment string) string {
+	if environment != "" {
+		return environment
+	}
+	a.envMu.Lock()
+	store, _ := a.loadEnvironments()
+	a.envMu.Unlock()
+	return store.Active
+}
+
+// GetHTTPCookies lists the cookies of an environment (empty means the
+// active one), sorted by domain, path and name
+func (a *App) GetHTTPCookies(environment string) FileSystemResponse {
+	environment = a.cookieEnvironment(environment)
+
+	a.cookieMu.Lock()
+	defer a.cookieMu.Unlock()
+	if err := a.loadCookies(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load cookies: %v", err)}
+	}
+
+	now := time.Now()
+	cookies := []HTTPCookie{}
+	for _, c := range a.cookies[environment] {
+		if c.Session || c.Expires.After(now) {
+			cookies = append(cookies, c)
+		}
+	}
+	sort.Slice(cookies, func(i, j int) bool {
+		if cookies[i].Domain != cookies[j].Domain {
+			return cookies[i].Domain < cookies[j].Domain
+		}
+		if cookies[i].Path != cookies[j].Path {
+			return cookies[i].Path < cookies[j].Path
+		}
+		return cookies[i].Name < cookies[j].Name
+	})
+	return FileSystemResponse{Success: true, Data: cookies}
+}
+
+// SaveHTTPCookie adds a cookie or replaces the one with the same domain,
+// path and name
+func (a *App) SaveHTTPCookie(environment string, cookie HTTPCookie) FileSystemResponse {
+	cookie.Name = strings.TrimSpace(cookie.Name)
+	cookie.Domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(cookie.Domain), "."))
+	if cookie.Name == "" || cookie.Domain == "" {
+		return FileSystemResponse{Success: false, Error: "Cookie name and domain are required"}
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.Expires.IsZero() {
+		cookie.Session = true
+	}
+	environment = a.cookieEnvironment(environment)
+
+	a.cookieMu.Lock()
+	defer a.cookieMu.Unlock()
+	if err := a.loadCookies(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load cookies: %v", err)}
+	}
+
+	a.cookies[environment] = storeCookie(a.cookies[environment], cookie)
+	if err := a.saveCookies(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save cookies: %v", err)}
+	}
+	return FileSystemResponse{Success: true, Data: cookie}
+}
+
+// DeleteHTTPCookie removes one cookie. The domain is matched in any case,
+// with or without a leading dot.
+func (a *App) DeleteHTTPCookie(environment string, domain string, cookiePath string, name string) FileSystemResponse {
+	environment = a.cookieEnvironment(environment)
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+
+	a.cookieMu.Lock()
+	defer a.cookieMu.Unlock()
+	if err := a.loadCookies(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load cookies: %v", err)}
+	}
+
+	cookies := a.cookies[environment]
+	kept := cookies[:0]
+	for _, c := range cookies {
+		if !(c.Domain == domain && c.Path == cookiePath && c.Name == name) {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == len(cookies) {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Cookie %q does not exist", name)}
+	}
+	a.cookies[environment] = kept
+
+	if err := a.saveCookies(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save cookies: %v", err)}
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// ClearHTTPCookies removes every cookie of an environment, or only those
+// of one domain and its subdomains if domain is set
+func (a *App) ClearHTTPCookies(environment string, domain string) FileSystemResponse {
+	environment = a.cookieEnvironment(environment)
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+
+	a.cookieMu.Lock()
+	defer a.cookieMu.Unlock()
+	if err := a.loadCookies(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load cookies: %v", err)}
+	}
+
+	if domain == "" {
+		delete(a.cookies, environment)
+	} else {
+		cookies := a.cookies[environment]
+		kept := cookies[:0]
+		for _, c := range cookies {
+			if !domainMatch(c.Domain, domain) {
+				kept = append(kept, c)
+			}
+		}
+		a.cookies[environment] = kept
+	}
+
+	if err := a.saveCookies(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save cookies: %v", err)}
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// storeCookie replaces the cookie with the same domain, path and name,
+// keeping its creation time, or appends it
+func storeCookie(cookies []HTTPCookie, cookie HTTPCookie) []HTTPCookie {
+	for i, c := range cookies {
+		if c.Domain == cookie.Domain && c.Path == cookie.Path && c.Name == cookie.Name {
+			cookie.Created = c.Created
+			cookies[i] = cookie
+			return cookies
+		}
+	}
+	if cookie.Created.IsZero() {
+		cookie.Created = time.Now()
+	}
+	return append(cookies, cookie)
+}
+
+// httpCookieJar is the http.CookieJar of one environment, backed by the
+// app's persistent cookie store
+type httpCookieJar struct {
+	app         *App
+	environment string
+}
+
+// cookieJar returns the jar for an environment
+func (a *App) cookieJar(environment string) http.CookieJar {
+	return &httpCookieJar{app: a, environment: environment}
+}
+
+// SetCookies stores the cookies of a response following RFC 6265
+func (j *httpCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+
+	a := j.app
+	a.cookieMu.Lock()
+	defer a.cookieMu.Unlock()
+	if err := a.loadCookies(); err != nil {
+		return
+	}
+
+	stored := a.cookies[j.environment]
+	for _, c := range cookies {
+		cookie := HTTPCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: sameSiteName(c.SameSite),
+		}
+
+		domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+		isIP := net.ParseIP(host) != nil
+		switch {
+		case domain == "" || isIP && domain == host:
+			cookie.Domain, cookie.HostOnly = host, true
+		case !isIP && isPublicSuffix(domain):
+			// Like net/http/cookiejar: a public suffix such as "com" only
+			// names the host itself
+			if domain != host {
+				continue
+			}
+			cookie.Domain, cookie.HostOnly = host, true
+		case !isIP && domainMatch(host, domain):
+			cookie.Domain = domain
+		default:
+			continue // a site may not set cookies for other domains
+		}
+
+		if cookie.Path == "" || !strings.HasPrefix(cookie.Path, "/") {
+			cookie.Path = defaultCookiePath(u.Path)
+		}
+
+		switch {
+		case c.MaxAge < 0:
+			cookie.Expires = now.Add(-time.Second)
+		case c.MaxAge > 0:
+			cookie.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			cookie.Expires = c.Expires
+		default:
+			cookie.Session = true
+		}
+
+		stored = storeCookie(stored, cookie)
+	}
+	a.cookies[j.environment] = stored
+	a.saveCookies()
+}
+
+// isPublicSuffix reports whether a domain is a public suffix, under which
+// anyone may register names
+func isPublicSuffix(domain string) bool {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+// Cookies returns the cookies to send with a request to u, longest path first
+func (j *httpCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	host := strings.ToLower(u.Hostname())
+	requestPath := u.Path
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+	now := time.Now()
+
+	a := j.app
+	a.cookieMu.Lock()
+	defer a.cookieMu.Unlock()
+	if err := a.loadCookies(); err != nil {
+		return nil
+	}
+
+	var matched []HTTPCookie
+	for _, c := range a.cookies[j.environment] {
+		if !c.Session && !c.Expires.After(now) {
+			continue
+		}
+		if c.HostOnly && host != c.Domain || !c.HostOnly && !domainMatch(host, c.Domain) {
+			continue
+		}
+		if !pathMatch(requestPath, c.Path) || c.Secure && !secure {
+			continue
+		}
+		matched = append(matched, c)
+	}
+	sort.SliceStable(matched, func(i, k int) bool {
+		return len(matched[i].Path) > len(matched[k].Path)
+	})
+
+	cookies := make([]*http.Cookie, len(matched))
+	for i, c := range matched {
+		cookies[i] = &http.Cookie{Name: c.Name, Value: c.Value}
+	}
+	return cookies
+}
+
+// domainMatch reports whether host is domain or one of its subdomains
+func domainMatch(host string, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// pathMatch implements the RFC 6265 path-match rule
+func pathMatch(requestPath string, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath is the directory of the request path
+func defaultCookiePath(requestPath string) string {
+	if requestPath == "" || requestPath[0] != '/' {
+		return "/"
+	}
+	dir := path.Dir(requestPath)
+	if dir == "." {
+		return "/"
+	}
+	return dir
+}
+
+// sameSiteName renders http.SameSite as its attribute value
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
@@ -0,0 +1,864 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ========== cURL Import and Export ==========
+
+// CurlImportResult is a request parsed from a curl command
+type CurlImportResult struct {
+	Request HTTPFileRequest `json:"request"`
+	// Warnings lists options that were ignored or only partly imported
+	Warnings []string `json:"warnings"`
+	// File is the .http file the request was saved to by ImportCurlCommand
+	File *FileItem `json:"file,omitempty"`
+}
+
+// curlShortOptions maps curl's single-letter options to their long names
+var curlShortOptions = map[byte]string{
+	'X': "request", 'H': "header", 'd': "data", 'u': "user", 'F': "form",
+	'b': "cookie", 'A': "user-agent", 'e': "referer", 'x': "proxy",
+	'm': "max-time", 'o': "output", 'k': "insecure", 'L': "location",
+	'I': "head", 'G': "get", 's': "silent", 'S': "show-error", 'v': "verbose",
+	'i': "include", 'f': "fail", 'g': "globoff", 'N': "no-buffer", 'O': "remote-name",
+	'J': "remote-header-name", 'w': "write-out", 'c': "cookie-jar", 'E': "cert",
+	'T': "upload-file", 'r': "range", 'U': "proxy-user", 'K': "config",
+	'C': "continue-at", 'z': "time-cond", 'Y': "speed-limit", 'y': "speed-time",
+	'0': "http1.0", '1': "tlsv1", '2': "sslv2", '3': "sslv3", '4': "ipv4", '6': "ipv6",
+	'#': "progress-bar", 'q': "disable", 'n': "netrc", 'l': "list-only",
+	'j': "junk-session-cookies", 'R': "remote-time", 'Z': "parallel", 'Q': "quote",
+}
+
+// curlValueOptions lists the long options that take a value
+var curlValueOptions = map[string]bool{
+	"request": true, "header": true, "data": true, "data-ascii": true, "data-raw": true,
+	"data-binary": true, "data-urlencode": true, "json": true, "user": true,
+	"form": true, "form-string": true, "cookie": true, "user-agent": true,
+	"referer": true, "url": true, "max-time": true, "connect-timeout": true,
+	"max-redirs": true, "oauth2-bearer": true, "proxy": true, "output": true,
+	"write-out": true, "cookie-jar": true, "cert": true, "key": true,
+	"cacert": true, "capath": true, "cert-type": true, "key-type": true,
+	"upload-file": true, "range": true, "proxy-user": true, "config": true,
+	"continue-at": true, "time-cond": true, "speed-limit": true, "speed-time": true,
+	"resolve": true, "connect-to": true, "interface": true, "limit-rate": true,
+	"retry": true, "retry-delay": true, "retry-max-time": true, "dns-servers": true,
+	"unix-socket": true, "abstract-unix-socket": true, "aws-sigv4": true,
+	"trace": true, "trace-ascii": true, "stderr": true, "ciphers": true,
+	"tls-max": true, "noproxy": true, "quote": true, "proxy-header": true,
+	"request-target": true, "expect100-timeout": true, "keepalive-time": true,
+	"local-port": true, "max-filesize": true, "pass": true, "pinnedpubkey": true,
+	"preproxy": true, "socks5": true, "socks5-hostname": true, "variable": true,
+	"happy-eyeballs-timeout-ms": true, "output-dir": true, "create-file-mode": true,
+}
+
+// curlQuietOptions are accepted without a warning because they only affect
+// curl's own output, or because requests here behave that way anyway
+var curlQuietOptions = map[string]bool{
+	"compressed": true, "location": true, "silent": true, "show-error": true,
+	"verbose": true, "include": true, "fail": true, "fail-with-body": true,
+	"globoff": true, "no-buffer": true, "progress-bar": true, "http1.1": true,
+	"http2": true, "http2-prior-knowledge": true, "disable": true, "output": true,
+	"write-out": true, "remote-name": true, "remote-header-name": true,
+	"no-progress-meter": true, "no-keepalive": true, "stderr": true, "trace": true,
+	"trace-ascii": true, "path-as-is": true, "tcp-nodelay": true, "ipv4": true, "ipv6": true,
+}
+
+// shellSafePattern matches words that need no quoting in a POSIX shell
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_\-./:=@%+,]+$`)
+
+// ParseCurlCommand turns a curl command, such as one from a browser's
+// "Copy as cURL", into a request without saving it
+func (a *App) ParseCurlCommand(command string) FileSystemResponse {
+	result, err := parseCurlCommand(command)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: result}
+}
+
+// ImportCurlCommand parses a curl command and saves it as a .http file in
+// parentPath, or in the http folder if parentPath is empty. An empty
+// fileName names the file after the URL.
+func (a *App) ImportCurlCommand(command string, parentPath string, fileName string) FileSystemResponse {
+	result, err := parseCurlCommand(command)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	if fileName == "" {
+		fileName = requestFileName(result.Request.Request)
+	}
+
+	item, err := a.createHTTPFile(parentPath, fileName, formatHTTPFile(nil, []HTTPFileRequest{result.Request}))
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	result.File = &item
+	return FileSystemResponse{Success: true, Data: result}
+}
+
+// ExportHTTPRequestAsCurl returns a request as a curl command, with its
+// variables resolved in its environment and session
+func (a *App) ExportHTTPRequestAsCurl(request HTTPRequest) JSONFormatResponse {
+	return a.GenerateHTTPCode(request, "curl")
+}
+
+// ExportHTTPFileRequestAsCurl returns the named request of a .http file as
+// a curl command, with its variables and body file resolved
+func (a *App) ExportHTTPFileRequestAsCurl(filePath string, name string, environment string) JSONFormatResponse {
+	return a.GenerateHTTPFileCode(filePath, name, environment, "curl")
+}
+
+// parseCurlCommand parses the options curl users paste most often. Data
+// options are combined with "&" as curl does, and imply POST unless -G or
+// -X says otherwise.
+func parseCurlCommand(command string) (CurlImportResult, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return CurlImportResult{}, err
+	}
+	if len(args) == 0 || strings.TrimSuffix(path.Base(filepath.ToSlash(args[0])), ".exe") != "curl" {
+		return CurlImportResult{}, fmt.Errorf("not a curl command")
+	}
+
+	result := CurlImportResult{Warnings: []string{}}
+	request := HTTPRequest{Headers: map[string]string{}}
+	warn := func(format string, args ...interface{}) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	var (
+		method, rawURL string
+		data           []string
+		dataFiles      []string
+		jsonData       bool
+		form           []string
+		head, get      bool
+		user, awsSigV4 string
+		digest         bool
+	)
+
+	// Expand "-XPOST" and "-sSL" into separate options
+	var options [][2]string // name, value
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		var name string
+		switch {
+		case arg == "--":
+			for _, rest := range args[i+1:] {
+				options = append(options, [2]string{"url", rest})
+			}
+			i = len(args)
+			continue
+		case strings.HasPrefix(arg, "--"):
+			name = strings.TrimPrefix(arg, "--")
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			letters := arg[1:]
+			for j := 0; j < len(letters); j++ {
+				long, ok := curlShortOptions[letters[j]]
+				if !ok {
+					warn("Ignored unknown option -%c", letters[j])
+					continue
+				}
+				if !curlValueOptions[long] {
+					options = append(options, [2]string{long, ""})
+					continue
+				}
+				if j+1 < len(letters) {
+					options = append(options, [2]string{long, letters[j+1:]})
+				} else if i+1 < len(args) {
+					i++
+					options = append(options, [2]string{long, args[i]})
+				} else {
+					return result, fmt.Errorf("option -%c needs a value", letters[j])
+				}
+				break
+			}
+			continue
+		default:
+			options = append(options, [2]string{"url", arg})
+			continue
+		}
+
+		value := ""
+		if curlValueOptions[name] {
+			if i+1 >= len(args) {
+				return result, fmt.Errorf("option --%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		options = append(options, [2]string{name, value})
+	}
+
+	for _, option := range options {
+		name, value := option[0], option[1]
+		switch name {
+		case "url":
+			if rawURL != "" {
+				warn("Ignored extra URL %s", value)
+				continue
+			}
+			rawURL = value
+		case "request":
+			method = strings.ToUpper(value)
+		case "header":
+			key, headerValue, ok := strings.Cut(value, ":")
+			if !ok {
+				// "Name;" sends an empty header
+				if key, ok = strings.CutSuffix(strings.TrimSpace(value), ";"); !ok {
+					warn("Ignored malformed header %q", value)
+					continue
+				}
+			} else if strings.TrimSpace(headerValue) == "" {
+				// "Name:" removes a header curl would send
+				continue
+			}
+			addCurlHeader(request.Headers, strings.TrimSpace(key), strings.TrimSpace(headerValue))
+		case "data", "data-ascii", "data-binary":
+			if file, ok := strings.CutPrefix(value, "@"); ok {
+				dataFiles = append(dataFiles, file)
+				continue
+			}
+			data = append(data, value)
+		case "data-raw":
+			data = append(data, value)
+		case "json":
+			jsonData = true
+			if file, ok := strings.CutPrefix(value, "@"); ok {
+				dataFiles = append(dataFiles, file)
+				continue
+			}
+			data = append(data, value)
+		case "data-urlencode":
+			encoded, ok := curlURLEncode(value)
+			if !ok {
+				warn("Ignored --data-urlencode %q: reading values from files is not supported", value)
+				continue
+			}
+			data = append(data, encoded)
+		case "form", "form-string":
+			form = append(form, name+"\x00"+value)
+		case "user":
+			if !strings.Contains(value, ":") {
+				warn("No password given for user %q; curl would prompt for one", value)
+				value += ":"
+			}
+			user = value
+		case "digest":
+			digest = true
+		case "basic":
+			digest = false
+		case "aws-sigv4":
+			awsSigV4 = value
+		case "oauth2-bearer":
+			addCurlHeader(request.Headers, "Authorization", "Bearer "+value)
+		case "cookie":
+			if !strings.Contains(value, "=") {
+				warn("Ignored cookie file %s", value)
+				continue
+			}
+			addCurlHeader(request.Headers, "Cookie", value)
+		case "user-agent":
+			addCurlHeader(request.Headers, "User-Agent", value)
+		case "referer":
+			if referer := strings.TrimSuffix(value, ";auto"); referer != "" {
+				addCurlHeader(request.Headers, "Referer", referer)
+			}
+		case "head":
+			head = true
+		case "get":
+			get = true
+		case "insecure":
+			insecure := true
+			curlSettings(&request).InsecureSkipVerify = &insecure
+		case "max-time", "connect-timeout":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds <= 0 {
+				warn("Ignored --%s %q", name, value)
+				continue
+			}
+			if name == "max-time" {
+				curlSettings(&request).ReadTimeout = int(seconds * 1000)
+			} else {
+				curlSettings(&request).ConnectTimeout = int(seconds * 1000)
+			}
+		default:
+			if curlQuietOptions[name] {
+				continue
+			}
+			if value != "" {
+				warn("Ignored option --%s %s", name, value)
+			} else {
+				warn("Ignored option --%s", name)
+			}
+		}
+	}
+
+	// Credentials: Basic unless --digest or --aws-sigv4 says otherwise
+	if user != "" {
+		name, password, _ := strings.Cut(user, ":")
+		switch {
+		case awsSigV4 != "":
+			// "aws:amz:region:service", where region and service are optional
+			providers := strings.Split(awsSigV4, ":")
+			auth := &HTTPAuth{Type: "aws", AccessKey: name, SecretKey: password}
+			if len(providers) > 2 {
+				auth.Region = providers[2]
+			}
+			if len(providers) > 3 {
+				auth.Service = providers[3]
+			}
+			if token, ok := lookupHeader(request.Headers, "X-Amz-Security-Token"); ok {
+				auth.SessionToken = token
+				deleteHeader(request.Headers, "X-Amz-Security-Token")
+			}
+			request.Auth = auth
+		case digest:
+			request.Auth = &HTTPAuth{Type: "digest", Username: name, Password: password}
+		default:
+			addCurlHeader(request.Headers, "Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user)))
+		}
+	} else if awsSigV4 != "" {
+		warn("Ignored --aws-sigv4 without --user credentials")
+	}
+
+	if rawURL == "" {
+		return result, fmt.Errorf("no URL in curl command")
+	}
+	if !strings.Contains(rawURL, "://") {
+		// curl assumes http:// for bare hosts
+		rawURL = "http://" + rawURL
+	}
+	request.URL = rawURL
+
+	// Body: inline data, a single data file, or a multipart form
+	switch {
+	case len(dataFiles) > 0 && len(data) == 0 && len(dataFiles) == 1 && !get:
+		if dataFiles[0] == "-" {
+			warn("Ignored data from standard input")
+		} else {
+			result.Request.BodyFile = dataFiles[0]
+			warn("The body is read from %s, which must be inside the storage folder", dataFiles[0])
+		}
+	case len(dataFiles) > 0:
+		warn("Ignored data files %s; only a single data file is supported", strings.Join(dataFiles, ", "))
+	}
+	hasData := len(data) > 0 || result.Request.BodyFile != ""
+	if len(form) > 0 && hasData {
+		warn("Ignored -F options; curl cannot combine them with -d either")
+		form = nil
+	}
+
+	switch {
+	case get && len(data) > 0:
+		separator := "?"
+		if strings.Contains(request.URL, "?") {
+			separator = "&"
+		}
+		request.URL += separator + strings.Join(data, "&")
+	case jsonData:
+		request.Body = strings.Join(data, "")
+		if _, ok := lookupHeader(request.Headers, "Content-Type"); !ok {
+			request.Headers["Content-Type"] = "application/json"
+		}
+		if _, ok := lookupHeader(request.Headers, "Accept"); !ok {
+			request.Headers["Accept"] = "application/json"
+		}
+	case hasData:
+		request.Body = strings.Join(data, "&")
+		if _, ok := lookupHeader(request.Headers, "Content-Type"); !ok {
+			request.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+		}
+	case len(form) > 0:
+		body, warnings := curlMultipartBody(form)
+		request.StructuredBody = body
+		deleteHeader(request.Headers, "Content-Type")
+		result.Warnings = append(result.Warnings, warnings...)
+	}
+
+	switch {
+	case method != "":
+		request.Method = method
+	case head:
+		request.Method = "HEAD"
+	case (hasData || len(form) > 0) && !get:
+		request.Method = "POST"
+	default:
+		request.Method = "GET"
+	}
+
+	result.Request.Request = request
+	return result, nil
+}
+
+// curlSettings returns the request's settings, creating them if needed
+func curlSettings(request *HTTPRequest) *HTTPTransportSettings {
+	if request.Settings == nil {
+		request.Settings = &HTTPTransportSettings{}
+	}
+	return request.Settings
+}
+
+// addCurlHeader sets a header, joining repeated ones the way servers read
+// them: cookies with "; ", others with ", "
+func addCurlHeader(headers map[string]string, name string, value string) {
+	for key, existing := range headers {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		separator := ", "
+		if strings.EqualFold(name, "Cookie") {
+			separator = "; "
+		}
+		headers[key] = existing + separator + value
+		return
+	}
+	headers[name] = value
+}
+
+// deleteHeader removes a header whatever its case
+func deleteHeader(headers map[string]string, name string) {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			delete(headers, key)
+		}
+	}
+}
+
+// curlURLEncode applies --data-urlencode: "content", "=content" and
+// "name=content" encode the content. Reading from files is not supported.
+func curlURLEncode(value string) (string, bool) {
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		if strings.Contains(value, "@") {
+			return "", false
+		}
+		return percentEncode(value), true
+	}
+	if strings.Contains(name, "@") {
+		return "", false
+	}
+	if name == "" {
+		return percentEncode(content), true
+	}
+	return name + "=" + percentEncode(content), true
+}
+
+// percentEncode escapes everything but unreserved characters, as curl does
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// curlMultipartBody builds a multipart body from -F and --form-string
+// options, each given as "option\x00name=value"
+func curlMultipartBody(form []string) (*HTTPBody, []string) {
+	body := &HTTPBody{Type: "multipart"}
+	var warnings []string
+	for _, entry := range form {
+		option, field, _ := strings.Cut(entry, "\x00")
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("Ignored form field %q", field))
+			continue
+		}
+
+		part := HTTPBodyField{Name: name}
+		if option == "form" && (strings.HasPrefix(value, "@") || strings.HasPrefix(value, "<")) {
+			attrs := strings.Split(value[1:], ";")
+			part.File = attrs[0]
+			for _, attr := range attrs[1:] {
+				key, v, _ := strings.Cut(strings.TrimSpace(attr), "=")
+				switch key {
+				case "type":
+					part.ContentType = v
+				case "filename":
+					part.FileName = strings.Trim(v, `"`)
+				}
+			}
+			if value[0] == '<' {
+				warnings = append(warnings, fmt.Sprintf("Form field %q is sent as a file upload of %s", name, part.File))
+			}
+			warnings = append(warnings, fmt.Sprintf("Form field %q uploads %s; .http files can only upload files inside the storage folder", name, part.File))
+			body.Fields = append(body.Fields, part)
+			continue
+		}
+
+		if option == "form" {
+			if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+				value = unquoted
+			} else if i := strings.LastIndex(value, ";type="); i >= 0 {
+				value, part.ContentType = value[:i], value[i+len(";type="):]
+			}
+		}
+		part.Value = value
+		body.Fields = append(body.Fields, part)
+	}
+	return body, warnings
+}
+
+// splitShellWords splits a POSIX shell command line into words, handling
+// single, double and $'...' quotes, backslash escapes and line
+// continuations. Text after an unquoted # is a comment.
+func splitShellWords(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	end := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	s := command
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			end()
+		case c == '#' && !inWord:
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+					i++
+				}
+				if s[i] != '\n' {
+					word.WriteByte(s[i])
+					inWord = true
+				}
+			}
+		case c == '\'':
+			closing := strings.IndexByte(s[i+1:], '\'')
+			if closing < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+closing])
+			i += closing + 1
+			inWord = true
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			n, err := readANSIQuoted(s[i+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+			inWord = true
+		case c == '"' || (c == '$' && i+1 < len(s) && s[i+1] == '"'):
+			if c == '$' {
+				i++
+			}
+			closed := false
+			for i++; i < len(s); i++ {
+				if s[i] == '"' {
+					closed = true
+					break
+				}
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	end()
+	return words, nil
+}
+
+// readANSIQuoted decodes the body of a $'...' string, returning how many
+// bytes it consumed including the closing quote
+func readANSIQuoted(s string, word *strings.Builder) (int, error) {
+	simple := map[byte]byte{
+		'n': '\n', 't': '\t', 'r': '\r', 'a': '\a', 'b': '\b', 'f': '\f',
+		'v': '\v', 'e': 0x1b, 'E': 0x1b, '\\': '\\', '\'': '\'', '"': '"', '?': '?',
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			return i + 1, nil
+		case c != '\\' || i+1 == len(s):
+			word.WriteByte(c)
+		default:
+			i++
+			e := s[i]
+			if r, ok := simple[e]; ok {
+				word.WriteByte(r)
+				continue
+			}
+
+			var base, width int
+			switch {
+			case e == 'x':
+				base, width = 16, 2
+			case e == 'u':
+				base, width = 16, 4
+			case e == 'U':
+				base, width = 16, 8
+			case e >= '0' && e <= '7':
+				base, width = 8, 3
+				i-- // the first digit is part of the number
+			default:
+				word.WriteByte('\\')
+				word.WriteByte(e)
+				continue
+			}
+			digits := "01234567"
+			if base == 16 {
+				digits = "0123456789abcdefABCDEF"
+			}
+			n := 0
+			for n < width && i+1+n < len(s) && strings.IndexByte(digits, s[i+1+n]) >= 0 {
+				n++
+			}
+			if n == 0 {
+				word.WriteByte('\\')
+				word.WriteByte(e)
+				continue
+			}
+			value, _ := strconv.ParseUint(s[i+1:i+1+n], base, 32)
+			i += n
+			if e == 'x' || base == 8 {
+				word.WriteByte(byte(value))
+			} else {
+				word.WriteRune(rune(value))
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated $' quote")
+}
+
+// curlCommand writes a resolved request as a curl command, one option per
+// line, quoted for POSIX shells
+func curlCommand(request HTTPRequest, settings HTTPTransportSettings) string {
+	if flat, err := flattenBody(request); err == nil {
+		request = flat
+	}
+	var multipartFields []HTTPBodyField
+	if request.StructuredBody != nil && request.StructuredBody.Type == "multipart" {
+		multipartFields = request.StructuredBody.Fields
+		headers := make(map[string]string, len(request.Headers))
+		for key, value := range request.Headers {
+			headers[key] = value
+		}
+		// curl writes its own Content-Type with the boundary
+		deleteHeader(headers, "Content-Type")
+		request.Headers = headers
+	}
+
+	method := strings.ToUpper(request.Method)
+	if method == "" {
+		method = "GET"
+	}
+
+	first := "curl"
+	switch {
+	case method == "HEAD":
+		first += " --head"
+	case method == "GET" && request.Body == "":
+	case method == "POST" && (request.Body != "" || multipartFields != nil):
+	default:
+		first += " -X " + shellQuote(method)
+	}
+	lines := []string{first + " " + shellQuote(request.URL)}
+
+	keys := make([]string, 0, len(request.Headers))
+	for key := range request.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		header := key + ": " + request.Headers[key]
+		if request.Headers[key] == "" {
+			// "Name;" is how curl sends an empty header
+			header = key + ";"
+		}
+		lines = append(lines, "-H "+shellQuote(header))
+	}
+	if request.Body != "" && multipartFields == nil {
+		lines = append(lines, "--data-raw "+shellQuote(request.Body))
+	}
+	for _, field := range multipartFields {
+		switch {
+		case field.File != "":
+			spec := field.Name + "=@" + field.File
+			if field.ContentType != "" {
+				spec += ";type=" + field.ContentType
+			}
+			if field.FileName != "" {
+				spec += ";filename=" + field.FileName
+			}
+			lines = append(lines, "-F "+shellQuote(spec))
+		case field.ContentType != "":
+			lines = append(lines, "-F "+shellQuote(field.Name+"="+field.Value+";type="+field.ContentType))
+		default:
+			// --form-string keeps a leading @ or < literal
+			lines = append(lines, "--form-string "+shellQuote(field.Name+"="+field.Value))
+		}
+	}
+	// exportAuth leaves only the schemes curl signs itself
+	if auth := request.Auth; auth != nil {
+		switch strings.ToLower(auth.Type) {
+		case "digest":
+			lines = append(lines, "--digest -u "+shellQuote(auth.Username+":"+auth.Password))
+		case "aws":
+			provider := "aws:amz"
+			if auth.Region != "" {
+				provider += ":" + auth.Region
+				if auth.Service != "" {
+					provider += ":" + auth.Service
+				}
+			}
+			lines = append(lines, "--aws-sigv4 "+shellQuote(provider), "-u "+shellQuote(auth.AccessKey+":"+auth.SecretKey))
+			if auth.SessionToken != "" {
+				lines = append(lines, "-H "+shellQuote("X-Amz-Security-Token: "+auth.SessionToken))
+			}
+		}
+	}
+
+	if settings.FollowRedirects == nil || *settings.FollowRedirects {
+		redirects := "-L"
+		if settings.MaxRedirects > 0 {
+			redirects += fmt.Sprintf(" --max-redirs %d", settings.MaxRedirects)
+		}
+		lines = append(lines, redirects)
+	}
+	if settings.InsecureSkipVerify != nil && *settings.InsecureSkipVerify {
+		lines = append(lines, "-k")
+	}
+	if settings.ConnectTimeout > 0 {
+		lines = append(lines, "--connect-timeout "+formatSeconds(settings.ConnectTimeout))
+	}
+	if settings.ReadTimeout > 0 {
+		lines = append(lines, "--max-time "+formatSeconds(settings.ReadTimeout))
+	}
+	switch settings.Proxy {
+	case "":
+	case "direct", "none":
+		lines = append(lines, "--noproxy '*'")
+	default:
+		lines = append(lines, "-x "+shellQuote(settings.Proxy))
+	}
+	switch settings.HTTPVersion {
+	case "1.1":
+		lines = append(lines, "--http1.1")
+	case "2":
+		lines = append(lines, "--http2")
+	}
+	if settings.CACertPath != "" {
+		lines = append(lines, "--cacert "+shellQuote(expandHome(settings.CACertPath)))
+	}
+	if settings.ClientCertPath != "" {
+		lines = append(lines, "--cert "+shellQuote(expandHome(settings.ClientCertPath)))
+		if settings.ClientKeyPath != "" {
+			lines = append(lines, "--key "+shellQuote(expandHome(settings.ClientKeyPath)))
+		}
+	}
+	// Responses are always decompressed here, so ask curl to do the same
+	lines = append(lines, "--compressed")
+
+	return strings.Join(lines, " \\\n  ")
+}
+
+// formatSeconds writes milliseconds as seconds for curl
+func formatSeconds(ms int) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)
+}
+
+// shellQuote quotes s for a POSIX shell, leaving plain words as they are.
+// Control characters other than newlines use bash's $'...' quoting so they
+// survive copy and paste.
+func shellQuote(s string) string {
+	if shellSafePattern.MatchString(s) {
+		return s
+	}
+	if !strings.ContainsFunc(s, func(r rune) bool { return r < ' ' && r != '\n' || r == 0x7f }) {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+
+	var b strings.Builder
+	b.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '\'':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < ' ' || c == 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteString("'")
+	return b.String()
+}
+
+// requestFileName names a .http file after the last segment of a
+// request's URL path, or its host
+func requestFileName(request HTTPRequest) string {
+	name := ""
+	if u, err := url.Parse(request.URL); err == nil {
+		name = path.Base(strings.TrimRight(u.Path, "/"))
+		if name == "." || name == "/" || name == "" {
+			name = u.Hostname()
+		}
+	}
+
+	name = safeFileName(name)
+	if name == "" {
+		name = "request"
+	}
+	return name + ".http"
+}
+
+// safeFileName replaces the characters of name that are not portable
+// across file systems
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || strings.ContainsRune(`<>:"/\|?*{}`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.Trim(name, " .")
+}
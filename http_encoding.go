@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ========== HTTP Content Encoding and Charsets ==========
+
+// acceptEncoding is sent unless the request sets Accept-Encoding itself
+const acceptEncoding = "gzip, deflate, br, zstd"
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// decodeContentEncoding undoes a Content-Encoding such as "gzip" or
+// "deflate, br". Unknown codings are left as they are. The returned
+// function releases the decoders and is never nil.
+func decodeContentEncoding(header string, body io.Reader) (io.Reader, func(), error) {
+	var closers []func()
+	release := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+
+	codings := strings.Split(header, ",")
+	// Codings are listed in the order they were applied
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+
+		// An empty body (HEAD, 204, 304) has nothing to decode
+		buffered := bufio.NewReader(body)
+		if _, err := buffered.Peek(1); err == io.EOF {
+			return buffered, release, nil
+		}
+		body = buffered
+
+		switch coding {
+		case "gzip", "x-gzip":
+			reader, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, release, fmt.Errorf("gzip: %v", err)
+			}
+			closers = append(closers, func() { reader.Close() })
+			body = reader
+		case "deflate":
+			// "deflate" should be zlib-wrapped, but some servers send raw
+			// DEFLATE. A zlib header is a multiple of 31 when read big-endian.
+			header, _ := buffered.Peek(2)
+			if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+				reader, err := zlib.NewReader(body)
+				if err != nil {
+					return nil, release, fmt.Errorf("deflate: %v", err)
+				}
+				closers = append(closers, func() { reader.Close() })
+				body = reader
+			} else {
+				reader := flate.NewReader(body)
+				closers = append(closers, func() { reader.Close() })
+				body = reader
+			}
+		case "br":
+			body = brotli.NewReader(body)
+		case "zstd":
+			decoder, err := zstd.NewReader(body)
+			if err != nil {
+				return nil, release, fmt.Errorf("zstd: %v", err)
+			}
+			closers = append(closers, decoder.Close)
+			body = decoder
+		default:
+			return body, release, nil
+		}
+	}
+	return body, release, nil
+}
+
+// transcodeCharset converts text in the charset declared by contentType to
+// UTF-8. It returns the charset it converted from, or "" if the body was
+// left alone.
+func transcodeCharset(contentType string, body []byte) ([]byte, string, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return body, "", nil
+	}
+	charset := strings.ToLower(strings.Trim(params["charset"], `"' `))
+	switch charset {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return body, "", nil
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return body, "", fmt.Errorf("unsupported charset %q", charset)
+	}
+	decoded, err := io.ReadAll(encoding.NewDecoder().Reader(bytes.NewReader(body)))
+	if err != nil {
+		return body, "", fmt.Errorf("failed to decode %s: %v", charset, err)
+	}
+	name, _ := htmlindex.Name(encoding)
+	if name == "" {
+		name = charset
+	}
+	return decoded, name, nil
+}
@@ -0,0 +1,552 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ========== HTTP Environments ==========
+
+// secretMask replaces secret values in exports
+const secretMask = "******"
+
+// HTTPVariable is a named value; secrets are masked in the UI and exports
+type HTTPVariable struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// HTTPEnvironment is a named set of variables such as dev, staging or prod
+type HTTPEnvironment struct {
+	Name      string         `json:"name"`
+	Variables []HTTPVariable `json:"variables"`
+}
+
+// HTTPEnvironmentStore is the on-disk form of all environments
+type HTTPEnvironmentStore struct {
+	Active       string            `json:"active"`
+	Environments []HTTPEnvironment `json:"environments"`
+}
+
+// environmentsPath returns the environments file inside the http folder.
+// It is a dot file so the explorer and global search skip it.
+func (a *App) environmentsPath() string {
+	return filepath.Join(a.storagePath, "http", ".environments.json")
+}
+
+// loadEnvironments reads the environment store, empty if missing
+func (a *App) loadEnvironments() (HTTPEnvironmentStore, error) {
+	store := HTTPEnvironmentStore{}
+	data, err := os.ReadFile(a.environmentsPath())
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return store, err
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return store, fmt.Errorf("invalid environments file: %w", err)
+	}
+	return store, nil
+}
+
+// saveEnvironments writes the environment store
+func (a *App) saveEnvironments(store HTTPEnvironmentStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.environmentsPath()), 0755); err != nil {
+		return err
+	}
+	// Environments may hold secrets, keep them private to the user
+	return os.WriteFile(a.environmentsPath(), data, 0600)
+}
+
+// GetHTTPEnvironments returns all environments and the active one
+func (a *App) GetHTTPEnvironments() FileSystemResponse {
+	a.envMu.Lock()
+	defer a.envMu.Unlock()
+
+	store, err := a.loadEnvironments()
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load environments: %v", err)}
+	}
+	return FileSystemResponse{Success: true, Data: store}
+}
+
+// SaveHTTPEnvironment creates or replaces an environment by name
+func (a *App) SaveHTTPEnvironment(env HTTPEnvironment) FileSystemResponse {
+	env.Name = strings.TrimSpace(env.Name)
+	if env.Name == "" {
+		return FileSystemResponse{Success: false, Error: "Environment name is required"}
+	}
+
+	a.envMu.Lock()
+	defer a.envMu.Unlock()
+
+	store, err := a.loadEnvironments()
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load environments: %v", err)}
+	}
+
+	replaced := false
+	for i := range store.Environments {
+		if store.Environments[i].Name == env.Name {
+			store.Environments[i] = env
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		store.Environments = append(store.Environments, env)
+	}
+
+	if err := a.saveEnvironments(store); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save environments: %v", err)}
+	}
+	return FileSystemResponse{Success: true, Data: env}
+}
+
+// DeleteHTTPEnvironment removes an environment by name
+func (a *App) DeleteHTTPEnvironment(name string) FileSystemResponse {
+	a.envMu.Lock()
+	defer a.envMu.Unlock()
+
+	store, err := a.loadEnvironments()
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load environments: %v", err)}
+	}
+
+	kept := store.Environments[:0]
+	for _, env := range store.Environments {
+		if env.Name != name {
+			kept = append(kept, env)
+		}
+	}
+	if len(kept) == len(store.Environments) {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Environment %q does not exist", name)}
+	}
+	store.Environments = kept
+	if store.Active == name {
+		store.Active = ""
+	}
+
+	if err := a.saveEnvironments(store); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save environments: %v", err)}
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// SetActiveHTTPEnvironment selects the environment used when a request
+// doesn't name one. An empty name means no environment.
+func (a *App) SetActiveHTTPEnvironment(name string) FileSystemResponse {
+	a.envMu.Lock()
+	defer a.envMu.Unlock()
+
+	store, err := a.loadEnvironments()
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load environments: %v", err)}
+	}
+	if name != "" && findEnvironment(store, name) == nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Environment %q does not exist", name)}
+	}
+
+	store.Active = name
+	if err := a.saveEnvironments(store); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save environments: %v", err)}
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// ExportHTTPEnvironments returns the environments as JSON with secret
+// values masked
+func (a *App) ExportHTTPEnvironments() JSONFormatResponse {
+	a.envMu.Lock()
+	store, err := a.loadEnvironments()
+	a.envMu.Unlock()
+	if err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("Failed to load environments: %v", err)}
+	}
+
+	for i := range store.Environments {
+		store.Environments[i].Variables = maskSecretVariables(store.Environments[i].Variables)
+	}
+	return marshalIndentResponse(store)
+}
+
+// maskSecretVariables returns a copy with secret values replaced
+func maskSecretVariables(vars []HTTPVariable) []HTTPVariable {
+	masked := make([]HTTPVariable, len(vars))
+	for i, v := range vars {
+		if v.Secret {
+			v.Value = secretMask
+		}
+		masked[i] = v
+	}
+	return masked
+}
+
+// secretReplacer masks the values of every environment's secret
+// variables, for reports and exports that show resolved requests
+func (a *App) secretReplacer() (*strings.Replacer, error) {
+	a.envMu.Lock()
+	store, err := a.loadEnvironments()
+	a.envMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, env := range store.Environments {
+		for _, v := range env.Variables {
+			if v.Secret && v.Value != "" {
+				values = append(values, v.Value)
+			}
+		}
+	}
+	// Longer values first, so a secret containing another is masked whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		pairs = append(pairs, value, secretMask)
+	}
+	return strings.NewReplacer(pairs...), nil
+}
+
+// findEnvironment looks an environment up by name
+func findEnvironment(store HTTPEnvironmentStore, name string) *HTTPEnvironment {
+	for i := range store.Environments {
+		if store.Environments[i].Name == name {
+			return &store.Environments[i]
+		}
+	}
+	return nil
+}
+
+// ========== Variable Interpolation ==========
+
+// variablePattern matches {{name}} and {{$dynamic args}} placeholders
+var variablePattern = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// maxVariableDepth bounds variables that reference other variables
+const maxVariableDepth = 10
+
+// variableResolver expands {{variable}} placeholders. Values are layered;
+// later layers override earlier ones, and variables captured into the
+// session override them all.
+type variableResolver struct {
+	values      map[string]string
+	dotenvDir   string
+	dotenv      map[string]string
+	session     *httpSession
+	environment string // resolved environment name, empty if none
+}
+
+// newVariableResolver prepares a resolver for the named environment, or
+// the active one if name is empty. {{$dotenv}} reads the .env file in
+// dotenvDir, which defaults to the http storage folder.
+func (a *App) newVariableResolver(name string, dotenvDir string) (*variableResolver, error) {
+	a.envMu.Lock()
+	store, err := a.loadEnvironments()
+	a.envMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &variableResolver{
+		values:    make(map[string]string),
+		dotenvDir: dotenvDir,
+	}
+	if dotenvDir == "" {
+		r.dotenvDir = filepath.Join(a.storagePath, "http")
+	}
+
+	if name == "" {
+		name = store.Active
+	}
+	if name != "" {
+		env := findEnvironment(store, name)
+		if env == nil {
+			return nil, fmt.Errorf("environment %q does not exist", name)
+		}
+		r.set(env.Variables)
+	}
+	r.environment = name
+	return r, nil
+}
+
+// set adds a layer of variables
+func (r *variableResolver) set(vars []HTTPVariable) {
+	for _, v := range vars {
+		r.values[v.Key] = v.Value
+	}
+}
+
+// resolve expands every placeholder in s
+func (r *variableResolver) resolve(s string) (string, error) {
+	return r.expand(s, 0)
+}
+
+// expand replaces placeholders, recursing into variable values
+func (r *variableResolver) expand(s string, depth int) (string, error) {
+	if depth > maxVariableDepth {
+		return "", fmt.Errorf("variables nested too deeply in %q", s)
+	}
+
+	var firstErr error
+	result := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if firstErr != nil {
+			return match
+		}
+		expr := strings.TrimSpace(match[2 : len(match)-2])
+
+		var value string
+		var err error
+		if strings.HasPrefix(expr, "$") {
+			value, err = r.dynamic(expr)
+		} else if v, ok := r.lookup(expr); ok {
+			value, err = r.expand(v, depth+1)
+		} else if r.session != nil {
+			var found bool
+			value, found, err = r.session.reference(expr)
+			if err == nil && !found {
+				err = fmt.Errorf("unresolved variable {{%s}}", expr)
+			}
+		} else {
+			err = fmt.Errorf("unresolved variable {{%s}}", expr)
+		}
+
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		return value
+	})
+
+	return result, firstErr
+}
+
+// lookup finds a named variable, preferring session captures
+func (r *variableResolver) lookup(name string) (string, bool) {
+	if r.session != nil {
+		if value, ok := r.session.lookup(name); ok {
+			return value, true
+		}
+	}
+	value, ok := r.values[name]
+	return value, ok
+}
+
+// dynamic evaluates {{$...}} variables
+func (r *variableResolver) dynamic(expr string) (string, error) {
+	fields := strings.Fields(expr)
+	name, args := fields[0], fields[1:]
+
+	switch name {
+	case "$uuid", "$guid", "$random.uuid":
+		return uuid.NewString(), nil
+	case "$timestamp":
+		t, err := applyTimeOffset(time.Now(), args)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "$isoTimestamp":
+		return time.Now().UTC().Format(time.RFC3339), nil
+	case "$datetime", "$localDatetime":
+		if len(args) == 0 {
+			return "", fmt.Errorf("%s requires a format", name)
+		}
+		t, err := applyTimeOffset(time.Now(), args[1:])
+		if err != nil {
+			return "", err
+		}
+		if name == "$datetime" {
+			t = t.UTC()
+		}
+		return formatDatetime(t, args[0]), nil
+	case "$randomInt", "$random.integer":
+		min, max := 0, 1000
+		if len(args) == 2 {
+			var err1, err2 error
+			min, err1 = strconv.Atoi(args[0])
+			max, err2 = strconv.Atoi(args[1])
+			if err1 != nil || err2 != nil || max <= min {
+				return "", fmt.Errorf("invalid range for %s", name)
+			}
+		}
+		return strconv.Itoa(min + rand.Intn(max-min)), nil
+	case "$processEnv":
+		if len(args) != 1 {
+			return "", fmt.Errorf("$processEnv requires a variable name")
+		}
+		return os.Getenv(r.indirectName(args[0])), nil
+	case "$dotenv":
+		if len(args) != 1 {
+			return "", fmt.Errorf("$dotenv requires a variable name")
+		}
+		return r.dotenvValue(r.indirectName(args[0]))
+	}
+	return "", fmt.Errorf("unknown dynamic variable {{%s}}", expr)
+}
+
+// indirectName supports the %name form, which looks the real name up in
+// the environment first
+func (r *variableResolver) indirectName(name string) string {
+	if strings.HasPrefix(name, "%") {
+		if v, ok := r.values[name[1:]]; ok {
+			return v
+		}
+		return name[1:]
+	}
+	return name
+}
+
+// dotenvValue reads NAME from the nearest .env file
+func (r *variableResolver) dotenvValue(name string) (string, error) {
+	if r.dotenv == nil {
+		r.dotenv = make(map[string]string)
+		if err := loadDotenv(filepath.Join(r.dotenvDir, ".env"), r.dotenv); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read .env: %v", err)
+		}
+	}
+	value, ok := r.dotenv[name]
+	if !ok {
+		return "", fmt.Errorf("%s is not defined in .env", name)
+	}
+	return value, nil
+}
+
+// loadDotenv parses KEY=VALUE lines, ignoring comments and "export"
+func loadDotenv(path string, into map[string]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		into[strings.TrimSpace(key)] = value
+	}
+	return scanner.Err()
+}
+
+// applyTimeOffset applies an optional "<amount> <unit>" offset, where unit
+// is one of y, M, w, d, h, m, s, ms
+func applyTimeOffset(t time.Time, args []string) (time.Time, error) {
+	if len(args) == 0 {
+		return t, nil
+	}
+	if len(args) != 2 {
+		return t, fmt.Errorf("time offset must be \"<amount> <unit>\"")
+	}
+
+	amount, err := strconv.Atoi(args[0])
+	if err != nil {
+		return t, fmt.Errorf("invalid time offset %q", args[0])
+	}
+	switch args[1] {
+	case "y":
+		return t.AddDate(amount, 0, 0), nil
+	case "M":
+		return t.AddDate(0, amount, 0), nil
+	case "w":
+		return t.AddDate(0, 0, 7*amount), nil
+	case "d":
+		return t.AddDate(0, 0, amount), nil
+	case "h":
+		return t.Add(time.Duration(amount) * time.Hour), nil
+	case "m":
+		return t.Add(time.Duration(amount) * time.Minute), nil
+	case "s":
+		return t.Add(time.Duration(amount) * time.Second), nil
+	case "ms":
+		return t.Add(time.Duration(amount) * time.Millisecond), nil
+	}
+	return t, fmt.Errorf("invalid time offset unit %q", args[1])
+}
+
+// formatDatetime supports rfc1123, iso8601 or a quoted Go layout
+func formatDatetime(t time.Time, format string) string {
+	switch format {
+	case "rfc1123":
+		return t.Format(time.RFC1123)
+	case "iso8601":
+		return t.Format(time.RFC3339)
+	}
+	return t.Format(strings.Trim(format, `"'`))
+}
+
+// resolveRequest expands variables in the URL, headers, body and expected
+// assertion values, and pins the request to the resolved environment
+func (r *variableResolver) resolveRequest(request HTTPRequest) (HTTPRequest, error) {
+	request.Environment = r.environment
+
+	var err error
+	if request.URL, err = r.resolve(request.URL); err != nil {
+		return request, err
+	}
+
+	headers := make(map[string]string, len(request.Headers))
+	for key, value := range request.Headers {
+		resolvedKey, err := r.resolve(key)
+		if err != nil {
+			return request, err
+		}
+		if headers[resolvedKey], err = r.resolve(value); err != nil {
+			return request, err
+		}
+	}
+	request.Headers = headers
+
+	if request.Body, err = r.resolve(request.Body); err != nil {
+		return request, err
+	}
+	if request.StructuredBody != nil {
+		if request.StructuredBody, err = r.resolveBody(request.StructuredBody); err != nil {
+			return request, err
+		}
+	}
+	if request.Auth != nil {
+		if request.Auth, err = r.resolveAuth(request.Auth); err != nil {
+			return request, err
+		}
+	}
+
+	assertions := make([]HTTPAssertion, len(request.Assertions))
+	for i, assertion := range request.Assertions {
+		if assertion.Expected, err = r.resolve(assertion.Expected); err != nil {
+			return request, err
+		}
+		assertions[i] = assertion
+	}
+	request.Assertions = assertions
+	return request, nil
+}
@@ -0,0 +1,754 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========== HTTP File Parser ==========
+
+// HTTPFileRequest is one request parsed from a .http file. Its fields still
+// contain {{variable}} placeholders.
+type HTTPFileRequest struct {
+	Name    string      `json:"name"`
+	Line    int         `json:"line"` // 1-based line of the request line
+	Request HTTPRequest `json:"request"`
+	// BodyFile is included as the body ("< ./file"); BodyFileVariables is set
+	// for "<@ ./file", whose content is interpolated as well
+	BodyFile          string `json:"bodyFile,omitempty"`
+	BodyFileVariables bool   `json:"bodyFileVariables,omitempty"`
+	// ResponseFile receives the response body (">> ./file"); with ">>!" an
+	// existing file is overwritten instead of getting a numbered copy
+	ResponseFile      string `json:"responseFile,omitempty"`
+	ResponseOverwrite bool   `json:"responseOverwrite,omitempty"`
+}
+
+// HTTPFile is a parsed .http file
+type HTTPFile struct {
+	Path      string            `json:"path"`
+	Variables []HTTPVariable    `json:"variables"`
+	Requests  []HTTPFileRequest `json:"requests"`
+}
+
+// HTTPFileRunResult is the outcome of one request from a file
+type HTTPFileRunResult struct {
+	Name     string       `json:"name"`
+	Method   string       `json:"method"`
+	URL      string       `json:"url"`
+	Response HTTPResponse `json:"response"`
+}
+
+// HTTPFileRunResponse is the outcome of running requests from a file
+type HTTPFileRunResponse struct {
+	Results []HTTPFileRunResult `json:"results"`
+	Error   string              `json:"error"`
+}
+
+var (
+	// fileVariablePattern matches "@name = value" definitions
+	fileVariablePattern = regexp.MustCompile(`^@([A-Za-z0-9_.\-]+)\s*=\s*(.*)$`)
+	// nameAnnotationPattern matches "# @name login" and "// @name login"
+	nameAnnotationPattern = regexp.MustCompile(`^(?:#|//)\s*@name\s*=?\s*(\S+)`)
+	// captureAnnotationPattern matches "# @capture token = $.data.token"
+	captureAnnotationPattern = regexp.MustCompile(`^(?:#|//)\s*@capture\s+(.+)$`)
+	// assertAnnotationPattern matches "# @assert status == 200"
+	assertAnnotationPattern = regexp.MustCompile(`^(?:#|//)\s*@assert\s+(.+)$`)
+	// authAnnotationPattern matches "# @auth basic username=bob password=secret"
+	authAnnotationPattern = regexp.MustCompile(`^(?:#|//)\s*@auth\s+(.+)$`)
+	// settingAnnotationPattern matches "# @no-redirect", "# @insecure",
+	// "# @timeout 5 s" and "# @connection-timeout 500 ms"
+	settingAnnotationPattern = regexp.MustCompile(`^(?:#|//)\s*@(no-redirect|insecure|timeout|connection-timeout)\b\s*(.*)$`)
+	// requestLinePattern matches "METHOD url" or a bare URL
+	requestLinePattern = regexp.MustCompile(`^(?i:(GET|POST|PUT|DELETE|PATCH|HEAD|OPTIONS|TRACE|CONNECT)\s+)?(\S.*)$`)
+	// httpVersionPattern matches a trailing " HTTP/1.1"
+	httpVersionPattern = regexp.MustCompile(`\s+HTTP/[\d.]+$`)
+)
+
+// ParseHTTPFile parses a stored .http file into its requests
+func (a *App) ParseHTTPFile(filePath string) FileSystemResponse {
+	file, err := a.loadHTTPFile(filePath)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: file}
+}
+
+// RunHTTPFileRequest sends the request with the given name from a .http file
+func (a *App) RunHTTPFileRequest(filePath string, name string, environment string) HTTPResponse {
+	file, err := a.loadHTTPFile(filePath)
+	if err != nil {
+		return HTTPResponse{Error: err.Error()}
+	}
+
+	for _, request := range file.Requests {
+		if request.Name == name {
+			resolver, err := a.newFileVariableResolver(file, environment)
+			if err != nil {
+				return HTTPResponse{Error: fmt.Sprintf("Environment error: %v", err)}
+			}
+			_, response := a.runHTTPFileRequest(file, request, resolver)
+			return response
+		}
+	}
+	return HTTPResponse{Error: fmt.Sprintf("Request %q not found in %s", name, filepath.Base(filePath))}
+}
+
+// RunHTTPFile sends every request of a .http file in order
+func (a *App) RunHTTPFile(filePath string, environment string) HTTPFileRunResponse {
+	file, err := a.loadHTTPFile(filePath)
+	if err != nil {
+		return HTTPFileRunResponse{Error: err.Error()}
+	}
+
+	resolver, err := a.newFileVariableResolver(file, environment)
+	if err != nil {
+		return HTTPFileRunResponse{Error: fmt.Sprintf("Environment error: %v", err)}
+	}
+
+	results := []HTTPFileRunResult{}
+	for _, request := range file.Requests {
+		resolved, response := a.runHTTPFileRequest(file, request, resolver)
+		results = append(results, HTTPFileRunResult{
+			Name:     request.Name,
+			Method:   resolved.Method,
+			URL:      resolved.URL,
+			Response: response,
+		})
+	}
+	return HTTPFileRunResponse{Results: results}
+}
+
+// loadHTTPFile reads and parses a .http file from storage
+func (a *App) loadHTTPFile(filePath string) (*HTTPFile, error) {
+	absPath, err := a.resolveStoragePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read file: %v", err)
+	}
+
+	file := parseHTTPFile(string(content))
+	file.Path = absPath
+	return file, nil
+}
+
+// newFileVariableResolver layers the file's variables over the environment.
+// Requests of a file share a session named after the file's ID.
+func (a *App) newFileVariableResolver(file *HTTPFile, environment string) (*variableResolver, error) {
+	resolver, err := a.newVariableResolver(environment, filepath.Dir(file.Path))
+	if err != nil {
+		return nil, err
+	}
+	resolver.set(file.Variables)
+	relPath, _ := filepath.Rel(a.storagePath, file.Path)
+	resolver.session = a.session(relPath)
+	return resolver, nil
+}
+
+// runHTTPFileRequest resolves, sends and post-processes one file request
+func (a *App) runHTTPFileRequest(file *HTTPFile, request HTTPFileRequest, resolver *variableResolver) (HTTPRequest, HTTPResponse) {
+	resolved, err := a.prepareHTTPFileRequest(file, request, resolver)
+	if err != nil {
+		return resolved, HTTPResponse{Error: err.Error()}
+	}
+
+	response := a.executeHTTPRequest(resolved)
+	applyCaptures(request.Request.Captures, &response)
+	a.evaluateAssertions(resolved.Assertions, &response, filepath.Dir(file.Path))
+	resolver.session.record(request.Name, resolved, response)
+
+	if request.ResponseFile != "" && response.Error == "" {
+		if err := a.writeResponseFile(file.Path, request.ResponseFile, request.ResponseOverwrite, responseBodyBytes(response)); err != nil {
+			response.Error = fmt.Sprintf("Failed to save response: %v", err)
+		}
+	}
+
+	// Keep the body of "< ./file" requests so a promoted copy has it
+	original := request.Request
+	if request.BodyFile != "" {
+		original.Body = resolved.Body
+	}
+	source, _ := filepath.Rel(a.storagePath, file.Path)
+	a.recordHistory(HTTPHistoryEntry{
+		HTTPHistorySummary: HTTPHistorySummary{Source: source, Name: request.Name, Environment: resolver.environment},
+		Request:            resolved,
+		Original:           &original,
+		Response:           response,
+	})
+	return resolved, response
+}
+
+// prepareHTTPFileRequest resolves a file request's variables and reads its
+// body file, giving the request as it would be sent
+func (a *App) prepareHTTPFileRequest(file *HTTPFile, request HTTPFileRequest, resolver *variableResolver) (HTTPRequest, error) {
+	resolved, err := resolver.resolveRequest(request.Request)
+	if err != nil {
+		return resolved, fmt.Errorf("Variable error: %v", err)
+	}
+
+	if request.BodyFile != "" {
+		body, err := a.readRelativeFile(file.Path, request.BodyFile)
+		if err != nil {
+			return resolved, fmt.Errorf("Failed to read body file: %v", err)
+		}
+		if request.BodyFileVariables {
+			if body, err = resolver.resolve(body); err != nil {
+				return resolved, fmt.Errorf("Variable error: %v", err)
+			}
+		}
+		resolved.Body = body
+	}
+
+	// Multipart uploads are relative to the .http file too
+	return resolveBodyFiles(resolved, func(path string) (string, error) {
+		return a.relativeStoragePath(file.Path, path)
+	})
+}
+
+// relativeStoragePath resolves target against the folder of a .http file,
+// refusing paths that leave the storage directory
+func (a *App) relativeStoragePath(httpFilePath string, target string) (string, error) {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(httpFilePath), target)
+	}
+	return a.resolveStoragePath(target)
+}
+
+// readRelativeFile reads a file referenced from a .http file
+func (a *App) readRelativeFile(httpFilePath string, target string) (string, error) {
+	absPath, err := a.relativeStoragePath(httpFilePath, target)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// writeResponseFile stores a response body next to the .http file
+func (a *App) writeResponseFile(httpFilePath string, target string, overwrite bool, body []byte) error {
+	absPath, err := a.relativeStoragePath(httpFilePath, target)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return err
+	}
+	if !overwrite {
+		absPath = uniquePath(absPath)
+	}
+	return os.WriteFile(absPath, body, 0644)
+}
+
+// uniquePath appends _1, _2, ... before the extension until path is unused
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	candidate := path
+	for counter := 1; ; counter++ {
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d%s", base, counter, ext)
+	}
+}
+
+// parseHTTPFile parses the VS Code REST Client / JetBrains HTTP Client
+// format: requests separated by ###, "# @name" annotations, "@var = value"
+// file variables, "< file" body includes and ">> file" response redirects.
+func parseHTTPFile(content string) *HTTPFile {
+	file := &HTTPFile{Variables: []HTTPVariable{}, Requests: []HTTPFileRequest{}}
+
+	type block struct {
+		title string
+		start int
+		lines []string
+	}
+	var blocks []block
+	current := block{start: 1}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(strings.TrimSpace(line), "###") {
+			blocks = append(blocks, current)
+			current = block{title: strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#")), start: lineNo + 1}
+			continue
+		}
+		current.lines = append(current.lines, line)
+	}
+	blocks = append(blocks, current)
+
+	for _, b := range blocks {
+		request, ok := parseHTTPBlock(b.lines, b.start, file)
+		if !ok {
+			continue
+		}
+		if request.Name == "" {
+			request.Name = b.title
+		}
+		if request.Name == "" {
+			request.Name = fmt.Sprintf("%s %s", request.Request.Method, request.Request.URL)
+		}
+		file.Requests = append(file.Requests, request)
+	}
+
+	return file
+}
+
+// parseHTTPBlock parses the text between two ### separators. File variables
+// found before the request line are added to file.
+func parseHTTPBlock(lines []string, firstLine int, file *HTTPFile) (HTTPFileRequest, bool) {
+	request := HTTPFileRequest{Request: HTTPRequest{Headers: map[string]string{}}}
+
+	// 1. Preamble: blank lines, comments, annotations and file variables
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if m := nameAnnotationPattern.FindStringSubmatch(line); m != nil {
+			request.Name = m[1]
+			continue
+		}
+		if m := captureAnnotationPattern.FindStringSubmatch(line); m != nil {
+			if capture, ok := parseCaptureAnnotation(m[1]); ok {
+				request.Request.Captures = append(request.Request.Captures, capture)
+			}
+			continue
+		}
+		if m := assertAnnotationPattern.FindStringSubmatch(line); m != nil {
+			if assertion, ok := parseAssertAnnotation(m[1]); ok {
+				request.Request.Assertions = append(request.Request.Assertions, assertion)
+			}
+			continue
+		}
+		if m := authAnnotationPattern.FindStringSubmatch(line); m != nil {
+			if auth, ok := parseAuthAnnotation(m[1]); ok {
+				request.Request.Auth = auth
+			}
+			continue
+		}
+		if m := settingAnnotationPattern.FindStringSubmatch(line); m != nil {
+			if request.Request.Settings == nil {
+				request.Request.Settings = &HTTPTransportSettings{}
+			}
+			applySettingAnnotation(request.Request.Settings, m[1], m[2])
+			continue
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if m := fileVariablePattern.FindStringSubmatch(line); m != nil {
+			file.Variables = append(file.Variables, HTTPVariable{Key: m[1], Value: strings.TrimSpace(m[2])})
+			continue
+		}
+		break
+	}
+	if i == len(lines) {
+		return request, false
+	}
+
+	// 2. Request line, followed by optional "?a=1" / "&b=2" continuation lines
+	m := requestLinePattern.FindStringSubmatch(strings.TrimSpace(lines[i]))
+	if m == nil {
+		return request, false
+	}
+	request.Line = firstLine + i
+	request.Request.Method = strings.ToUpper(m[1])
+	if request.Request.Method == "" {
+		request.Request.Method = "GET"
+	}
+	url := m[2]
+	for i++; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "?") && !strings.HasPrefix(line, "&") {
+			break
+		}
+		url += line
+	}
+	request.Request.URL = httpVersionPattern.ReplaceAllString(url, "")
+
+	// 3. Headers until the first blank line
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			i++
+			break
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if existing, ok := request.Request.Headers[key]; ok {
+			separator := ", "
+			if strings.EqualFold(key, "Cookie") {
+				separator = "; "
+			}
+			value = existing + separator + value
+		}
+		request.Request.Headers[key] = value
+	}
+
+	// 4. Body, with include, redirect and response handler lines pulled out
+	var body []string
+	inHandler := false
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case inHandler:
+			if strings.HasSuffix(trimmed, "%}") {
+				inHandler = false
+			}
+			continue
+		case strings.HasPrefix(trimmed, ">>"):
+			target := strings.TrimPrefix(trimmed, ">>")
+			request.ResponseOverwrite = strings.HasPrefix(target, "!")
+			request.ResponseFile = strings.TrimSpace(strings.TrimPrefix(target, "!"))
+			continue
+		case strings.HasPrefix(trimmed, "> {%"):
+			// JetBrains response handler scripts are not supported, skip them
+			inHandler = !strings.HasSuffix(trimmed, "%}")
+			continue
+		case strings.HasPrefix(trimmed, "> ") && len(body) == 0:
+			continue
+		case (strings.HasPrefix(trimmed, "< ") || strings.HasPrefix(trimmed, "<@ ")) && len(body) == 0 && request.BodyFile == "":
+			target := strings.TrimPrefix(trimmed, "<")
+			request.BodyFileVariables = strings.HasPrefix(target, "@")
+			request.BodyFile = strings.TrimSpace(strings.TrimPrefix(target, "@"))
+			continue
+		}
+		body = append(body, line)
+	}
+
+	// Trailing blank lines separate requests, they are not part of the body
+	for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+		body = body[:len(body)-1]
+	}
+	request.Request.Body = strings.Join(body, "\n")
+
+	// Multipart bodies with "< ./file" parts upload those files
+	if multipartBody, ok := parseMultipartText(request.Request.Headers, request.Request.Body); ok {
+		deleteHeader(request.Request.Headers, "Content-Type")
+		request.Request.Body = ""
+		request.Request.StructuredBody = multipartBody
+	}
+
+	return request, true
+}
+
+// parseMultipartText reads a multipart/form-data body written out in a
+// .http file. It only succeeds if a part includes a file with "< path";
+// other multipart bodies are sent as the text they are.
+func parseMultipartText(headers map[string]string, body string) (*HTTPBody, bool) {
+	contentType, _ := lookupHeader(headers, "Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil, false
+	}
+
+	delimiter := "--" + params["boundary"]
+	result := &HTTPBody{Type: "multipart"}
+	hasFile := false
+	var part []string
+	inPart := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != delimiter && trimmed != delimiter+"--" {
+			if inPart {
+				part = append(part, line)
+			}
+			continue
+		}
+
+		if inPart {
+			field, ok := parseMultipartTextPart(part)
+			if !ok {
+				return nil, false
+			}
+			hasFile = hasFile || field.File != ""
+			result.Fields = append(result.Fields, field)
+		}
+		part, inPart = nil, trimmed == delimiter
+	}
+	return result, hasFile && !inPart
+}
+
+// parseMultipartTextPart reads the headers and content of one part
+func parseMultipartTextPart(lines []string) (HTTPBodyField, bool) {
+	var field HTTPBodyField
+	fileName := ""
+	i := 0
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+		key, value, _ := strings.Cut(lines[i], ":")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "content-disposition":
+			_, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+			if err != nil {
+				return field, false
+			}
+			field.Name, fileName = params["name"], params["filename"]
+		case "content-type":
+			field.ContentType = strings.TrimSpace(value)
+		}
+	}
+	if field.Name == "" || i == len(lines) {
+		return field, false
+	}
+
+	content := strings.Join(lines[i+1:], "\n")
+	if path, ok := strings.CutPrefix(strings.TrimSpace(content), "< "); ok && !strings.Contains(content, "\n") {
+		field.File = strings.TrimSpace(path)
+		if fileName != filepath.Base(field.File) {
+			field.FileName = fileName
+		}
+		return field, true
+	}
+	field.Value = content
+	return field, true
+}
+
+// applySettingAnnotation applies a transport annotation. Timeouts are in
+// seconds unless followed by a unit: ms, s or m.
+func applySettingAnnotation(settings *HTTPTransportSettings, name string, value string) {
+	switch name {
+	case "no-redirect":
+		follow := false
+		settings.FollowRedirects = &follow
+		return
+	case "insecure":
+		insecure := true
+		settings.InsecureSkipVerify = &insecure
+		return
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return
+	}
+	amount, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || amount <= 0 {
+		return
+	}
+	unit := time.Second
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "ms":
+			unit = time.Millisecond
+		case "m":
+			unit = time.Minute
+		}
+	}
+	ms := int(amount * float64(unit/time.Millisecond))
+	if name == "timeout" {
+		settings.ReadTimeout = ms
+	} else {
+		settings.ConnectTimeout = ms
+	}
+}
+
+// formatHTTPFile writes file variables and requests in the format read by
+// parseHTTPFile
+func formatHTTPFile(variables []HTTPVariable, requests []HTTPFileRequest) string {
+	var b strings.Builder
+	for _, v := range variables {
+		fmt.Fprintf(&b, "@%s = %s\n", v.Key, v.Value)
+	}
+	for i, request := range requests {
+		if i > 0 || len(variables) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(formatHTTPFileRequest(request))
+	}
+	return b.String()
+}
+
+// formatHTTPFileRequest writes one request block, starting with its ###
+// separator
+func formatHTTPFileRequest(request HTTPFileRequest) string {
+	var b strings.Builder
+	r := request.Request
+	if flat, err := flattenBody(r); err == nil {
+		r = flat
+	}
+	name := strings.Join(strings.Fields(request.Name), " ")
+	if name == "" {
+		name = strings.Join(strings.Fields(r.Name), " ")
+	}
+	b.WriteString(strings.TrimSpace("### " + name))
+	b.WriteString("\n")
+
+	if s := r.Settings; s != nil {
+		if s.FollowRedirects != nil && !*s.FollowRedirects {
+			b.WriteString("# @no-redirect\n")
+		}
+		if s.InsecureSkipVerify != nil && *s.InsecureSkipVerify {
+			b.WriteString("# @insecure\n")
+		}
+		if s.ReadTimeout > 0 {
+			fmt.Fprintf(&b, "# @timeout %d ms\n", s.ReadTimeout)
+		}
+		if s.ConnectTimeout > 0 {
+			fmt.Fprintf(&b, "# @connection-timeout %d ms\n", s.ConnectTimeout)
+		}
+	}
+	if r.Auth != nil {
+		fmt.Fprintf(&b, "# @auth %s\n", formatAuthAnnotation(r.Auth))
+	}
+	for _, capture := range r.Captures {
+		fmt.Fprintf(&b, "# @capture %s = %s\n", capture.Name, strings.TrimSpace(capture.Source+" "+capture.Expression))
+	}
+	for _, assertion := range r.Assertions {
+		var parts []string
+		for _, part := range []string{assertion.Type, assertion.Target, assertion.Operator, assertion.Expected} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		fmt.Fprintf(&b, "# @assert %s\n", strings.Join(parts, " "))
+	}
+
+	method := r.Method
+	if method == "" {
+		method = "GET"
+	}
+	fmt.Fprintf(&b, "%s %s\n", strings.ToUpper(method), r.URL)
+
+	// Multipart bodies are written out with their files as "< path" lines
+	multipartText := ""
+	if r.StructuredBody != nil && r.StructuredBody.Type == "multipart" {
+		var contentType string
+		multipartText, contentType = formatMultipartText(r.StructuredBody)
+		headers := make(map[string]string, len(r.Headers)+1)
+		for key, value := range r.Headers {
+			headers[key] = value
+		}
+		deleteHeader(headers, "Content-Type")
+		headers["Content-Type"] = contentType
+		r.Headers = headers
+		r.Body = multipartText
+	}
+
+	keys := make([]string, 0, len(r.Headers))
+	for key := range r.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", key, r.Headers[key])
+	}
+
+	if request.BodyFile == "" && r.Body == "" && request.ResponseFile == "" {
+		return b.String()
+	}
+	b.WriteString("\n")
+	switch {
+	case request.BodyFile != "" && request.BodyFileVariables:
+		fmt.Fprintf(&b, "<@ %s\n", request.BodyFile)
+	case request.BodyFile != "":
+		fmt.Fprintf(&b, "< %s\n", request.BodyFile)
+	case r.Body != "":
+		b.WriteString(strings.TrimRight(r.Body, "\r\n"))
+		b.WriteString("\n")
+	}
+	if request.ResponseFile != "" {
+		redirect := ">>"
+		if request.ResponseOverwrite {
+			redirect = ">>!"
+		}
+		fmt.Fprintf(&b, "%s %s\n", redirect, request.ResponseFile)
+	}
+	return b.String()
+}
+
+// formatMultipartText writes multipart parts in the form read by
+// parseMultipartText, returning the text and its Content-Type
+func formatMultipartText(body *HTTPBody) (string, string) {
+	boundary := "WebAppBoundary"
+	for n := 1; ; n++ {
+		clash := false
+		for _, field := range body.Fields {
+			clash = clash || strings.Contains(field.Value, boundary)
+		}
+		if !clash {
+			break
+		}
+		boundary = fmt.Sprintf("WebAppBoundary%d", n)
+	}
+
+	var b strings.Builder
+	for _, field := range body.Fields {
+		fmt.Fprintf(&b, "--%s\n", boundary)
+		if field.File != "" {
+			fileName := field.FileName
+			if fileName == "" {
+				fileName = filepath.Base(field.File)
+			}
+			fmt.Fprintf(&b, "Content-Disposition: form-data; name=\"%s\"; filename=\"%s\"\n", escapeQuotes(field.Name), escapeQuotes(fileName))
+		} else {
+			fmt.Fprintf(&b, "Content-Disposition: form-data; name=\"%s\"\n", escapeQuotes(field.Name))
+		}
+		if field.ContentType != "" {
+			fmt.Fprintf(&b, "Content-Type: %s\n", field.ContentType)
+		}
+		if field.File != "" {
+			fmt.Fprintf(&b, "\n< %s\n", field.File)
+		} else {
+			fmt.Fprintf(&b, "\n%s\n", field.Value)
+		}
+	}
+	fmt.Fprintf(&b, "--%s--", boundary)
+	return b.String(), "multipart/form-data; boundary=" + boundary
+}
+
+// createHTTPFile writes content to a new .http file in dir, or in the http
+// folder if dir is empty, numbering the name if it is taken
+func (a *App) createHTTPFile(dir string, fileName string, content string) (FileItem, error) {
+	if dir == "" {
+		dir = filepath.Join(a.storagePath, "http")
+	}
+	dirPath, err := a.resolveStoragePath(dir)
+	if err != nil {
+		return FileItem{}, err
+	}
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return FileItem{}, fmt.Errorf("Failed to create directory: %v", err)
+	}
+
+	fileName = strings.TrimSpace(filepath.Base(fileName))
+	if fileName == "" || fileName == "." || fileName == string(filepath.Separator) {
+		fileName = "request"
+	}
+	if ext := filepath.Ext(fileName); ext != ".http" && ext != ".rest" {
+		fileName += ".http"
+	}
+	filePath := uniquePath(filepath.Join(dirPath, fileName))
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		return FileItem{}, fmt.Errorf("Failed to write file: %v", err)
+	}
+
+	relPath, _ := filepath.Rel(a.storagePath, filePath)
+	return FileItem{ID: relPath, Name: filepath.Base(filePath), Type: "file", Path: filePath}, nil
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ========== HAR Import and Export ==========
+
+// HTTP Archive 1.2, as saved by browsers' developer tools. The same types
+// read imports and write exports; only the fields used here are declared.
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string     `json:"mimeType"`
+	Text     string     `json:"text"`
+	Params   []harParam `json:"params,omitempty"`
+}
+
+type harParam struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	FileName    string `json:"fileName,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// harTimings are in milliseconds, -1 where a phase did not happen
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harSkippedHeaders are set by the transport when the request is sent
+var harSkippedHeaders = map[string]bool{
+	"host": true, "content-length": true, "connection": true,
+}
+
+// parseHAR reads every entry of a HAR file as a request of one folder,
+// named after the first entry's host
+func parseHAR(data []byte) (*collectionImport, error) {
+	var file harFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("Invalid HAR file: %v", err)
+	}
+	c := &collectionImport{format: "har"}
+	if len(file.Log.Entries) == 0 {
+		return c, nil
+	}
+
+	folder := &importedFolder{Name: "har-import"}
+	if u, err := url.Parse(file.Log.Entries[0].Request.URL); err == nil && u.Hostname() != "" {
+		folder.Name = u.Hostname()
+	}
+	for _, entry := range file.Log.Entries {
+		folder.Requests = append(folder.Requests, c.harRequest(entry.Request))
+	}
+	c.folders = []*importedFolder{folder}
+	return c, nil
+}
+
+// harRequest converts the request of an entry, named after its method and
+// path
+func (c *collectionImport) harRequest(raw harRequest) HTTPFileRequest {
+	request := HTTPRequest{Method: strings.ToUpper(raw.Method), URL: raw.URL, Headers: map[string]string{}}
+	if request.Method == "" {
+		request.Method = "GET"
+	}
+	name := request.Method + " " + raw.URL
+	if u, err := url.Parse(raw.URL); err == nil {
+		name = request.Method + " " + u.EscapedPath()
+	}
+
+	for _, header := range raw.Headers {
+		// HTTP/2 pseudo-headers such as :authority come from the URL
+		if strings.HasPrefix(header.Name, ":") || harSkippedHeaders[strings.ToLower(header.Name)] {
+			continue
+		}
+		if existing, ok := lookupHeader(request.Headers, header.Name); ok {
+			separator := ", "
+			if strings.EqualFold(header.Name, "Cookie") {
+				separator = "; "
+			}
+			deleteHeader(request.Headers, header.Name)
+			header.Value = existing + separator + header.Value
+		}
+		request.Headers[header.Name] = header.Value
+	}
+
+	fileRequest := HTTPFileRequest{Name: name}
+	if post := raw.PostData; post != nil {
+		mediaType, _, _ := mime.ParseMediaType(post.MimeType)
+		switch {
+		case post.Text != "" || len(post.Params) == 0:
+			request.Body = post.Text
+		case mediaType == "multipart/form-data":
+			request.StructuredBody = &HTTPBody{Type: "multipart"}
+			for _, param := range post.Params {
+				part := HTTPBodyField{Name: param.Name, Value: param.Value, ContentType: param.ContentType}
+				if param.FileName != "" {
+					part.Value, part.File = "", param.FileName
+					c.warn("Request %q: the file %s was not saved in the HAR file; choose it again", name, param.FileName)
+				}
+				request.StructuredBody.Fields = append(request.StructuredBody.Fields, part)
+			}
+			deleteHeader(request.Headers, "Content-Type")
+		default:
+			request.StructuredBody = &HTTPBody{Type: "form"}
+			for _, param := range post.Params {
+				request.StructuredBody.Fields = append(request.StructuredBody.Fields, HTTPBodyField{Name: param.Name, Value: param.Value})
+			}
+		}
+		if request.StructuredBody == nil {
+			setDefaultHeader(&request, "Content-Type", post.MimeType)
+		}
+	}
+
+	fileRequest.Request = request
+	return fileRequest
+}
+
+// ExportHTTPHistoryHAR returns the history entries matching a query as a
+// HAR file, oldest first. A zero Limit exports every match.
+func (a *App) ExportHTTPHistoryHAR(query HTTPHistoryQuery) JSONFormatResponse {
+	data, err := a.historyHAR(query)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+	return JSONFormatResponse{Result: string(data)}
+}
+
+// SaveHTTPHistoryHAR exports history like ExportHTTPHistoryHAR and saves
+// it through the native save dialog. It returns the chosen path, empty if
+// cancelled.
+func (a *App) SaveHTTPHistoryHAR(query HTTPHistoryQuery) (string, error) {
+	data, err := a.historyHAR(query)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export History as HAR",
+		DefaultFilename: fmt.Sprintf("http-history-%s.har", time.Now().Format("2006-01-02-150405")),
+		Filters: []runtime.FileFilter{
+			{DisplayName: "HAR Files (*.har)", Pattern: "*.har"},
+		},
+	})
+	if err != nil || file == "" {
+		return "", err
+	}
+	return file, os.WriteFile(file, data, 0600)
+}
+
+// historyHAR renders the entries matching a query
+func (a *App) historyHAR(query HTTPHistoryQuery) ([]byte, error) {
+	if query.Limit <= 0 {
+		query.Limit = math.MaxInt
+	}
+	result, err := a.searchHistory(query)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load history: %v", err)
+	}
+	secrets, err := a.secretReplacer()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load environments: %v", err)
+	}
+
+	har := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "MacDevTools", Version: a.GetSystemInfo()["version"]},
+		Entries: []harEntry{},
+	}}
+	for i := len(result.Entries) - 1; i >= 0; i-- {
+		entry, err := a.loadHistoryEntry(result.Entries[i].ID)
+		if err != nil {
+			continue
+		}
+		har.Log.Entries = append(har.Log.Entries, historyHAREntry(entry, secrets))
+	}
+	return json.MarshalIndent(har, "", "  ")
+}
+
+// historyHAREntry converts a history entry. The request was recorded with
+// its variables resolved, so secret values are masked in it.
+func historyHAREntry(entry HTTPHistoryEntry, secrets *strings.Replacer) harEntry {
+	request, response := entry.Request, entry.Response
+	version := "HTTP/1.1"
+	if response.Timing != nil && response.Timing.Protocol != "" {
+		version = response.Timing.Protocol
+	}
+
+	result := harEntry{
+		StartedDateTime: entry.Time.Format(time.RFC3339Nano),
+		Time:            float64(response.Duration),
+		Timings:         harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: float64(response.Duration)},
+	}
+	if timing := response.Timing; timing != nil {
+		result.Time = timing.Total
+		result.Timings.Wait, result.Timings.Receive = timing.TimeToFirstByte, timing.ContentTransfer
+		if !timing.ConnectionReused {
+			result.Timings.DNS = timing.DNSLookup
+			result.Timings.Connect = timing.TCPConnect + timing.TLSHandshake
+			if timing.TLSHandshake > 0 {
+				result.Timings.SSL = timing.TLSHandshake
+			}
+		}
+		if host, _, err := net.SplitHostPort(timing.RemoteAddr); err == nil {
+			result.ServerIPAddress = host
+		}
+	}
+	if response.Error != "" {
+		result.Comment = secrets.Replace(response.Error)
+	} else if entry.BodyTruncated {
+		result.Comment = "Bodies were cut to the history size limit"
+	}
+
+	// Request
+	if flat, err := flattenBody(request); err == nil {
+		request = flat
+	}
+	bodySize := int64(len(request.Body))
+	request.URL, request.Body = secrets.Replace(request.URL), secrets.Replace(request.Body)
+	header := http.Header{}
+	for name, value := range request.Headers {
+		header.Add(name, secrets.Replace(value))
+	}
+	result.Request = harRequest{
+		Method:      strings.ToUpper(request.Method),
+		URL:         request.URL,
+		HTTPVersion: version,
+		Cookies:     harCookies((&http.Request{Header: header}).Cookies()),
+		Headers:     harHeaders(header),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    bodySize,
+	}
+	if result.Request.Method == "" {
+		result.Request.Method = "GET"
+	}
+	if u, err := url.Parse(request.URL); err == nil {
+		for _, pair := range strings.Split(u.RawQuery, "&") {
+			if pair == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(pair, "=")
+			name, _ = url.QueryUnescape(name)
+			value, _ = url.QueryUnescape(value)
+			result.Request.QueryString = append(result.Request.QueryString, harNameValue{Name: name, Value: secrets.Replace(value)})
+		}
+	}
+	contentType, _ := lookupHeader(request.Headers, "Content-Type")
+	if body := request.StructuredBody; body != nil && body.Type == "multipart" {
+		post := &harPostData{MimeType: "multipart/form-data"}
+		for _, field := range body.Fields {
+			param := harParam{Name: field.Name, Value: secrets.Replace(field.Value), ContentType: field.ContentType}
+			if field.File != "" {
+				param.Value, param.FileName = "", field.FileName
+				if param.FileName == "" {
+					param.FileName = field.File
+				}
+			}
+			post.Params = append(post.Params, param)
+		}
+		result.Request.PostData, result.Request.BodySize = post, -1
+	} else if request.Body != "" {
+		result.Request.PostData = &harPostData{MimeType: contentType, Text: request.Body}
+	}
+
+	// Response
+	responseHeader := http.Header{}
+	for _, h := range response.HeaderList {
+		responseHeader.Add(h.Name, h.Value)
+	}
+	mimeType := responseHeader.Get("Content-Type")
+	statusText := strings.TrimSpace(strings.TrimPrefix(response.Status, fmt.Sprint(response.StatusCode)))
+	result.Response = harResponse{
+		Status:      response.StatusCode,
+		StatusText:  statusText,
+		HTTPVersion: version,
+		Cookies:     harCookies((&http.Response{Header: responseHeader}).Cookies()),
+		Headers:     harHeaders(responseHeader),
+		Content:     harContent{Size: response.Size, MimeType: mimeType, Text: response.Body},
+		RedirectURL: responseHeader.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    response.CompressedSize,
+	}
+	if response.Binary {
+		result.Response.Content.Text, result.Response.Content.Encoding = response.BodyBase64, "base64"
+	}
+	if response.StatusCode == 0 {
+		result.Response.HTTPVersion, result.Response.BodySize = "", -1
+	}
+	return result
+}
+
+// harHeaders lists headers sorted by name, repeated ones in order
+func harHeaders(header http.Header) []harNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := []harNameValue{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			list = append(list, harNameValue{Name: name, Value: value})
+		}
+	}
+	return list
+}
+
+// harCookies lists the names and values of cookies
+func harCookies(cookies []*http.Cookie) []harNameValue {
+	list := []harNameValue{}
+	for _, cookie := range cookies {
+		list = append(list, harNameValue{Name: cookie.Name, Value: cookie.Value})
+	}
+	return list
+}
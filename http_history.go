@@ -0,0 +1,833 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ========== HTTP History ==========
+
+// historyAddedEvent carries the HTTPHistorySummary of each new entry
+const historyAddedEvent = "http:history:added"
+
+// Retention defaults, used when the settings leave a limit at zero
+const (
+	defaultHistoryMaxEntries  = 1000
+	defaultHistoryMaxAgeDays  = 30
+	defaultHistoryMaxBodySize = 1024 * 1024
+)
+
+// HTTPHistorySettings limits what the history keeps. Zero limits use the
+// defaults: 1000 entries, 30 days and 1 MB per body.
+type HTTPHistorySettings struct {
+	Disabled    bool `json:"disabled,omitempty"`
+	MaxEntries  int  `json:"maxEntries,omitempty"`
+	MaxAgeDays  int  `json:"maxAgeDays,omitempty"`
+	MaxBodySize int  `json:"maxBodySize,omitempty"` // bytes kept of each body
+}
+
+// HTTPHistorySummary describes an entry without its bodies; the history
+// index holds one per line
+type HTTPHistorySummary struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Source is "request" for requests sent from the editor, or the ID of
+	// the .http file the request came from
+	Source      string `json:"source"`
+	Name        string `json:"name,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	StatusCode  int    `json:"statusCode"`
+	Duration    int64  `json:"duration"`
+	Size        int64  `json:"size"`
+	Error       string `json:"error,omitempty"`
+	Passed      int    `json:"passed,omitempty"`
+	Failed      int    `json:"failed,omitempty"`
+	// ReplayOf is the entry this one replayed
+	ReplayOf string `json:"replayOf,omitempty"`
+	// Snippet shows where a search matched
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// HTTPHistoryEntry is a request as it was sent and the response to it.
+// Original keeps an editor request before its variables were resolved.
+type HTTPHistoryEntry struct {
+	HTTPHistorySummary
+	Request  HTTPRequest  `json:"request"`
+	Original *HTTPRequest `json:"original,omitempty"`
+	Response HTTPResponse `json:"response"`
+	// BodyTruncated is set when a body was cut to the size limit
+	BodyTruncated bool `json:"bodyTruncated,omitempty"`
+	// RequestTruncated is set when the request body was cut, so the
+	// request can no longer be replayed
+	RequestTruncated bool `json:"requestTruncated,omitempty"`
+}
+
+// HTTPHistoryQuery filters the history. Text is searched, ignoring case,
+// in URLs, headers, bodies and errors. Status is a code such as "404", a
+// class such as "5xx", or "error" for requests that got no response.
+type HTTPHistoryQuery struct {
+	Text        string    `json:"text,omitempty"`
+	Method      string    `json:"method,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Environment string    `json:"environment,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Since       time.Time `json:"since,omitempty"`
+	Until       time.Time `json:"until,omitempty"`
+	Limit       int       `json:"limit,omitempty"` // default 100
+	Offset      int       `json:"offset,omitempty"`
+}
+
+// HTTPHistorySearchResult is a page of matching entries, newest first
+type HTTPHistorySearchResult struct {
+	Entries []HTTPHistorySummary `json:"entries"`
+	Total   int                  `json:"total"`
+}
+
+// historyDir returns the history folder inside the http folder. It is a
+// dot folder so the explorer and global search skip it.
+func (a *App) historyDir() string {
+	return filepath.Join(a.storagePath, "http", ".history")
+}
+
+// historySettings returns the configured limits with defaults filled in
+func (a *App) historySettings() HTTPHistorySettings {
+	settings := a.loadConfig().HTTPHistory
+	if settings.MaxEntries <= 0 {
+		settings.MaxEntries = defaultHistoryMaxEntries
+	}
+	if settings.MaxAgeDays <= 0 {
+		settings.MaxAgeDays = defaultHistoryMaxAgeDays
+	}
+	if settings.MaxBodySize <= 0 {
+		settings.MaxBodySize = defaultHistoryMaxBodySize
+	}
+	return settings
+}
+
+// GetHTTPHistorySettings returns the history limits
+func (a *App) GetHTTPHistorySettings() HTTPHistorySettings {
+	return a.loadConfig().HTTPHistory
+}
+
+// SaveHTTPHistorySettings stores the history limits and applies them
+func (a *App) SaveHTTPHistorySettings(settings HTTPHistorySettings) FileSystemResponse {
+	if settings.MaxEntries < 0 || settings.MaxAgeDays < 0 || settings.MaxBodySize < 0 {
+		return FileSystemResponse{Success: false, Error: "History limits cannot be negative"}
+	}
+
+	config := a.loadConfig()
+	config.HTTPHistory = settings
+	if err := a.saveConfig(config); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save settings: %v", err)}
+	}
+
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+	if err := a.loadHistory(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load history: %v", err)}
+	}
+	if err := a.pruneHistory(a.historySettings()); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to prune history: %v", err)}
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// loadHistory reads the history index once; callers hold historyMu
+func (a *App) loadHistory() error {
+	if a.history != nil {
+		return nil
+	}
+
+	a.history = []HTTPHistorySummary{}
+	file, err := os.Open(filepath.Join(a.historyDir(), "index.jsonl"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var summary HTTPHistorySummary
+		// Skip lines cut short by a crash
+		if json.Unmarshal(scanner.Bytes(), &summary) == nil && summary.ID != "" {
+			a.history = append(a.history, summary)
+		}
+	}
+	return scanner.Err()
+}
+
+// saveHistoryIndex rewrites the index; callers hold historyMu
+func (a *App) saveHistoryIndex() error {
+	var buf bytes.Buffer
+	for _, summary := range a.history {
+		line, _ := json.Marshal(summary)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	path := filepath.Join(a.historyDir(), "index.jsonl")
+	if err := os.WriteFile(path+".tmp", buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// pruneHistory drops entries past the age and count limits; callers hold
+// historyMu
+func (a *App) pruneHistory(settings HTTPHistorySettings) error {
+	cutoff := time.Now().AddDate(0, 0, -settings.MaxAgeDays)
+	kept := make([]HTTPHistorySummary, 0, len(a.history))
+	var dropped []string
+	for i, summary := range a.history {
+		if summary.Time.Before(cutoff) || len(a.history)-i > settings.MaxEntries {
+			dropped = append(dropped, summary.ID)
+			continue
+		}
+		kept = append(kept, summary)
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	a.history = kept
+	for _, id := range dropped {
+		os.Remove(a.historyEntryPath(id))
+	}
+	return a.saveHistoryIndex()
+}
+
+// historyEntryPath returns the file of one entry
+func (a *App) historyEntryPath(id string) string {
+	return filepath.Join(a.historyDir(), filepath.Base(id)+".json")
+}
+
+// recordHistory stores an entry given its source, name, environment,
+// request and response, filling in the rest of its summary. Failures to
+// record are not reported, as they must not fail the request.
+func (a *App) recordHistory(entry HTTPHistoryEntry) {
+	settings := a.historySettings()
+	if settings.Disabled {
+		return
+	}
+
+	entry.ID = uuid.NewString()
+	entry.Time = time.Now()
+	entry.Method = strings.ToUpper(entry.Request.Method)
+	if entry.Method == "" {
+		entry.Method = "GET"
+	}
+	entry.URL = entry.Request.URL
+	entry.StatusCode = entry.Response.StatusCode
+	entry.Duration = entry.Response.Duration
+	entry.Size = entry.Response.Size
+	entry.Error = entry.Response.Error
+	entry.Passed = len(entry.Response.Passed)
+	entry.Failed = len(entry.Response.Failed)
+	entry.Snippet = ""
+
+	// Keep bodies within the size limit
+	limit := settings.MaxBodySize
+	if truncateRequestBody(&entry.Request, limit) {
+		entry.RequestTruncated = true
+	}
+	if entry.Original != nil {
+		original := *entry.Original
+		if truncateRequestBody(&original, limit) {
+			entry.RequestTruncated = true
+		}
+		entry.Original = &original
+	}
+	if entry.RequestTruncated {
+		entry.BodyTruncated = true
+	}
+	for _, body := range []*string{&entry.Response.Body, &entry.Response.BodyBase64} {
+		if len(*body) > limit {
+			*body = truncateUTF8(*body, limit)
+			entry.BodyTruncated = true
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+	if err := a.loadHistory(); err != nil {
+		return
+	}
+	if err := os.MkdirAll(a.historyDir(), 0700); err != nil {
+		return
+	}
+	// Entries hold credentials, keep them private to the user
+	if err := os.WriteFile(a.historyEntryPath(entry.ID), data, 0600); err != nil {
+		return
+	}
+	line, _ := json.Marshal(entry.HTTPHistorySummary)
+	index, err := os.OpenFile(filepath.Join(a.historyDir(), "index.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		os.Remove(a.historyEntryPath(entry.ID))
+		return
+	}
+	index.Write(append(line, '\n'))
+	index.Close()
+
+	a.history = append(a.history, entry.HTTPHistorySummary)
+	a.pruneHistory(settings)
+	a.emitEvent(historyAddedEvent, entry.HTTPHistorySummary)
+}
+
+// truncateRequestBody cuts a request's raw body and the texts of its
+// structured body, such as form fields and GraphQL queries, to limit. It
+// reports whether anything was cut.
+func truncateRequestBody(request *HTTPRequest, limit int) bool {
+	truncated := len(request.Body) > limit
+	request.Body = truncateUTF8(request.Body, limit)
+	if request.StructuredBody == nil {
+		return truncated
+	}
+	// The body may be shared with the caller's request
+	body := *request.StructuredBody
+	if len(body.Text) > limit {
+		body.Text = truncateUTF8(body.Text, limit)
+		truncated = true
+	}
+	body.Fields = append([]HTTPBodyField(nil), body.Fields...)
+	for i := range body.Fields {
+		if len(body.Fields[i].Value) > limit {
+			body.Fields[i].Value = truncateUTF8(body.Fields[i].Value, limit)
+			truncated = true
+		}
+	}
+	request.StructuredBody = &body
+	return truncated
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && n < len(s) && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
+
+// loadHistoryEntry reads one entry
+func (a *App) loadHistoryEntry(id string) (HTTPHistoryEntry, error) {
+	var entry HTTPHistoryEntry
+	data, err := os.ReadFile(a.historyEntryPath(id))
+	if os.IsNotExist(err) {
+		return entry, fmt.Errorf("History entry %q does not exist", id)
+	}
+	if err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, fmt.Errorf("invalid history entry: %w", err)
+	}
+	return entry, nil
+}
+
+// GetHTTPHistoryEntry returns one entry with its request and response
+func (a *App) GetHTTPHistoryEntry(id string) FileSystemResponse {
+	entry, err := a.loadHistoryEntry(id)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: entry}
+}
+
+// SearchHTTPHistory returns the entries matching a query, newest first
+func (a *App) SearchHTTPHistory(query HTTPHistoryQuery) FileSystemResponse {
+	if query.Limit <= 0 {
+		query.Limit = 100
+	}
+	result, err := a.searchHistory(query)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load history: %v", err)}
+	}
+	return FileSystemResponse{Success: true, Data: result}
+}
+
+// searchHistory returns a page of the entries matching a query, newest
+// first
+func (a *App) searchHistory(query HTTPHistoryQuery) (HTTPHistorySearchResult, error) {
+	a.historyMu.Lock()
+	if err := a.loadHistory(); err != nil {
+		a.historyMu.Unlock()
+		return HTTPHistorySearchResult{}, err
+	}
+	summaries := append([]HTTPHistorySummary(nil), a.history...)
+	a.historyMu.Unlock()
+
+	text := strings.ToLower(query.Text)
+	result := HTTPHistorySearchResult{Entries: []HTTPHistorySummary{}}
+	for i := len(summaries) - 1; i >= 0; i-- {
+		summary := summaries[i]
+		if !query.matches(summary) {
+			continue
+		}
+		if text != "" {
+			entry, err := a.loadHistoryEntry(summary.ID)
+			if err != nil {
+				continue
+			}
+			snippet, ok := entry.search(text)
+			if !ok {
+				continue
+			}
+			summary.Snippet = snippet
+		}
+
+		result.Total++
+		if result.Total > query.Offset && len(result.Entries) < query.Limit {
+			result.Entries = append(result.Entries, summary)
+		}
+	}
+	return result, nil
+}
+
+// matches applies the filters that need only the summary
+func (q HTTPHistoryQuery) matches(s HTTPHistorySummary) bool {
+	if q.Method != "" && !strings.EqualFold(q.Method, s.Method) {
+		return false
+	}
+	if q.URL != "" && !strings.Contains(strings.ToLower(s.URL), strings.ToLower(q.URL)) {
+		return false
+	}
+	if q.Environment != "" && q.Environment != s.Environment {
+		return false
+	}
+	if q.Source != "" && q.Source != s.Source {
+		return false
+	}
+	if !q.Since.IsZero() && s.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && s.Time.After(q.Until) {
+		return false
+	}
+
+	switch status := strings.ToLower(q.Status); {
+	case status == "":
+	case status == "error":
+		return s.Error != "" && s.StatusCode == 0
+	case len(status) == 3 && strings.HasSuffix(status, "xx"):
+		return s.StatusCode/100 == int(status[0]-'0')
+	default:
+		code, err := strconv.Atoi(status)
+		return err == nil && s.StatusCode == code
+	}
+	return true
+}
+
+// search looks for lowercase text in the entry, returning a snippet of
+// the first match
+func (e HTTPHistoryEntry) search(text string) (string, bool) {
+	fields := []string{e.Method + " " + e.URL, e.Response.Error, e.Name}
+	for key, value := range e.Request.Headers {
+		fields = append(fields, key+": "+value)
+	}
+	fields = append(fields, e.Request.Body)
+	if e.Request.StructuredBody != nil {
+		body := e.Request.StructuredBody
+		fields = append(fields, body.Text)
+		for _, field := range body.Fields {
+			fields = append(fields, field.Name+"="+field.Value)
+		}
+	}
+	for _, header := range e.Response.HeaderList {
+		fields = append(fields, header.Name+": "+header.Value)
+	}
+	fields = append(fields, e.Response.Body)
+
+	for _, field := range fields {
+		i := strings.Index(strings.ToLower(field), text)
+		if i < 0 {
+			continue
+		}
+		start, end := max(i-40, 0), min(i+len(text)+40, len(field))
+		for start > 0 && field[start]&0xC0 == 0x80 {
+			start--
+		}
+		for end < len(field) && field[end]&0xC0 == 0x80 {
+			end++
+		}
+		snippet := strings.Join(strings.Fields(field[start:end]), " ")
+		if start > 0 {
+			snippet = "…" + snippet
+		}
+		if end < len(field) {
+			snippet += "…"
+		}
+		return snippet, true
+	}
+	return "", false
+}
+
+// DeleteHTTPHistoryEntry removes one entry
+func (a *App) DeleteHTTPHistoryEntry(id string) FileSystemResponse {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+	if err := a.loadHistory(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load history: %v", err)}
+	}
+
+	kept := a.history[:0]
+	for _, summary := range a.history {
+		if summary.ID != id {
+			kept = append(kept, summary)
+		}
+	}
+	if len(kept) == len(a.history) {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("History entry %q does not exist", id)}
+	}
+	a.history = kept
+	os.Remove(a.historyEntryPath(id))
+	if err := a.saveHistoryIndex(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save history: %v", err)}
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// ClearHTTPHistory removes every entry
+func (a *App) ClearHTTPHistory() FileSystemResponse {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+	if err := os.RemoveAll(a.historyDir()); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to clear history: %v", err)}
+	}
+	a.history = []HTTPHistorySummary{}
+	return FileSystemResponse{Success: true}
+}
+
+// ReplayHTTPHistoryEntry sends an entry's request again exactly as it was
+// sent, with its assertions, and records the new exchange
+func (a *App) ReplayHTTPHistoryEntry(id string) HTTPResponse {
+	entry, err := a.loadHistoryEntry(id)
+	if err != nil {
+		return HTTPResponse{Error: err.Error()}
+	}
+	if entry.RequestTruncated {
+		return HTTPResponse{Error: "The request body was cut to the history size limit, so it cannot be replayed"}
+	}
+
+	request := entry.Request
+	request.ID = ""
+	response := a.executeHTTPRequest(request)
+	applyCaptures(request.Captures, &response)
+	a.evaluateAssertions(request.Assertions, &response, filepath.Join(a.storagePath, "http"))
+	replayOf := entry.ID
+	entry.Request, entry.Response, entry.ReplayOf = request, response, replayOf
+	entry.BodyTruncated, entry.RequestTruncated = false, false
+	a.recordHistory(entry)
+	return response
+}
+
+// PromoteHTTPHistoryEntry saves an entry's request to a .http file. An
+// editor request keeps its {{variables}}. If target is an existing .http
+// file the request is appended to it; otherwise target is the folder for
+// a new file named fileName, or after the URL if fileName is empty.
+func (a *App) PromoteHTTPHistoryEntry(id string, target string, fileName string) FileSystemResponse {
+	entry, err := a.loadHistoryEntry(id)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	if entry.RequestTruncated {
+		return FileSystemResponse{Success: false, Error: "The request body was cut to the history size limit, so it cannot be saved"}
+	}
+	request := entry.Request
+	if entry.Original != nil {
+		request = *entry.Original
+	}
+	request.ID, request.Environment, request.Session = "", "", ""
+	name := entry.Name
+	if name == "" {
+		name = entry.Method + " " + entry.URL
+	}
+	block := formatHTTPFileRequest(HTTPFileRequest{Name: name, Request: request})
+
+	if target != "" {
+		if targetPath, err := a.resolveStoragePath(target); err == nil {
+			if info, err := os.Stat(targetPath); err == nil && !info.IsDir() {
+				return a.appendHTTPFileRequest(targetPath, block)
+			}
+		}
+	}
+
+	if fileName == "" {
+		fileName = requestFileName(request)
+	}
+	item, err := a.createHTTPFile(target, fileName, block)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: item}
+}
+
+// appendHTTPFileRequest adds a request block to the end of a .http file
+func (a *App) appendHTTPFileRequest(filePath string, block string) FileSystemResponse {
+	if ext := filepath.Ext(filePath); ext != ".http" && ext != ".rest" {
+		return FileSystemResponse{Success: false, Error: "Requests can only be added to .http and .rest files"}
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to read file: %v", err)}
+	}
+
+	text := strings.TrimRight(string(content), "\r\n \t")
+	if text != "" {
+		text += "\n\n"
+	}
+	if err := os.WriteFile(filePath, []byte(text+block), 0644); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to write file: %v", err)}
+	}
+	relPath, _ := filepath.Rel(a.storagePath, filePath)
+	return FileSystemResponse{Success: true, Data: FileItem{ID: relPath, Name: filepath.Base(filePath), Type: "file", Path: filePath}}
+}
+
+// ========== History Diff ==========
+
+// HTTPHistoryDiff compares two entries. JSON bodies are compared value by
+// value as Changes; other bodies line by line as BodyLines.
+type HTTPHistoryDiff struct {
+	Left      HTTPHistorySummary  `json:"left"`
+	Right     HTTPHistorySummary  `json:"right"`
+	Changes   []HTTPHistoryChange `json:"changes"`
+	BodyLines []HTTPDiffLine      `json:"bodyLines,omitempty"`
+	BodyEqual bool                `json:"bodyEqual"`
+}
+
+// HTTPHistoryChange is one difference. Part is "request", "status",
+// "header" or "body"; Kind is "added", "removed" or "changed".
+type HTTPHistoryChange struct {
+	Part  string `json:"part"`
+	Path  string `json:"path"`
+	Kind  string `json:"kind"`
+	Left  string `json:"left,omitempty"`
+	Right string `json:"right,omitempty"`
+}
+
+// HTTPDiffLine is a line of a text diff; Op is " ", "-" or "+"
+type HTTPDiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffHTTPHistoryEntries compares the responses of two entries
+func (a *App) DiffHTTPHistoryEntries(leftID string, rightID string) FileSystemResponse {
+	left, err := a.loadHistoryEntry(leftID)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	right, err := a.loadHistoryEntry(rightID)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: diffHistoryEntries(left, right)}
+}
+
+// diffHistoryEntries compares two entries
+func diffHistoryEntries(left HTTPHistoryEntry, right HTTPHistoryEntry) HTTPHistoryDiff {
+	diff := HTTPHistoryDiff{Left: left.HTTPHistorySummary, Right: right.HTTPHistorySummary, Changes: []HTTPHistoryChange{}}
+	change := func(part, path, l, r string) {
+		kind := "changed"
+		switch {
+		case l == r:
+			return
+		case l == "":
+			kind = "added"
+		case r == "":
+			kind = "removed"
+		}
+		diff.Changes = append(diff.Changes, HTTPHistoryChange{Part: part, Path: path, Kind: kind, Left: l, Right: r})
+	}
+
+	change("request", "method", left.Method, right.Method)
+	change("request", "url", left.URL, right.URL)
+	change("status", "status", left.Response.Status, right.Response.Status)
+	change("status", "error", left.Response.Error, right.Response.Error)
+
+	names := map[string]bool{}
+	for name := range left.Response.Headers {
+		names[name] = true
+	}
+	for name := range right.Response.Headers {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		change("header", name, left.Response.Headers[name], right.Response.Headers[name])
+	}
+
+	leftBody, rightBody := responseText(left.Response), responseText(right.Response)
+	diff.BodyEqual = leftBody == rightBody
+	if diff.BodyEqual {
+		return diff
+	}
+	leftValues, leftJSON := flattenJSON(leftBody)
+	rightValues, rightJSON := flattenJSON(rightBody)
+	if leftJSON && rightJSON {
+		paths := make([]string, 0, len(leftValues)+len(rightValues))
+		for path := range leftValues {
+			paths = append(paths, path)
+		}
+		for path := range rightValues {
+			if _, ok := leftValues[path]; !ok {
+				paths = append(paths, path)
+			}
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			change("body", path, leftValues[path], rightValues[path])
+		}
+		return diff
+	}
+	diff.BodyLines = diffLines(strings.Split(leftBody, "\n"), strings.Split(rightBody, "\n"))
+	return diff
+}
+
+// responseText returns the body of a response, Base64 for binary ones
+func responseText(response HTTPResponse) string {
+	if response.Binary {
+		return response.BodyBase64
+	}
+	return response.Body
+}
+
+// flattenJSON maps the JSONPath of every scalar, empty object and empty
+// array in a JSON document to its compact JSON text
+func flattenJSON(text string) (map[string]string, bool) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return nil, false
+	}
+
+	values := make(map[string]string)
+	var walk func(path string, value interface{})
+	walk = func(path string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if len(v) == 0 {
+				values[path] = "{}"
+			}
+			for key, child := range v {
+				if jsonKeyPattern.MatchString(key) {
+					walk(path+"."+key, child)
+				} else {
+					quoted, _ := json.Marshal(key)
+					walk(path+"["+string(quoted)+"]", child)
+				}
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				values[path] = "[]"
+			}
+			for i, child := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), child)
+			}
+		default:
+			data, _ := json.Marshal(v)
+			values[path] = string(data)
+		}
+	}
+	walk("$", value)
+	return values, true
+}
+
+// jsonKeyPattern matches object keys that can follow a dot in a path
+var jsonKeyPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// maxDiffCells bounds the table of the line diff; longer texts only have
+// their common start and end matched
+const maxDiffCells = 4 * 1024 * 1024
+
+// diffLines returns a line diff of two texts, from their longest common
+// subsequence
+func diffLines(left []string, right []string) []HTTPDiffLine {
+	// Match the common start and end first, which is most of a typical
+	// response
+	prefix := 0
+	for prefix < len(left) && prefix < len(right) && left[prefix] == right[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(left)-prefix && suffix < len(right)-prefix && left[len(left)-1-suffix] == right[len(right)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]HTTPDiffLine, 0, len(left)+len(right))
+	for _, line := range left[:prefix] {
+		lines = append(lines, HTTPDiffLine{Op: " ", Text: line})
+	}
+	l, r := left[prefix:len(left)-suffix], right[prefix:len(right)-suffix]
+	if (len(l)+1)*(len(r)+1) > maxDiffCells {
+		for _, line := range l {
+			lines = append(lines, HTTPDiffLine{Op: "-", Text: line})
+		}
+		for _, line := range r {
+			lines = append(lines, HTTPDiffLine{Op: "+", Text: line})
+		}
+	} else {
+		// common[i][j] is the length of the longest common subsequence of
+		// l[i:] and r[j:]
+		common := make([][]int32, len(l)+1)
+		for i := range common {
+			common[i] = make([]int32, len(r)+1)
+		}
+		for i := len(l) - 1; i >= 0; i-- {
+			for j := len(r) - 1; j >= 0; j-- {
+				if l[i] == r[j] {
+					common[i][j] = common[i+1][j+1] + 1
+				} else {
+					common[i][j] = max(common[i+1][j], common[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(l) || j < len(r) {
+			switch {
+			case i < len(l) && j < len(r) && l[i] == r[j]:
+				lines = append(lines, HTTPDiffLine{Op: " ", Text: l[i]})
+				i++
+				j++
+			case i < len(l) && (j == len(r) || common[i+1][j] >= common[i][j+1]):
+				lines = append(lines, HTTPDiffLine{Op: "-", Text: l[i]})
+				i++
+			default:
+				lines = append(lines, HTTPDiffLine{Op: "+", Text: r[j]})
+				j++
+			}
+		}
+	}
+	for _, line := range left[len(left)-suffix:] {
+		lines = append(lines, HTTPDiffLine{Op: " ", Text: line})
+	}
+	return lines
+}
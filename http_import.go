@@ -0,0 +1,1294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ========== Collection Import ==========
+
+// HTTPImportResult describes what an import created
+type HTTPImportResult struct {
+	// Format is "har", "postman", "postman-environment" or "insomnia"
+	Format string     `json:"format"`
+	Files  []FileItem `json:"files"`
+	// Environments lists the environments created or updated
+	Environments []string `json:"environments"`
+	Requests     int      `json:"requests"`
+	// Warnings lists what was skipped or only partly imported
+	Warnings []string `json:"warnings"`
+}
+
+// importedFolder is a folder of an imported collection. A folder without
+// subfolders or files becomes one .http file; otherwise it becomes a
+// directory holding a .http file for its own requests, its files and its
+// subfolders.
+type importedFolder struct {
+	Name string
+	// Variables are written as file variables into this folder's files and
+	// those below it, overriding the environment as in the source tools
+	Variables []HTTPVariable
+	Requests  []HTTPFileRequest
+	Folders   []*importedFolder
+}
+
+// collectionImport collects what a parser read from an export
+type collectionImport struct {
+	format       string
+	folders      []*importedFolder
+	environments []HTTPEnvironment
+	warnings     []string
+	warned       map[string]bool
+}
+
+// warn records a warning once
+func (c *collectionImport) warn(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if c.warned == nil {
+		c.warned = make(map[string]bool)
+	}
+	if !c.warned[message] {
+		c.warned[message] = true
+		c.warnings = append(c.warnings, message)
+	}
+}
+
+// looseString decodes any JSON value as text, as exports store numbers and
+// booleans where strings are expected
+type looseString string
+
+func (s *looseString) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*s = looseString(text)
+	} else if string(data) == "null" {
+		*s = ""
+	} else {
+		*s = looseString(data)
+	}
+	return nil
+}
+
+// ImportHTTPCollection converts a HAR file, a Postman v2.0/v2.1
+// collection or environment, or an Insomnia export into .http files in
+// parentPath, or in the http folder if parentPath is empty. Environments
+// are merged into the HTTP environments by name.
+func (a *App) ImportHTTPCollection(content string, parentPath string) FileSystemResponse {
+	collection, err := parseCollectionExport([]byte(content))
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	result, err := a.saveCollectionImport(collection, parentPath)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: result}
+}
+
+// ImportHTTPCollectionFile picks an export with the native open dialog and
+// imports it like ImportHTTPCollection. Data is nil if the dialog was
+// cancelled.
+func (a *App) ImportHTTPCollectionFile(parentPath string) FileSystemResponse {
+	file, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Import HAR, Postman or Insomnia File",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "HAR, Postman and Insomnia Files (*.har, *.json)", Pattern: "*.har;*.json"},
+		},
+	})
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	if file == "" {
+		return FileSystemResponse{Success: true}
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to read file: %v", err)}
+	}
+	return a.ImportHTTPCollection(string(content), parentPath)
+}
+
+// parseCollectionExport tells the format of an export from its top-level
+// keys and parses it
+func parseCollectionExport(data []byte) (*collectionImport, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, fmt.Errorf("The file is not JSON: %v", err)
+	}
+
+	switch {
+	case top["log"] != nil:
+		return parseHAR(data)
+	case top["info"] != nil && top["item"] != nil:
+		return parsePostmanCollection(data)
+	case top["_postman_variable_scope"] != nil || (top["values"] != nil && top["name"] != nil):
+		return parsePostmanEnvironment(data)
+	case top["resources"] != nil && top["_type"] != nil:
+		return parseInsomniaExport(data)
+	case top["requests"] != nil && top["order"] != nil:
+		return nil, fmt.Errorf("Postman v1 collections are not supported; export the collection as v2.1")
+	}
+	return nil, fmt.Errorf("Unrecognized file: expected a HAR file, a Postman collection or environment, or an Insomnia export")
+}
+
+// saveCollectionImport writes the folders as .http files and merges the
+// environments
+func (a *App) saveCollectionImport(collection *collectionImport, parentPath string) (HTTPImportResult, error) {
+	result := HTTPImportResult{
+		Format:       collection.format,
+		Files:        []FileItem{},
+		Environments: []string{},
+	}
+	if parentPath == "" {
+		parentPath = filepath.Join(a.storagePath, "http")
+	}
+	dir, err := a.resolveStoragePath(parentPath)
+	if err != nil {
+		return result, err
+	}
+
+	for _, folder := range collection.folders {
+		if err := a.writeImportedFolder(dir, folder, nil, collection, &result); err != nil {
+			return result, err
+		}
+	}
+	if len(collection.environments) > 0 {
+		if err := a.mergeEnvironments(collection.environments); err != nil {
+			return result, fmt.Errorf("Failed to save environments: %v", err)
+		}
+		for _, env := range collection.environments {
+			result.Environments = append(result.Environments, env.Name)
+		}
+	}
+	result.Warnings = append([]string{}, collection.warnings...)
+	return result, nil
+}
+
+// writeImportedFolder writes a folder below dir, passing its variables on
+// to its subfolders
+func (a *App) writeImportedFolder(dir string, folder *importedFolder, inherited []HTTPVariable, collection *collectionImport, result *HTTPImportResult) error {
+	variables := mergeVariables(inherited, folder.Variables)
+	name := safeFileName(folder.Name)
+	if name == "" {
+		name = "collection"
+	}
+
+	if len(folder.Folders) > 0 {
+		dir = uniquePath(filepath.Join(dir, name))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("Failed to create directory: %v", err)
+		}
+	}
+	if len(folder.Requests) > 0 {
+		item, err := a.createHTTPFile(dir, name, formatHTTPFile(fileVariables(variables, collection), folder.Requests))
+		if err != nil {
+			return err
+		}
+		result.Files = append(result.Files, item)
+		result.Requests += len(folder.Requests)
+	}
+	for _, child := range folder.Folders {
+		if err := a.writeImportedFolder(dir, child, variables, collection, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileVariables keeps the variables that can be written as "@name = value"
+func fileVariables(variables []HTTPVariable, collection *collectionImport) []HTTPVariable {
+	kept := make([]HTTPVariable, 0, len(variables))
+	for _, v := range variables {
+		switch {
+		case !fileVariableNamePattern.MatchString(v.Key):
+			collection.warn("Variable %q was skipped: its name cannot be used in a .http file", v.Key)
+		case strings.ContainsAny(v.Value, "\r\n"):
+			collection.warn("Variable %q was skipped: its value spans several lines", v.Key)
+		default:
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// fileVariableNamePattern matches the names fileVariablePattern accepts
+var fileVariableNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// mergeVariables returns base with the variables of override replacing or
+// following those of the same name
+func mergeVariables(base []HTTPVariable, override []HTTPVariable) []HTTPVariable {
+	merged := append([]HTTPVariable{}, base...)
+	for _, v := range override {
+		replaced := false
+		for i := range merged {
+			if merged[i].Key == v.Key {
+				merged[i] = v
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+// hasVariable reports whether a variable is defined
+func hasVariable(variables []HTTPVariable, key string) bool {
+	for _, v := range variables {
+		if v.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeEnvironments adds environments to the store, merging the variables
+// of those that already exist. Empty imported values keep existing ones,
+// so importing again leaves filled-in credentials alone.
+func (a *App) mergeEnvironments(environments []HTTPEnvironment) error {
+	a.envMu.Lock()
+	defer a.envMu.Unlock()
+
+	store, err := a.loadEnvironments()
+	if err != nil {
+		return err
+	}
+	for _, env := range environments {
+		if existing := findEnvironment(store, env.Name); existing != nil {
+			variables := make([]HTTPVariable, 0, len(env.Variables))
+			for _, v := range env.Variables {
+				if v.Value != "" || !hasVariable(existing.Variables, v.Key) {
+					variables = append(variables, v)
+				}
+			}
+			existing.Variables = mergeVariables(existing.Variables, variables)
+		} else {
+			store.Environments = append(store.Environments, env)
+		}
+	}
+	return a.saveEnvironments(store)
+}
+
+// appendQueryParam adds name=value to a URL that may hold {{variables}},
+// which are left unescaped
+func appendQueryParam(rawURL string, name string, value string) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + escapeTemplate(name) + "=" + escapeTemplate(value)
+}
+
+// escapeTemplate query-escapes the text around {{variables}}
+func escapeTemplate(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range variablePattern.FindAllStringIndex(s, -1) {
+		b.WriteString(url.QueryEscape(s[last:m[0]]))
+		b.WriteString(s[m[0]:m[1]])
+		last = m[1]
+	}
+	b.WriteString(url.QueryEscape(s[last:]))
+	return b.String()
+}
+
+// rawContentTypes maps the languages of raw bodies to their content type
+var rawContentTypes = map[string]string{
+	"json": "application/json", "xml": "application/xml", "html": "text/html",
+	"text": "text/plain", "javascript": "application/javascript", "graphql": "application/json",
+}
+
+// setDefaultHeader sets a header unless the request already has it
+func setDefaultHeader(request *HTTPRequest, name string, value string) {
+	if _, ok := lookupHeader(request.Headers, name); ok || value == "" {
+		return
+	}
+	if request.Headers == nil {
+		request.Headers = make(map[string]string)
+	}
+	request.Headers[name] = value
+}
+
+// ========== Postman ==========
+
+// postmanCollection is a Postman v2.0 or v2.1 collection
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+	Auth     json.RawMessage   `json:"auth"`
+	Event    []postmanEvent    `json:"event"`
+}
+
+// postmanItem is a request, or a folder when Item is set
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item"`
+	Request  json.RawMessage   `json:"request"`
+	Variable []postmanKeyValue `json:"variable"`
+	Auth     json.RawMessage   `json:"auth"`
+	Event    []postmanEvent    `json:"event"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	URL    json.RawMessage   `json:"url"`
+	Header []postmanKeyValue `json:"header"`
+	Body   *postmanBody      `json:"body"`
+	Auth   json.RawMessage   `json:"auth"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// postmanKeyValue is a header, parameter, form field or variable
+type postmanKeyValue struct {
+	Key         looseString     `json:"key"`
+	Value       looseString     `json:"value"`
+	Disabled    bool            `json:"disabled"`
+	Enabled     *bool           `json:"enabled"` // environments use enabled
+	Type        string          `json:"type"`
+	Src         json.RawMessage `json:"src"`
+	ContentType string          `json:"contentType"`
+}
+
+// off reports whether the entry is disabled
+func (kv postmanKeyValue) off() bool {
+	return kv.Disabled || (kv.Enabled != nil && !*kv.Enabled)
+}
+
+type postmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw"`
+	URLEncoded []postmanKeyValue `json:"urlencoded"`
+	FormData   []postmanKeyValue `json:"formdata"`
+	File       *struct {
+		Src string `json:"src"`
+	} `json:"file"`
+	GraphQL *struct {
+		Query     string          `json:"query"`
+		Variables json.RawMessage `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+	Disabled bool `json:"disabled"`
+}
+
+type postmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec scriptLines `json:"exec"`
+	} `json:"script"`
+}
+
+// scriptLines decodes a script given as an array of lines or as one string
+type scriptLines []string
+
+func (s *scriptLines) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*s = lines
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*s = strings.Split(text, "\n")
+	return nil
+}
+
+// postmanDynamicVariables maps the dynamic variables Postman and this tool
+// share to their names here
+var postmanDynamicVariables = map[string]string{
+	"$guid": "$guid", "$randomUUID": "$uuid", "$timestamp": "$timestamp",
+	"$isoTimestamp": "$isoTimestamp", "$randomInt": "$randomInt",
+}
+
+// postmanPathVariablePattern matches :name path variables
+var postmanPathVariablePattern = regexp.MustCompile(`/:([A-Za-z_][\w\-]*)`)
+
+// parsePostmanCollection reads a collection into one folder named after
+// it. Collection variables and the literal values set by collection and
+// folder pre-request scripts become file variables, except secret ones,
+// which go into an environment named after the collection; captures and
+// status checks are taken from request test scripts. Variables that
+// request scripts set, and dynamic values, are only reported, as a .http
+// file has no per-request scope to hold them.
+func parsePostmanCollection(data []byte) (*collectionImport, error) {
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("Invalid Postman collection: %v", err)
+	}
+	c := &collectionImport{format: "postman"}
+
+	root := &importedFolder{Name: collection.Info.Name}
+	if root.Name == "" {
+		root.Name = "Postman collection"
+	}
+	root.Variables = c.postmanVariables(collection.Variable)
+	auth := c.postmanAuth(collection.Auth, nil)
+	c.postmanFolderEvents(root, collection.Event, "the collection")
+	for _, item := range collection.Item {
+		c.postmanItem(root, item, auth)
+	}
+	c.folders = []*importedFolder{root}
+
+	secrets := HTTPEnvironment{Name: root.Name}
+	moveSecretVariables(root, &secrets)
+	if len(secrets.Variables) > 0 {
+		c.environments = append(c.environments, secrets)
+		c.warn("Secret variables were saved to the %q environment rather than the .http files; select it to send the requests", secrets.Name)
+	}
+	return c, nil
+}
+
+// moveSecretVariables takes the secret variables out of a folder and its
+// subfolders and adds them to env, so they are not written in plain text
+func moveSecretVariables(folder *importedFolder, env *HTTPEnvironment) {
+	kept := make([]HTTPVariable, 0, len(folder.Variables))
+	for _, v := range folder.Variables {
+		if v.Secret {
+			env.Variables = mergeVariables(env.Variables, []HTTPVariable{v})
+		} else {
+			kept = append(kept, v)
+		}
+	}
+	folder.Variables = kept
+	for _, child := range folder.Folders {
+		moveSecretVariables(child, env)
+	}
+}
+
+// postmanVariables converts enabled variables
+func (c *collectionImport) postmanVariables(values []postmanKeyValue) []HTTPVariable {
+	variables := []HTTPVariable{}
+	for _, v := range values {
+		if v.off() || v.Key == "" {
+			continue
+		}
+		variables = append(variables, HTTPVariable{
+			Key:    string(v.Key),
+			Value:  c.postmanText(string(v.Value)),
+			Secret: v.Type == "secret",
+		})
+	}
+	return variables
+}
+
+// postmanItem adds a request or folder to folder. Auth is the one
+// inherited from the enclosing folders.
+func (c *collectionImport) postmanItem(folder *importedFolder, item postmanItem, auth json.RawMessage) {
+	auth = c.postmanAuth(item.Auth, auth)
+	if item.Item != nil || item.Request == nil {
+		child := &importedFolder{Name: item.Name, Variables: c.postmanVariables(item.Variable)}
+		c.postmanFolderEvents(child, item.Event, fmt.Sprintf("folder %q", item.Name))
+		for _, sub := range item.Item {
+			c.postmanItem(child, sub, auth)
+		}
+		folder.Folders = append(folder.Folders, child)
+		return
+	}
+
+	var raw postmanRequest
+	var rawURL string
+	if json.Unmarshal(item.Request, &rawURL) == nil {
+		raw = postmanRequest{Method: "GET", URL: item.Request}
+	} else if err := json.Unmarshal(item.Request, &raw); err != nil {
+		c.warn("Request %q was skipped: %v", item.Name, err)
+		return
+	}
+	raw.Auth = c.postmanAuth(raw.Auth, auth)
+
+	request := HTTPRequest{Method: strings.ToUpper(raw.Method), URL: c.postmanURL(raw.URL), Headers: map[string]string{}}
+	if request.Method == "" {
+		request.Method = "GET"
+	}
+	for _, header := range raw.Header {
+		if !header.off() && header.Key != "" {
+			request.Headers[string(header.Key)] = c.postmanText(string(header.Value))
+		}
+	}
+	fileRequest := HTTPFileRequest{Name: item.Name}
+	c.postmanBody(&fileRequest, &request, raw.Body, item.Name)
+	c.applyPostmanAuth(&request, raw.Auth, item.Name)
+
+	for _, event := range item.Event {
+		script := c.convertPostmanScript(event.Script.Exec, event.Listen == "test")
+		c.warnScriptVariables(script.variables, fmt.Sprintf("the %s script of request %q", event.Listen, item.Name))
+		request.Captures = append(request.Captures, script.captures...)
+		request.Assertions = append(request.Assertions, script.assertions...)
+		if script.skipped > 0 {
+			c.warn("Request %q: %d line(s) of its %s script were not converted", item.Name, script.skipped, event.Listen)
+		}
+	}
+
+	fileRequest.Request = request
+	folder.Requests = append(folder.Requests, fileRequest)
+}
+
+// postmanFolderEvents turns the literal values set by a folder's
+// pre-request script into variables. Dynamic values would have to be set
+// again before every request, and folder test scripts run after every
+// request, so those are only reported.
+func (c *collectionImport) postmanFolderEvents(folder *importedFolder, events []postmanEvent, owner string) {
+	for _, event := range events {
+		if event.Listen != "prerequest" {
+			if len(postmanScriptCode(event.Script.Exec)) > 0 {
+				c.warn("The %s script of %s was not converted", event.Listen, owner)
+			}
+			continue
+		}
+		script := c.convertPostmanScript(event.Script.Exec, false)
+		var literal, dynamic []HTTPVariable
+		for _, v := range script.variables {
+			if strings.Contains(v.Value, "{{$") {
+				dynamic = append(dynamic, v)
+			} else {
+				literal = append(literal, v)
+			}
+		}
+		folder.Variables = mergeVariables(folder.Variables, literal)
+		c.warnScriptVariables(dynamic, "the pre-request script of "+owner)
+		if script.skipped > 0 {
+			c.warn("%d line(s) of the pre-request script of %s were not converted", script.skipped, owner)
+		}
+	}
+}
+
+// warnScriptVariables reports the variables a script sets. Scripts run
+// for each request, so their values cannot become file variables shared by
+// every request in the file.
+func (c *collectionImport) warnScriptVariables(variables []HTTPVariable, owner string) {
+	if len(variables) == 0 {
+		return
+	}
+	names := make([]string, len(variables))
+	for i, v := range variables {
+		names[i] = v.Key
+	}
+	c.warn("Variables set by %s were not imported (%s); add them to an environment", owner, strings.Join(names, ", "))
+}
+
+// postmanURL reads a URL given as a string or an object, filling in its
+// :name path variables
+func (c *collectionImport) postmanURL(data json.RawMessage) string {
+	var u postmanURL
+	if json.Unmarshal(data, &u.Raw) != nil {
+		json.Unmarshal(data, &u)
+	}
+
+	values := map[string]string{}
+	for _, v := range u.Variable {
+		values[string(v.Key)] = string(v.Value)
+	}
+	raw := postmanPathVariablePattern.ReplaceAllStringFunc(u.Raw, func(m string) string {
+		name := m[2:]
+		if value := values[name]; value != "" {
+			return "/" + value
+		}
+		return "/{{" + name + "}}"
+	})
+	return c.postmanText(raw)
+}
+
+// postmanText converts the dynamic variables in s
+func (c *collectionImport) postmanText(s string) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := strings.TrimSpace(m[2 : len(m)-2])
+		if !strings.HasPrefix(name, "$") {
+			return m
+		}
+		if ours, ok := postmanDynamicVariables[name]; ok {
+			return "{{" + ours + "}}"
+		}
+		c.warn("Postman dynamic variable {{%s}} is not supported", name)
+		return m
+	})
+}
+
+// postmanBody converts the body of a request
+func (c *collectionImport) postmanBody(fileRequest *HTTPFileRequest, request *HTTPRequest, body *postmanBody, name string) {
+	if body == nil || body.Disabled {
+		return
+	}
+
+	switch body.Mode {
+	case "raw":
+		request.Body = c.postmanText(body.Raw)
+		setDefaultHeader(request, "Content-Type", rawContentTypes[body.Options.Raw.Language])
+	case "urlencoded":
+		request.StructuredBody = &HTTPBody{Type: "form"}
+		for _, field := range body.URLEncoded {
+			if !field.off() {
+				request.StructuredBody.Fields = append(request.StructuredBody.Fields, HTTPBodyField{
+					Name:  string(field.Key),
+					Value: c.postmanText(string(field.Value)),
+				})
+			}
+		}
+	case "formdata":
+		request.StructuredBody = &HTTPBody{Type: "multipart"}
+		for _, field := range body.FormData {
+			if field.off() {
+				continue
+			}
+			part := HTTPBodyField{Name: string(field.Key), ContentType: field.ContentType}
+			if field.Type == "file" {
+				part.File = postmanFileSource(field.Src)
+				if part.File == "" {
+					c.warn("Request %q: form field %q has no file", name, field.Key)
+					continue
+				}
+				c.warn("Request %q: uploads %s, a path on the machine it was exported from", name, part.File)
+			} else {
+				part.Value = c.postmanText(string(field.Value))
+			}
+			request.StructuredBody.Fields = append(request.StructuredBody.Fields, part)
+		}
+	case "file":
+		if body.File != nil && body.File.Src != "" {
+			fileRequest.BodyFile = body.File.Src
+			c.warn("Request %q: sends %s, a path on the machine it was exported from", name, body.File.Src)
+		}
+	case "graphql":
+		if body.GraphQL == nil {
+			return
+		}
+		payload := map[string]interface{}{"query": body.GraphQL.Query}
+		var variables string
+		if json.Unmarshal(body.GraphQL.Variables, &variables) != nil {
+			variables = string(body.GraphQL.Variables)
+		}
+		if strings.TrimSpace(variables) != "" {
+			if json.Valid([]byte(variables)) {
+				payload["variables"] = json.RawMessage(variables)
+			} else {
+				c.warn("Request %q: its GraphQL variables are not valid JSON and were left out", name)
+			}
+		}
+		data, _ := json.MarshalIndent(payload, "", "  ")
+		request.Body = c.postmanText(string(data))
+		setDefaultHeader(request, "Content-Type", "application/json")
+	}
+}
+
+// postmanFileSource reads a form field's file, given as a path or a list
+// of paths
+func postmanFileSource(src json.RawMessage) string {
+	var path string
+	if json.Unmarshal(src, &path) == nil {
+		return path
+	}
+	var paths []string
+	if json.Unmarshal(src, &paths) == nil && len(paths) > 0 {
+		return paths[0]
+	}
+	return ""
+}
+
+// postmanAuth returns the auth that applies given the inherited one: the
+// own auth unless it is missing or "inherit"
+func (c *collectionImport) postmanAuth(own json.RawMessage, inherited json.RawMessage) json.RawMessage {
+	var auth struct {
+		Type string `json:"type"`
+	}
+	if len(own) == 0 || string(own) == "null" || json.Unmarshal(own, &auth) != nil || auth.Type == "" || auth.Type == "inherit" {
+		return inherited
+	}
+	return own
+}
+
+// postmanAuthParams reads the parameters of an auth type, a list of
+// key/value pairs in v2.1 and an object in v2.0
+func postmanAuthParams(data json.RawMessage) map[string]string {
+	params := map[string]string{}
+	var list []postmanKeyValue
+	if json.Unmarshal(data, &list) == nil {
+		for _, kv := range list {
+			params[string(kv.Key)] = string(kv.Value)
+		}
+		return params
+	}
+	var object map[string]looseString
+	if json.Unmarshal(data, &object) == nil {
+		for key, value := range object {
+			params[key] = string(value)
+		}
+	}
+	return params
+}
+
+// postmanGrantTypes maps Postman's OAuth 2.0 grant types to the ones here
+var postmanGrantTypes = map[string]string{
+	"authorization_code": "authorization_code", "authorization_code_with_pkce": "authorization_code",
+	"client_credentials": "client_credentials", "password_credentials": "password",
+}
+
+// applyPostmanAuth sets a request's auth, or for API keys the header or
+// query parameter carrying the key
+func (c *collectionImport) applyPostmanAuth(request *HTTPRequest, data json.RawMessage, name string) {
+	if len(data) == 0 {
+		return
+	}
+	var typed map[string]json.RawMessage
+	if json.Unmarshal(data, &typed) != nil {
+		return
+	}
+	var authType string
+	json.Unmarshal(typed["type"], &authType)
+	p := postmanAuthParams(typed[authType])
+	for key, value := range p {
+		p[key] = c.postmanText(value)
+	}
+
+	switch authType {
+	case "noauth":
+	case "basic":
+		request.Auth = &HTTPAuth{Type: "basic", Username: p["username"], Password: p["password"]}
+	case "bearer":
+		request.Auth = &HTTPAuth{Type: "bearer", Token: p["token"]}
+	case "digest":
+		request.Auth = &HTTPAuth{Type: "digest", Username: p["username"], Password: p["password"]}
+	case "awsv4":
+		request.Auth = &HTTPAuth{Type: "aws", AccessKey: p["accessKey"], SecretKey: p["secretKey"],
+			SessionToken: p["sessionToken"], Region: p["region"], Service: p["service"]}
+	case "oauth1":
+		request.Auth = &HTTPAuth{Type: "oauth1", ConsumerKey: p["consumerKey"], ConsumerSecret: p["consumerSecret"],
+			Token: p["token"], TokenSecret: p["tokenSecret"], SignatureMethod: p["signatureMethod"],
+			Realm: p["realm"], Callback: p["callback"], Verifier: p["verifier"]}
+		if p["addParamsToHeader"] == "false" {
+			request.Auth.Placement = "query"
+		}
+	case "oauth2":
+		grant, ok := postmanGrantTypes[p["grant_type"]]
+		if p["grant_type"] == "" {
+			grant, ok = "authorization_code", p["accessTokenUrl"] != ""
+		}
+		if !ok {
+			if p["accessToken"] != "" {
+				request.Auth = &HTTPAuth{Type: "bearer", Token: p["accessToken"]}
+			}
+			c.warn("Request %q: OAuth 2.0 grant type %q is not supported; its saved token was kept", name, p["grant_type"])
+			return
+		}
+		request.Auth = &HTTPAuth{Type: "oauth2", GrantType: grant, TokenURL: p["accessTokenUrl"],
+			AuthURL: p["authUrl"], ClientID: p["clientId"], ClientSecret: p["clientSecret"],
+			Scope: p["scope"], Username: p["username"], Password: p["password"],
+			RedirectURI: p["redirect_uri"], Audience: p["audience"]}
+		if p["client_authentication"] == "body" {
+			request.Auth.ClientAuth = "body"
+		}
+	case "apikey":
+		key := p["key"]
+		if key == "" {
+			return
+		}
+		if p["in"] == "query" {
+			request.URL = appendQueryParam(request.URL, key, p["value"])
+		} else {
+			setDefaultHeader(request, key, p["value"])
+		}
+	default:
+		c.warn("Request %q: %s auth is not supported", name, authType)
+	}
+}
+
+// convertedScript is what could be taken from a Postman script
+type convertedScript struct {
+	variables  []HTTPVariable
+	captures   []HTTPCapture
+	assertions []HTTPAssertion
+	skipped    int // lines of code that were not converted
+}
+
+// Postman script statements that have an equivalent here
+var (
+	// postmanSetPattern matches pm.environment.set("name", value) and
+	// the older postman.setEnvironmentVariable("name", value)
+	postmanSetPattern = regexp.MustCompile(`^(?:pm\.(?:environment|collectionVariables|globals|variables)\.set|postman\.set(?:Environment|Global)Variable)\(\s*["']([^"']+)["']\s*,\s*(.+?)\s*\)\s*;?$`)
+	postmanGetPattern = regexp.MustCompile(`^pm\.(?:environment|collectionVariables|globals|variables)\.get\(\s*["']([^"']+)["']\s*\)$`)
+	// postmanStatusPattern matches the usual status code checks
+	postmanStatusPattern = regexp.MustCompile(`^(?:pm\.response\.to\.(?:have|be)\.status|pm\.expect\(\s*pm\.response\.code\s*\)\.to\.(?:eql|equal|be\.equal))\(\s*(\d{3})\s*\)\s*;?$`)
+	postmanHeaderPattern = regexp.MustCompile(`^pm\.response\.to\.have\.header\(\s*["']([^"']+)["']\s*\)\s*;?$`)
+	// postmanJSONVarPattern matches "var data = pm.response.json();"
+	postmanJSONVarPattern   = regexp.MustCompile(`^(?:var|let|const)\s+([A-Za-z_$][\w$]*)\s*=\s*(?:pm\.response\.json\(\)|JSON\.parse\(\s*responseBody\s*\))\s*;?$`)
+	postmanJSONPathPattern  = regexp.MustCompile(`^((?:\.[A-Za-z_$][\w$]*|\[\d+\]|\[(?:"[^"]*"|'[^']*')\])*)$`)
+	postmanHeaderGetPattern = regexp.MustCompile(`^pm\.response\.headers\.get\(\s*["']([^"']+)["']\s*\)$`)
+	postmanReplaceInPattern = regexp.MustCompile(`^pm\.variables\.replaceIn\(\s*(["'].*["'])\s*\)$`)
+	// postmanWrapperPattern matches the lines around pm.test blocks
+	postmanWrapperPattern = regexp.MustCompile(`^(?:pm\.test\(.*(?:function\s*\(\s*\)|\(\s*\)\s*=>)\s*\{|\}\s*\)?\s*;?)$`)
+)
+
+// postmanScriptCode returns the lines of a script that hold code
+func postmanScriptCode(lines []string) []string {
+	var code []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "//") {
+			code = append(code, line)
+		}
+	}
+	return code
+}
+
+// convertPostmanScript takes variables from the literal values a script
+// sets and, for test scripts, captures from the response values it sets
+// and assertions from its status and header checks
+func (c *collectionImport) convertPostmanScript(lines []string, test bool) convertedScript {
+	var script convertedScript
+	jsonVars := map[string]bool{}
+	for _, line := range postmanScriptCode(lines) {
+		if postmanWrapperPattern.MatchString(line) {
+			continue
+		}
+		if test {
+			if m := postmanJSONVarPattern.FindStringSubmatch(line); m != nil {
+				jsonVars[m[1]] = true
+				continue
+			}
+			if m := postmanStatusPattern.FindStringSubmatch(line); m != nil {
+				script.assertions = append(script.assertions, HTTPAssertion{Type: "status", Operator: "==", Expected: m[1]})
+				continue
+			}
+			if m := postmanHeaderPattern.FindStringSubmatch(line); m != nil {
+				script.assertions = append(script.assertions, HTTPAssertion{Type: "header", Target: m[1], Operator: "exists"})
+				continue
+			}
+		}
+
+		m := postmanSetPattern.FindStringSubmatch(line)
+		if m == nil {
+			script.skipped++
+			continue
+		}
+		if test {
+			if capture, ok := postmanCapture(m[1], m[2], jsonVars); ok {
+				script.captures = append(script.captures, capture)
+				continue
+			}
+		}
+		if value, ok := c.postmanScriptValue(m[2]); ok {
+			script.variables = append(script.variables, HTTPVariable{Key: m[1], Value: value})
+		} else {
+			script.skipped++
+		}
+	}
+	return script
+}
+
+// postmanCapture converts a value read from the response
+func postmanCapture(name string, expr string, jsonVars map[string]bool) (HTTPCapture, bool) {
+	if expr == "pm.response.code" || expr == "responseCode.code" {
+		return HTTPCapture{Name: name, Source: "status"}, true
+	}
+	if m := postmanHeaderGetPattern.FindStringSubmatch(expr); m != nil {
+		return HTTPCapture{Name: name, Source: "header", Expression: m[1]}, true
+	}
+
+	path := ""
+	if strings.HasPrefix(expr, "pm.response.json()") {
+		path = strings.TrimPrefix(expr, "pm.response.json()")
+	} else if i := strings.IndexAny(expr, ".["); i > 0 && jsonVars[expr[:i]] {
+		path = expr[i:]
+	} else if !jsonVars[expr] {
+		return HTTPCapture{}, false
+	}
+	if !postmanJSONPathPattern.MatchString(path) {
+		return HTTPCapture{}, false
+	}
+	return HTTPCapture{Name: name, Source: "body", Expression: "$" + path}, true
+}
+
+// postmanScriptValue converts a value a script sets: literals, variable
+// reads and the common uuid and time expressions
+func (c *collectionImport) postmanScriptValue(expr string) (string, bool) {
+	if m := postmanReplaceInPattern.FindStringSubmatch(expr); m != nil {
+		expr = m[1]
+	}
+	if m := postmanGetPattern.FindStringSubmatch(expr); m != nil {
+		return "{{" + m[1] + "}}", true
+	}
+
+	switch expr {
+	case "new Date().toISOString()", "(new Date()).toISOString()":
+		return "{{$isoTimestamp}}", true
+	case "Math.floor(Date.now() / 1000)", "Math.round(Date.now() / 1000)":
+		return "{{$timestamp}}", true
+	case "require('uuid').v4()", `require("uuid").v4()`, "uuid.v4()", "crypto.randomUUID()":
+		return "{{$uuid}}", true
+	case "true", "false":
+		return expr, true
+	}
+	if _, err := strconv.ParseFloat(expr, 64); err == nil {
+		return expr, true
+	}
+	if len(expr) >= 2 && (expr[0] == '"' || expr[0] == '\'') && expr[len(expr)-1] == expr[0] {
+		text := expr[1 : len(expr)-1]
+		if expr[0] == '\'' {
+			text = `"` + strings.ReplaceAll(strings.ReplaceAll(text, `\'`, `'`), `"`, `\"`) + `"`
+		} else {
+			text = expr
+		}
+		if value, err := strconv.Unquote(text); err == nil {
+			return c.postmanText(value), true
+		}
+	}
+	return "", false
+}
+
+// postmanEnvironment is a Postman environment or globals export
+type postmanEnvironment struct {
+	Name   string            `json:"name"`
+	Scope  string            `json:"_postman_variable_scope"`
+	Values []postmanKeyValue `json:"values"`
+}
+
+// parsePostmanEnvironment reads an environment export
+func parsePostmanEnvironment(data []byte) (*collectionImport, error) {
+	var env postmanEnvironment
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("Invalid Postman environment: %v", err)
+	}
+	c := &collectionImport{format: "postman-environment"}
+	name := strings.TrimSpace(env.Name)
+	if name == "" {
+		name = "Postman " + env.Scope
+	}
+	c.environments = []HTTPEnvironment{{Name: name, Variables: c.postmanVariables(env.Values)}}
+	return c, nil
+}
+
+// ========== Insomnia ==========
+
+// insomniaExport is an Insomnia v4 export
+type insomniaExport struct {
+	Type      string             `json:"_type"`
+	Format    int                `json:"__export_format"`
+	Resources []insomniaResource `json:"resources"`
+}
+
+// insomniaResource is a workspace, folder (request_group), request or
+// environment
+type insomniaResource struct {
+	ID          string  `json:"_id"`
+	Type        string  `json:"_type"`
+	ParentID    string  `json:"parentId"`
+	Name        string  `json:"name"`
+	MetaSortKey float64 `json:"metaSortKey"`
+
+	Method         string                 `json:"method"`
+	URL            string                 `json:"url"`
+	Body           insomniaBody           `json:"body"`
+	Headers        []insomniaParam        `json:"headers"`
+	Parameters     []insomniaParam        `json:"parameters"`
+	Authentication map[string]interface{} `json:"authentication"`
+
+	// Data holds an environment's variables, Environment a folder's
+	Data        map[string]interface{} `json:"data"`
+	Environment map[string]interface{} `json:"environment"`
+}
+
+type insomniaParam struct {
+	Name     string      `json:"name"`
+	Value    looseString `json:"value"`
+	Disabled bool        `json:"disabled"`
+	Type     string      `json:"type"`
+	FileName string      `json:"fileName"`
+}
+
+type insomniaBody struct {
+	MimeType string          `json:"mimeType"`
+	Text     string          `json:"text"`
+	FileName string          `json:"fileName"`
+	Params   []insomniaParam `json:"params"`
+}
+
+// Insomnia template syntax
+var (
+	// insomniaVariablePattern matches {{ _.name }}
+	insomniaVariablePattern = regexp.MustCompile(`\{\{\s*_\.([^{}]+?)\s*\}\}`)
+	// insomniaTagPattern matches {% tag args %}
+	insomniaTagPattern = regexp.MustCompile(`\{%\s*(\w+)([^%]*)%\}`)
+)
+
+// parseInsomniaExport reads every workspace of an export as a folder. The
+// base environment is merged into each sub-environment, which become HTTP
+// environments; folder environments become file variables.
+func parseInsomniaExport(data []byte) (*collectionImport, error) {
+	var export insomniaExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("Invalid Insomnia export: %v", err)
+	}
+	if export.Type != "export" || export.Format < 3 {
+		return nil, fmt.Errorf("Unsupported Insomnia export; export the data as Insomnia v4 JSON")
+	}
+	c := &collectionImport{format: "insomnia"}
+
+	children := map[string][]insomniaResource{}
+	for _, resource := range export.Resources {
+		children[resource.ParentID] = append(children[resource.ParentID], resource)
+	}
+	for _, list := range children {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].MetaSortKey < list[j].MetaSortKey })
+	}
+
+	for _, workspace := range export.Resources {
+		if workspace.Type != "workspace" {
+			continue
+		}
+		root := &importedFolder{Name: workspace.Name}
+		c.insomniaFolder(root, workspace.ID, children, nil)
+		if len(root.Requests) > 0 || len(root.Folders) > 0 {
+			c.folders = append(c.folders, root)
+		}
+		c.insomniaEnvironments(workspace, children)
+	}
+	return c, nil
+}
+
+// insomniaFolder adds the requests and folders below parentID. Auth is the
+// one inherited from the enclosing folders.
+func (c *collectionImport) insomniaFolder(folder *importedFolder, parentID string, children map[string][]insomniaResource, auth map[string]interface{}) {
+	for _, resource := range children[parentID] {
+		switch resource.Type {
+		case "request_group":
+			child := &importedFolder{Name: resource.Name}
+			flattenInsomniaData(c, "", resource.Environment, &child.Variables)
+			childAuth := auth
+			if len(resource.Authentication) > 0 {
+				childAuth = resource.Authentication
+			}
+			c.insomniaFolder(child, resource.ID, children, childAuth)
+			folder.Folders = append(folder.Folders, child)
+		case "request":
+			folder.Requests = append(folder.Requests, c.insomniaRequest(resource, auth))
+		case "grpc_request", "websocket_request":
+			c.warn("%s %q was skipped: only HTTP requests are imported", strings.TrimSuffix(resource.Type, "_request"), resource.Name)
+		}
+	}
+}
+
+// insomniaRequest converts a request
+func (c *collectionImport) insomniaRequest(resource insomniaResource, auth map[string]interface{}) HTTPFileRequest {
+	request := HTTPRequest{
+		Method:  strings.ToUpper(resource.Method),
+		URL:     c.insomniaText(resource.URL),
+		Headers: map[string]string{},
+	}
+	if request.Method == "" {
+		request.Method = "GET"
+	}
+	for _, param := range resource.Parameters {
+		if !param.Disabled && param.Name != "" {
+			request.URL = appendQueryParam(request.URL, c.insomniaText(param.Name), c.insomniaText(string(param.Value)))
+		}
+	}
+	for _, header := range resource.Headers {
+		if !header.Disabled && header.Name != "" {
+			request.Headers[header.Name] = c.insomniaText(string(header.Value))
+		}
+	}
+
+	fileRequest := HTTPFileRequest{Name: resource.Name}
+	body := resource.Body
+	switch {
+	case body.MimeType == "application/x-www-form-urlencoded":
+		request.StructuredBody = &HTTPBody{Type: "form"}
+		for _, param := range body.Params {
+			if !param.Disabled {
+				request.StructuredBody.Fields = append(request.StructuredBody.Fields, HTTPBodyField{
+					Name:  c.insomniaText(param.Name),
+					Value: c.insomniaText(string(param.Value)),
+				})
+			}
+		}
+	case body.MimeType == "multipart/form-data":
+		request.StructuredBody = &HTTPBody{Type: "multipart"}
+		for _, param := range body.Params {
+			if param.Disabled {
+				continue
+			}
+			part := HTTPBodyField{Name: c.insomniaText(param.Name)}
+			if param.Type == "file" {
+				part.File = param.FileName
+				c.warn("Request %q: uploads %s, a path on the machine it was exported from", resource.Name, param.FileName)
+			} else {
+				part.Value = c.insomniaText(string(param.Value))
+			}
+			request.StructuredBody.Fields = append(request.StructuredBody.Fields, part)
+		}
+		// The multipart body sets its own boundary
+		deleteHeader(request.Headers, "Content-Type")
+	case body.FileName != "":
+		fileRequest.BodyFile = body.FileName
+		c.warn("Request %q: sends %s, a path on the machine it was exported from", resource.Name, body.FileName)
+	case body.MimeType == "application/graphql":
+		request.Body = c.insomniaText(body.Text)
+		setDefaultHeader(&request, "Content-Type", "application/json")
+	case body.Text != "":
+		request.Body = c.insomniaText(body.Text)
+		setDefaultHeader(&request, "Content-Type", body.MimeType)
+	}
+
+	if len(resource.Authentication) > 0 {
+		auth = resource.Authentication
+	}
+	c.applyInsomniaAuth(&request, auth, resource.Name)
+	fileRequest.Request = request
+	return fileRequest
+}
+
+// insomniaText converts Insomnia's template syntax
+func (c *collectionImport) insomniaText(s string) string {
+	s = insomniaVariablePattern.ReplaceAllString(s, "{{$1}}")
+	return insomniaTagPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := insomniaTagPattern.FindStringSubmatch(m)
+		args := strings.Trim(strings.TrimSpace(parts[2]), `'"`)
+		switch {
+		case parts[1] == "uuid":
+			return "{{$uuid}}"
+		case parts[1] == "now" && strings.HasPrefix(args, "iso"):
+			return "{{$isoTimestamp}}"
+		case parts[1] == "now" && args == "unix":
+			return "{{$timestamp}}"
+		}
+		c.warn("Insomnia template tag {%% %s %%} is not supported", parts[1])
+		return m
+	})
+}
+
+// applyInsomniaAuth sets a request's auth, or for API keys the header or
+// query parameter carrying the key
+func (c *collectionImport) applyInsomniaAuth(request *HTTPRequest, auth map[string]interface{}, name string) {
+	if len(auth) == 0 || auth["disabled"] == true {
+		return
+	}
+	p := map[string]string{}
+	for key, value := range auth {
+		switch v := value.(type) {
+		case string:
+			p[key] = c.insomniaText(v)
+		case bool:
+			p[key] = strconv.FormatBool(v)
+		}
+	}
+
+	switch p["type"] {
+	case "", "none":
+	case "basic":
+		request.Auth = &HTTPAuth{Type: "basic", Username: p["username"], Password: p["password"]}
+	case "bearer":
+		if prefix := p["prefix"]; prefix != "" && !strings.EqualFold(prefix, "Bearer") {
+			setDefaultHeader(request, "Authorization", prefix+" "+p["token"])
+			return
+		}
+		request.Auth = &HTTPAuth{Type: "bearer", Token: p["token"]}
+	case "digest":
+		request.Auth = &HTTPAuth{Type: "digest", Username: p["username"], Password: p["password"]}
+	case "iam":
+		request.Auth = &HTTPAuth{Type: "aws", AccessKey: p["accessKeyId"], SecretKey: p["secretAccessKey"],
+			SessionToken: p["sessionToken"], Region: p["region"], Service: p["service"]}
+	case "oauth1":
+		request.Auth = &HTTPAuth{Type: "oauth1", ConsumerKey: p["consumerKey"], ConsumerSecret: p["consumerSecret"],
+			Token: p["tokenKey"], TokenSecret: p["tokenSecret"], SignatureMethod: p["signatureMethod"],
+			Realm: p["realm"], Callback: p["callback"], Verifier: p["verifier"]}
+	case "oauth2":
+		grant := p["grantType"]
+		if grant != "authorization_code" && grant != "client_credentials" && grant != "password" && grant != "refresh_token" {
+			c.warn("Request %q: OAuth 2.0 grant type %q is not supported", name, grant)
+			return
+		}
+		request.Auth = &HTTPAuth{Type: "oauth2", GrantType: grant, TokenURL: p["accessTokenUrl"],
+			AuthURL: p["authorizationUrl"], ClientID: p["clientId"], ClientSecret: p["clientSecret"],
+			Scope: p["scope"], Username: p["username"], Password: p["password"],
+			RedirectURI: p["redirectUrl"], Audience: p["audience"], RefreshToken: p["refreshToken"]}
+		if p["credentialsInBody"] == "true" {
+			request.Auth.ClientAuth = "body"
+		}
+	case "apikey":
+		if p["key"] == "" {
+			return
+		}
+		if p["addTo"] == "queryParams" {
+			request.URL = appendQueryParam(request.URL, p["key"], p["value"])
+		} else if p["addTo"] == "cookie" {
+			setDefaultHeader(request, "Cookie", p["key"]+"="+p["value"])
+		} else {
+			setDefaultHeader(request, p["key"], p["value"])
+		}
+	default:
+		c.warn("Request %q: %s auth is not supported", name, p["type"])
+	}
+}
+
+// insomniaEnvironments converts a workspace's environments. Without
+// sub-environments the base environment is named after the workspace.
+func (c *collectionImport) insomniaEnvironments(workspace insomniaResource, children map[string][]insomniaResource) {
+	for _, base := range children[workspace.ID] {
+		if base.Type != "environment" {
+			continue
+		}
+		var baseVariables []HTTPVariable
+		flattenInsomniaData(c, "", base.Data, &baseVariables)
+
+		subs := 0
+		for _, sub := range children[base.ID] {
+			if sub.Type != "environment" {
+				continue
+			}
+			var variables []HTTPVariable
+			flattenInsomniaData(c, "", sub.Data, &variables)
+			c.environments = append(c.environments, HTTPEnvironment{Name: sub.Name, Variables: mergeVariables(baseVariables, variables)})
+			subs++
+		}
+		if subs == 0 && len(baseVariables) > 0 {
+			c.environments = append(c.environments, HTTPEnvironment{Name: workspace.Name, Variables: baseVariables})
+		}
+	}
+}
+
+// flattenInsomniaData turns environment data into variables, naming
+// nested values by their dotted path as {{ _.a.b }} does
+func flattenInsomniaData(c *collectionImport, prefix string, data map[string]interface{}, variables *[]HTTPVariable) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := prefix + key
+		switch v := data[key].(type) {
+		case map[string]interface{}:
+			flattenInsomniaData(c, name+".", v, variables)
+		case string:
+			*variables = append(*variables, HTTPVariable{Key: name, Value: c.insomniaText(v)})
+		case nil:
+			*variables = append(*variables, HTTPVariable{Key: name})
+		default:
+			value, _ := json.Marshal(v)
+			*variables = append(*variables, HTTPVariable{Key: name, Value: string(value)})
+		}
+	}
+}
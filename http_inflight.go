@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ========== In-flight HTTP Requests ==========
+
+// httpStatusEvent carries HTTPRequestStatus updates to the frontend
+const httpStatusEvent = "http:request:status"
+
+// progressInterval throttles "receiving" status events
+const progressInterval = 100 * time.Millisecond
+
+// HTTPRequestStatus describes the state of an in-flight request. State is
+// "started", "headers", "receiving", "finished", "failed" or "cancelled".
+type HTTPRequestStatus struct {
+	ID            string `json:"id"`
+	State         string `json:"state"`
+	Method        string `json:"method"`
+	URL           string `json:"url"`
+	StatusCode    int    `json:"statusCode,omitempty"`
+	BytesReceived int64  `json:"bytesReceived"`
+	Duration      int64  `json:"duration"` // milliseconds since start
+	Error         string `json:"error,omitempty"`
+}
+
+// CancelHTTPRequest aborts an in-flight request. The request returns a
+// response with Cancelled set.
+func (a *App) CancelHTTPRequest(id string) FileSystemResponse {
+	a.inFlightMu.Lock()
+	cancel, ok := a.inFlight[id]
+	a.inFlightMu.Unlock()
+	if !ok {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Request %q is not running", id)}
+	}
+	cancel()
+	return FileSystemResponse{Success: true}
+}
+
+// ListInFlightHTTPRequests returns the IDs of running requests
+func (a *App) ListInFlightHTTPRequests() []string {
+	a.inFlightMu.Lock()
+	defer a.inFlightMu.Unlock()
+
+	ids := make([]string, 0, len(a.inFlight))
+	for id := range a.inFlight {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// beginHTTPRequest registers a request under its ID and returns its
+// context and a function to call when it is done
+func (a *App) beginHTTPRequest(id string) (context.Context, func(), error) {
+	a.inFlightMu.Lock()
+	defer a.inFlightMu.Unlock()
+	if _, ok := a.inFlight[id]; ok {
+		return nil, nil, fmt.Errorf("Request %q is already running", id)
+	}
+	if a.inFlight == nil {
+		a.inFlight = make(map[string]context.CancelFunc)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.inFlight[id] = cancel
+	return ctx, func() {
+		a.inFlightMu.Lock()
+		delete(a.inFlight, id)
+		a.inFlightMu.Unlock()
+		cancel()
+	}, nil
+}
+
+// httpStatusReporter emits status events for one request. As an
+// io.Writer it counts body bytes, so it can sit behind an io.TeeReader.
+type httpStatusReporter struct {
+	app      *App
+	status   HTTPRequestStatus
+	start    time.Time
+	lastSent time.Time
+}
+
+// newHTTPStatusReporter prepares events for a request
+func (a *App) newHTTPStatusReporter(request HTTPRequest) *httpStatusReporter {
+	return &httpStatusReporter{
+		app:    a,
+		status: HTTPRequestStatus{ID: request.ID, Method: request.Method, URL: request.URL},
+		start:  time.Now(),
+	}
+}
+
+// emit sends the current status with the given state
+func (r *httpStatusReporter) emit(state string) {
+	r.status.State = state
+	r.status.Duration = time.Since(r.start).Milliseconds()
+	r.lastSent = time.Now()
+	r.app.emitEvent(httpStatusEvent, r.status)
+}
+
+// headers reports that the response headers arrived
+func (r *httpStatusReporter) headers(statusCode int) {
+	r.status.StatusCode = statusCode
+	r.emit("headers")
+}
+
+// Write counts received bytes and emits throttled progress events
+func (r *httpStatusReporter) Write(p []byte) (int, error) {
+	r.status.BytesReceived += int64(len(p))
+	if time.Since(r.lastSent) >= progressInterval {
+		r.emit("receiving")
+	}
+	return len(p), nil
+}
+
+// finish emits the final state of a response
+func (r *httpStatusReporter) finish(response HTTPResponse) {
+	r.status.Error = response.Error
+	switch {
+	case response.Cancelled:
+		r.emit("cancelled")
+	case response.Error != "":
+		r.emit("failed")
+	default:
+		r.emit("finished")
+	}
+}
@@ -0,0 +1,729 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ========== OAuth 2.0 ==========
+
+const (
+	// oauth2DeviceEvent carries an OAuth2DeviceCode for the user to enter
+	oauth2DeviceEvent = "http:oauth2:device"
+	// oauth2AuthorizeEvent carries the authorization URL opened in the
+	// browser, in case it has to be opened by hand
+	oauth2AuthorizeEvent = "http:oauth2:authorize"
+)
+
+const (
+	// oauth2ExpiryMargin refreshes tokens this long before they expire
+	oauth2ExpiryMargin = 30 * time.Second
+	// oauth2AuthorizeTimeout bounds the wait for the browser to come back
+	// to the loopback listener
+	oauth2AuthorizeTimeout = 5 * time.Minute
+)
+
+// OAuth2Token is a cached access token. A zero ExpiresAt means the server
+// gave no lifetime, so the token is used until it is cleared.
+type OAuth2Token struct {
+	Key          string    `json:"key"`
+	GrantType    string    `json:"grantType"`
+	TokenURL     string    `json:"tokenUrl"`
+	ClientID     string    `json:"clientId,omitempty"`
+	AccessToken  string    `json:"accessToken"`
+	TokenType    string    `json:"tokenType"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	IDToken      string    `json:"idToken,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	Obtained     time.Time `json:"obtained"`
+}
+
+// OAuth2DeviceCode is what the user needs to approve a device code flow
+type OAuth2DeviceCode struct {
+	UserCode                string `json:"userCode"`
+	VerificationURI         string `json:"verificationUri"`
+	VerificationURIComplete string `json:"verificationUriComplete,omitempty"`
+	ExpiresIn               int    `json:"expiresIn"`
+}
+
+// expired reports whether the token is past, or close to, its expiry
+func (t OAuth2Token) expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Add(oauth2ExpiryMargin).Before(t.ExpiresAt)
+}
+
+// authorization returns the Authorization header value of the token
+func (t OAuth2Token) authorization() string {
+	if t.TokenType == "" || strings.EqualFold(t.TokenType, "bearer") {
+		return "Bearer " + t.AccessToken
+	}
+	return t.TokenType + " " + t.AccessToken
+}
+
+// isOAuth2 reports whether auth is an OAuth 2.0 block
+func isOAuth2(auth *HTTPAuth) bool {
+	return auth != nil && strings.EqualFold(auth.Type, "oauth2")
+}
+
+// oauth2GrantType returns the grant of an auth block, client_credentials
+// by default
+func oauth2GrantType(auth HTTPAuth) string {
+	if auth.GrantType == "" {
+		return "client_credentials"
+	}
+	return strings.ToLower(auth.GrantType)
+}
+
+// oauth2CacheKey identifies the tokens an auth block can reuse. Secrets
+// are left out so that changing one keeps the cached token.
+func oauth2CacheKey(auth HTTPAuth) string {
+	parts := []string{oauth2GrantType(auth), auth.TokenURL, auth.AuthURL, auth.ClientID, auth.Scope, auth.Audience, auth.Username}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// oauth2TokensPath returns the token cache file, keyed by environment name
+func (a *App) oauth2TokensPath() string {
+	return filepath.Join(a.storagePath, "http", ".oauth2-tokens.json")
+}
+
+// loadOAuth2Tokens reads the token cache once; callers hold oauth2Mu
+func (a *App) loadOAuth2Tokens() error {
+	if a.oauth2Tokens != nil {
+		return nil
+	}
+
+	a.oauth2Tokens = make(map[string]map[string]OAuth2Token)
+	data, err := os.ReadFile(a.oauth2TokensPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &a.oauth2Tokens); err != nil {
+		return fmt.Errorf("invalid OAuth2 tokens file: %w", err)
+	}
+	return nil
+}
+
+// saveOAuth2Tokens writes the token cache; callers hold oauth2Mu
+func (a *App) saveOAuth2Tokens() error {
+	for env, tokens := range a.oauth2Tokens {
+		if len(tokens) == 0 {
+			delete(a.oauth2Tokens, env)
+		}
+	}
+	data, err := json.MarshalIndent(a.oauth2Tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.oauth2TokensPath()), 0755); err != nil {
+		return err
+	}
+	// Tokens are credentials, keep them private to the user
+	return os.WriteFile(a.oauth2TokensPath(), data, 0600)
+}
+
+// cachedOAuth2Token returns the cached token of an auth block, if any
+func (a *App) cachedOAuth2Token(environment string, auth HTTPAuth) (OAuth2Token, bool, error) {
+	a.oauth2Mu.Lock()
+	defer a.oauth2Mu.Unlock()
+	if err := a.loadOAuth2Tokens(); err != nil {
+		return OAuth2Token{}, false, err
+	}
+	token, ok := a.oauth2Tokens[environment][oauth2CacheKey(auth)]
+	return token, ok, nil
+}
+
+// lockOAuth2Flow waits until no other flow runs for the token an auth
+// block caches in an environment, or until ctx ends. Flows for other
+// environments and providers are not held up. It returns the function
+// that lets the next flow run.
+func (a *App) lockOAuth2Flow(ctx context.Context, environment string, auth HTTPAuth) (func(), error) {
+	key := environment + "\x00" + oauth2CacheKey(auth)
+	a.oauth2Mu.Lock()
+	if a.oauth2Flows == nil {
+		a.oauth2Flows = make(map[string]chan struct{})
+	}
+	slot, ok := a.oauth2Flows[key]
+	if !ok {
+		slot = make(chan struct{}, 1)
+		a.oauth2Flows[key] = slot
+	}
+	a.oauth2Mu.Unlock()
+
+	select {
+	case slot <- struct{}{}:
+		return func() { <-slot }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// storeOAuth2Token caches a token for an environment
+func (a *App) storeOAuth2Token(environment string, token OAuth2Token) error {
+	a.oauth2Mu.Lock()
+	defer a.oauth2Mu.Unlock()
+	if err := a.loadOAuth2Tokens(); err != nil {
+		return err
+	}
+	if a.oauth2Tokens[environment] == nil {
+		a.oauth2Tokens[environment] = make(map[string]OAuth2Token)
+	}
+	a.oauth2Tokens[environment][token.Key] = token
+	return a.saveOAuth2Tokens()
+}
+
+// ListOAuth2Tokens returns the cached tokens of an environment (empty
+// means the active one), most recent first
+func (a *App) ListOAuth2Tokens(environment string) FileSystemResponse {
+	environment = a.cookieEnvironment(environment)
+
+	a.oauth2Mu.Lock()
+	defer a.oauth2Mu.Unlock()
+	if err := a.loadOAuth2Tokens(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load OAuth2 tokens: %v", err)}
+	}
+
+	tokens := []OAuth2Token{}
+	for _, token := range a.oauth2Tokens[environment] {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Obtained.After(tokens[j].Obtained) })
+	return FileSystemResponse{Success: true, Data: tokens}
+}
+
+// ClearOAuth2Tokens drops the cached tokens of an environment, or only the
+// one with the given key if key is set
+func (a *App) ClearOAuth2Tokens(environment string, key string) FileSystemResponse {
+	environment = a.cookieEnvironment(environment)
+
+	a.oauth2Mu.Lock()
+	defer a.oauth2Mu.Unlock()
+	if err := a.loadOAuth2Tokens(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to load OAuth2 tokens: %v", err)}
+	}
+
+	if key == "" {
+		delete(a.oauth2Tokens, environment)
+	} else {
+		delete(a.oauth2Tokens[environment], key)
+	}
+	if err := a.saveOAuth2Tokens(); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save OAuth2 tokens: %v", err)}
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// FetchOAuth2Token runs the flow of an auth block now, even if a token is
+// cached, and caches the new token. Variables resolve in the environment
+// (empty means the active one). The flow can be stopped with
+// CancelHTTPRequest(id); an ID is generated if empty.
+func (a *App) FetchOAuth2Token(environment string, auth HTTPAuth, id string) FileSystemResponse {
+	resolver, err := a.newVariableResolver(environment, "")
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Environment error: %v", err)}
+	}
+	resolved, err := resolver.resolveAuth(&auth)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Variable error: %v", err)}
+	}
+
+	if id == "" {
+		id = uuid.NewString()
+	}
+	ctx, done, err := a.beginHTTPRequest(id)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	defer done()
+
+	settings := a.loadConfig().HTTPSettings
+	transport, err := a.transport(settings)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Invalid transport settings: %v", err)}
+	}
+	flow := &oauth2Flow{app: a, auth: *resolved, client: &http.Client{Transport: transport, Timeout: settings.readTimeout()}}
+
+	unlock, err := a.lockOAuth2Flow(ctx, resolver.environment, *resolved)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("OAuth2 error: %v", err)}
+	}
+	defer unlock()
+	token, err := flow.run(ctx)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("OAuth2 error: %v", err)}
+	}
+	if err := a.storeOAuth2Token(resolver.environment, token); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save OAuth2 tokens: %v", err)}
+	}
+	return FileSystemResponse{Success: true, Data: token}
+}
+
+// oauth2Token returns a usable token for an auth block: the cached one,
+// a refreshed one, or one from a new flow. Flows for the same cached token
+// run one at a time, so concurrent requests share the token the first one
+// fetches.
+func (a *App) oauth2Token(ctx context.Context, environment string, auth HTTPAuth, client *http.Client) (OAuth2Token, error) {
+	unlock, err := a.lockOAuth2Flow(ctx, environment, auth)
+	if err != nil {
+		return OAuth2Token{}, err
+	}
+	defer unlock()
+
+	cached, ok, err := a.cachedOAuth2Token(environment, auth)
+	if err != nil {
+		return OAuth2Token{}, err
+	}
+	if ok && !cached.expired(time.Now()) {
+		return cached, nil
+	}
+
+	flow := &oauth2Flow{app: a, auth: auth, client: client}
+	var token OAuth2Token
+	if ok && cached.RefreshToken != "" {
+		token, err = flow.refresh(ctx, cached.RefreshToken)
+	}
+	// Without a refresh token, or if refreshing failed, start over
+	if !ok || cached.RefreshToken == "" || err != nil {
+		if ctx.Err() != nil {
+			return OAuth2Token{}, ctx.Err()
+		}
+		if token, err = flow.run(ctx); err != nil {
+			return OAuth2Token{}, err
+		}
+	}
+	if err := a.storeOAuth2Token(environment, token); err != nil {
+		return OAuth2Token{}, err
+	}
+	return token, nil
+}
+
+// authorizeOAuth2 sets the Authorization header of an OAuth 2.0 request
+func (a *App) authorizeOAuth2(ctx context.Context, req *http.Request, request HTTPRequest, client *http.Client) error {
+	if !isOAuth2(request.Auth) {
+		return nil
+	}
+	token, err := a.oauth2Token(ctx, request.Environment, *request.Auth, client)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", token.authorization())
+	return nil
+}
+
+// exportOAuth2 replaces an OAuth 2.0 auth block with the cached token, so
+// exported code carries it. No flow is run for an export.
+func (a *App) exportOAuth2(request HTTPRequest) (HTTPRequest, error) {
+	if !isOAuth2(request.Auth) {
+		return request, nil
+	}
+	token, ok, err := a.cachedOAuth2Token(request.Environment, *request.Auth)
+	if err != nil {
+		return request, fmt.Errorf("Failed to load OAuth2 tokens: %v", err)
+	}
+	if !ok {
+		return request, fmt.Errorf("No OAuth2 token yet; send the request or fetch a token first")
+	}
+
+	headers := make(map[string]string, len(request.Headers)+1)
+	for key, value := range request.Headers {
+		headers[key] = value
+	}
+	deleteHeader(headers, "Authorization")
+	headers["Authorization"] = token.authorization()
+	request.Headers = headers
+	request.Auth = nil
+	return request, nil
+}
+
+// openBrowser shows url in the system browser. It is a variable so the
+// flows can run without a window.
+var openBrowser = func(a *App, url string) {
+	if a.ctx == nil || a.ctx.Value("events") == nil {
+		return
+	}
+	runtime.BrowserOpenURL(a.ctx, url)
+}
+
+// oauth2Flow talks to an authorization server for one auth block
+type oauth2Flow struct {
+	app    *App
+	auth   HTTPAuth
+	client *http.Client
+}
+
+// oauth2Error is an error response from an authorization server
+type oauth2Error struct {
+	Code        string
+	Description string
+}
+
+func (e *oauth2Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// run obtains a new token with the auth block's grant
+func (f *oauth2Flow) run(ctx context.Context) (OAuth2Token, error) {
+	if f.auth.TokenURL == "" {
+		return OAuth2Token{}, fmt.Errorf("a token URL is required")
+	}
+
+	switch grant := oauth2GrantType(f.auth); grant {
+	case "client_credentials":
+		return f.token(ctx, url.Values{"grant_type": {grant}}, true)
+	case "password":
+		return f.token(ctx, url.Values{
+			"grant_type": {grant},
+			"username":   {f.auth.Username},
+			"password":   {f.auth.Password},
+		}, true)
+	case "refresh_token":
+		if f.auth.RefreshToken == "" {
+			return OAuth2Token{}, fmt.Errorf("a refresh token is required")
+		}
+		return f.refresh(ctx, f.auth.RefreshToken)
+	case "device_code":
+		return f.deviceCode(ctx)
+	case "authorization_code":
+		return f.authorizationCode(ctx)
+	default:
+		return OAuth2Token{}, fmt.Errorf("unknown grant type %q", f.auth.GrantType)
+	}
+}
+
+// refresh trades a refresh token for a new access token, keeping the
+// refresh token if the server doesn't rotate it
+func (f *oauth2Flow) refresh(ctx context.Context, refreshToken string) (OAuth2Token, error) {
+	token, err := f.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}, true)
+	if err == nil && token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, err
+}
+
+// token posts a grant to the token endpoint. With scoped set, the auth
+// block's scope and audience go along.
+func (f *oauth2Flow) token(ctx context.Context, params url.Values, scoped bool) (OAuth2Token, error) {
+	if scoped {
+		f.addScope(params)
+	}
+	var body struct {
+		AccessToken  string      `json:"access_token"`
+		TokenType    string      `json:"token_type"`
+		ExpiresIn    json.Number `json:"expires_in"`
+		RefreshToken string      `json:"refresh_token"`
+		IDToken      string      `json:"id_token"`
+		Scope        string      `json:"scope"`
+	}
+	if err := f.post(ctx, f.auth.TokenURL, params, &body); err != nil {
+		return OAuth2Token{}, err
+	}
+	if body.AccessToken == "" {
+		return OAuth2Token{}, fmt.Errorf("the token endpoint returned no access_token")
+	}
+
+	now := time.Now()
+	token := OAuth2Token{
+		Key:          oauth2CacheKey(f.auth),
+		GrantType:    oauth2GrantType(f.auth),
+		TokenURL:     f.auth.TokenURL,
+		ClientID:     f.auth.ClientID,
+		AccessToken:  body.AccessToken,
+		TokenType:    body.TokenType,
+		RefreshToken: body.RefreshToken,
+		IDToken:      body.IDToken,
+		Scope:        body.Scope,
+		Obtained:     now,
+	}
+	if token.Scope == "" {
+		token.Scope = f.auth.Scope
+	}
+	if seconds, err := body.ExpiresIn.Float64(); err == nil && seconds > 0 {
+		token.ExpiresAt = now.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	return token, nil
+}
+
+// addScope adds the auth block's scope and audience to params
+func (f *oauth2Flow) addScope(params url.Values) {
+	if f.auth.Scope != "" {
+		params.Set("scope", f.auth.Scope)
+	}
+	if f.auth.Audience != "" {
+		params.Set("audience", f.auth.Audience)
+	}
+}
+
+// post sends a form with the client's credentials and decodes the JSON,
+// or form-encoded, answer into v
+func (f *oauth2Flow) post(ctx context.Context, endpoint string, params url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	// Public clients, and those asking for it, send their ID in the body
+	if f.auth.ClientSecret != "" && !strings.EqualFold(f.auth.ClientAuth, "body") {
+		req.SetBasicAuth(url.QueryEscape(f.auth.ClientID), url.QueryEscape(f.auth.ClientSecret))
+	} else {
+		if f.auth.ClientID != "" {
+			params.Set("client_id", f.auth.ClientID)
+		}
+		if f.auth.ClientSecret != "" {
+			params.Set("client_secret", f.auth.ClientSecret)
+		}
+	}
+	form := params.Encode()
+	req.Body = io.NopCloser(strings.NewReader(form))
+	req.ContentLength = int64(len(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+
+	// Some servers, GitHub's among them, answer with a form by default
+	fields := map[string]interface{}{}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" || mediaType == "text/plain" {
+		values, _ := url.ParseQuery(string(data))
+		for key := range values {
+			fields[key] = values.Get(key)
+		}
+		data, _ = json.Marshal(fields)
+	} else if err := json.Unmarshal(data, &fields); err != nil {
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+		}
+		return fmt.Errorf("%s returned invalid JSON: %v", endpoint, err)
+	}
+
+	if code, ok := fields["error"].(string); ok && code != "" {
+		description, _ := fields["error_description"].(string)
+		return &oauth2Error{Code: code, Description: description}
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// deviceCode runs the device authorization grant (RFC 8628): the user
+// enters a code on another device while the token endpoint is polled
+func (f *oauth2Flow) deviceCode(ctx context.Context) (OAuth2Token, error) {
+	if f.auth.DeviceAuthURL == "" {
+		return OAuth2Token{}, fmt.Errorf("a device authorization URL is required")
+	}
+	params := url.Values{}
+	f.addScope(params)
+	var device struct {
+		DeviceCode              string      `json:"device_code"`
+		UserCode                string      `json:"user_code"`
+		VerificationURI         string      `json:"verification_uri"`
+		VerificationURL         string      `json:"verification_url"` // Google's name for it
+		VerificationURIComplete string      `json:"verification_uri_complete"`
+		ExpiresIn               json.Number `json:"expires_in"`
+		Interval                json.Number `json:"interval"`
+	}
+	if err := f.post(ctx, f.auth.DeviceAuthURL, params, &device); err != nil {
+		return OAuth2Token{}, err
+	}
+	if device.DeviceCode == "" {
+		return OAuth2Token{}, fmt.Errorf("the device authorization endpoint returned no device_code")
+	}
+	if device.VerificationURI == "" {
+		device.VerificationURI = device.VerificationURL
+	}
+
+	expiresIn, err := device.ExpiresIn.Int64()
+	if err != nil || expiresIn <= 0 {
+		expiresIn = 600
+	}
+	interval := 5 * time.Second
+	if seconds, err := device.Interval.Int64(); err == nil && seconds > 0 {
+		interval = time.Duration(seconds) * time.Second
+	}
+	f.app.emitEvent(oauth2DeviceEvent, OAuth2DeviceCode{
+		UserCode:                device.UserCode,
+		VerificationURI:         device.VerificationURI,
+		VerificationURIComplete: device.VerificationURIComplete,
+		ExpiresIn:               int(expiresIn),
+	})
+	if device.VerificationURIComplete != "" {
+		openBrowser(f.app, device.VerificationURIComplete)
+	} else if device.VerificationURI != "" {
+		openBrowser(f.app, device.VerificationURI)
+	}
+
+	deadline := time.Now().Add(time.Duration(expiresIn) * time.Second)
+	for {
+		select {
+		case <-ctx.Done():
+			return OAuth2Token{}, ctx.Err()
+		case <-time.After(interval):
+		}
+		if time.Now().After(deadline) {
+			return OAuth2Token{}, fmt.Errorf("the device code expired before it was approved")
+		}
+
+		token, err := f.token(ctx, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {device.DeviceCode},
+		}, false)
+		var oauthErr *oauth2Error
+		switch {
+		case err == nil:
+			return token, nil
+		case errors.As(err, &oauthErr) && oauthErr.Code == "authorization_pending":
+		case errors.As(err, &oauthErr) && oauthErr.Code == "slow_down":
+			interval += 5 * time.Second
+		default:
+			return OAuth2Token{}, err
+		}
+	}
+}
+
+// authorizationCode runs the authorization code grant with PKCE (RFC 7636):
+// the browser signs the user in and comes back to a loopback listener
+// with the code
+func (f *oauth2Flow) authorizationCode(ctx context.Context) (OAuth2Token, error) {
+	if f.auth.AuthURL == "" {
+		return OAuth2Token{}, fmt.Errorf("an authorization URL is required")
+	}
+	authURL, err := url.Parse(f.auth.AuthURL)
+	if err != nil {
+		return OAuth2Token{}, fmt.Errorf("invalid authorization URL: %v", err)
+	}
+
+	redirect, err := url.Parse(f.auth.RedirectURI)
+	if f.auth.RedirectURI == "" {
+		redirect, err = &url.URL{Scheme: "http", Host: "127.0.0.1:0", Path: "/callback"}, nil
+	}
+	if err != nil {
+		return OAuth2Token{}, fmt.Errorf("invalid redirect URI: %v", err)
+	}
+	switch redirect.Hostname() {
+	case "127.0.0.1", "localhost", "::1":
+	default:
+		return OAuth2Token{}, fmt.Errorf("the redirect URI must be a loopback address such as http://127.0.0.1:8080/callback")
+	}
+	if redirect.Path == "" {
+		redirect.Path = "/"
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(redirect.Hostname(), redirect.Port()))
+	if err != nil {
+		return OAuth2Token{}, fmt.Errorf("cannot listen for the redirect: %v", err)
+	}
+	redirect.Host = net.JoinHostPort(redirect.Hostname(), strconv.Itoa(listener.Addr().(*net.TCPAddr).Port))
+
+	verifier := base64.RawURLEncoding.EncodeToString([]byte(newAuthNonce() + newAuthNonce()))
+	challenge := sha256.Sum256([]byte(verifier))
+	state := newAuthNonce()
+
+	type callback struct {
+		code string
+		err  error
+	}
+	results := make(chan callback, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != redirect.Path {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		var result callback
+		switch {
+		case query.Get("state") != state:
+			result.err = fmt.Errorf("the authorization server returned the wrong state")
+		case query.Get("error") != "":
+			result.err = &oauth2Error{Code: query.Get("error"), Description: query.Get("error_description")}
+		case query.Get("code") == "":
+			result.err = fmt.Errorf("the authorization server returned no code")
+		default:
+			result.code = query.Get("code")
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if result.err != nil {
+			fmt.Fprintf(w, "<p>Authorization failed: %s</p>", html.EscapeString(result.err.Error()))
+		} else {
+			fmt.Fprint(w, "<p>Authorization complete. You can close this window.</p>")
+		}
+		select {
+		case results <- result:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", f.auth.ClientID)
+	query.Set("redirect_uri", redirect.String())
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if f.auth.Scope != "" {
+		query.Set("scope", f.auth.Scope)
+	}
+	if f.auth.Audience != "" {
+		query.Set("audience", f.auth.Audience)
+	}
+	authURL.RawQuery = query.Encode()
+	f.app.emitEvent(oauth2AuthorizeEvent, map[string]string{"url": authURL.String(), "redirectUri": redirect.String()})
+	openBrowser(f.app, authURL.String())
+
+	var result callback
+	select {
+	case <-ctx.Done():
+		return OAuth2Token{}, ctx.Err()
+	case <-time.After(oauth2AuthorizeTimeout):
+		return OAuth2Token{}, fmt.Errorf("timed out waiting for the browser to return")
+	case result = <-results:
+	}
+	if result.err != nil {
+		return OAuth2Token{}, result.err
+	}
+
+	return f.token(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {result.code},
+		"redirect_uri":  {redirect.String()},
+		"code_verifier": {verifier},
+	}, false)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockAuthServer is a local authorization server for the OAuth2 flows
+type mockAuthServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	issued     int
+	challenges map[string]string // code -> PKCE challenge
+	polls      int
+}
+
+func newMockAuthServer(t *testing.T) *mockAuthServer {
+	s := &mockAuthServer{challenges: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != "app" {
+			http.Error(w, "bad authorization request", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.challenges["auth-code"] = query.Get("code_challenge")
+		s.mu.Unlock()
+		redirect, _ := url.Parse(query.Get("redirect_uri"))
+		back := redirect.Query()
+		back.Set("code", "auth-code")
+		back.Set("state", query.Get("state"))
+		redirect.RawQuery = back.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": s.URL + "/activate",
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/activate", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch r.Form.Get("grant_type") {
+		case "client_credentials":
+			if id, secret, ok := r.BasicAuth(); !ok || id != "app" || secret != "secret" {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+				return
+			}
+		case "password":
+			if r.Form.Get("username") != "user" || r.Form.Get("password") != "pass" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+				return
+			}
+		case "refresh_token":
+			if r.Form.Get("refresh_token") == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+				return
+			}
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if s.challenges[r.Form.Get("code")] != base64.RawURLEncoding.EncodeToString(sum[:]) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE check failed"})
+				return
+			}
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if s.polls++; s.polls < 2 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+				return
+			}
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+			return
+		}
+		s.issued++
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token":  "token-" + r.Form.Get("grant_type"),
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": "refresh",
+		})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// writeJSON answers with v as JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// newOAuth2TestApp returns an App storing into a temporary directory, with
+// openBrowser following the URL it is given as a browser would
+func newOAuth2TestApp(t *testing.T) *App {
+	a := &App{storagePath: t.TempDir()}
+	if err := os.MkdirAll(filepath.Join(a.storagePath, "http"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	previous := openBrowser
+	openBrowser = func(_ *App, target string) {
+		go func() {
+			if resp, err := http.Get(target); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	t.Cleanup(func() { openBrowser = previous })
+	return a
+}
+
+func TestOAuth2Flows(t *testing.T) {
+	server := newMockAuthServer(t)
+	a := newOAuth2TestApp(t)
+
+	tests := []HTTPAuth{
+		{GrantType: "client_credentials", ClientID: "app", ClientSecret: "secret"},
+		{GrantType: "password", ClientID: "app", Username: "user", Password: "pass"},
+		{GrantType: "refresh_token", ClientID: "app", RefreshToken: "refresh"},
+		{GrantType: "device_code", ClientID: "app", DeviceAuthURL: server.URL + "/device"},
+		{GrantType: "authorization_code", ClientID: "app", AuthURL: server.URL + "/authorize"},
+	}
+	for _, auth := range tests {
+		t.Run(auth.GrantType, func(t *testing.T) {
+			auth.Type = "oauth2"
+			auth.TokenURL = server.URL + "/token"
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			flow := &oauth2Flow{app: a, auth: auth, client: server.Client()}
+			token, err := flow.run(ctx)
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			want := "token-" + auth.GrantType
+			if auth.GrantType == "device_code" {
+				want = "token-urn:ietf:params:oauth:grant-type:device_code"
+			}
+			if token.AccessToken != want || token.ExpiresAt.IsZero() {
+				t.Errorf("token = %+v, want access token %q with an expiry", token, want)
+			}
+		})
+	}
+}
+
+func TestOAuth2TokenCachesAndRefreshes(t *testing.T) {
+	server := newMockAuthServer(t)
+	a := newOAuth2TestApp(t)
+	auth := HTTPAuth{Type: "oauth2", TokenURL: server.URL + "/token", ClientID: "app", ClientSecret: "secret"}
+	ctx := context.Background()
+
+	first, err := a.oauth2Token(ctx, "dev", auth, server.Client())
+	if err != nil {
+		t.Fatalf("first token: %v", err)
+	}
+	second, err := a.oauth2Token(ctx, "dev", auth, server.Client())
+	if err != nil {
+		t.Fatalf("cached token: %v", err)
+	}
+	server.mu.Lock()
+	issued := server.issued
+	server.mu.Unlock()
+	if second.AccessToken != first.AccessToken || issued != 1 {
+		t.Fatalf("the cached token was not reused: %d tokens issued", issued)
+	}
+
+	// An expired token is refreshed with its refresh token
+	first.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := a.storeOAuth2Token("dev", first); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := a.oauth2Token(ctx, "dev", auth, server.Client())
+	if err != nil {
+		t.Fatalf("refreshed token: %v", err)
+	}
+	if refreshed.AccessToken != "token-refresh_token" {
+		t.Errorf("access token = %q, want a refreshed one", refreshed.AccessToken)
+	}
+}
+
+func TestOAuth2FlowLockIsPerToken(t *testing.T) {
+	a := newOAuth2TestApp(t)
+	auth := HTTPAuth{Type: "oauth2", TokenURL: "https://auth.example/token", ClientID: "app"}
+
+	unlock, err := a.lockOAuth2Flow(context.Background(), "dev", auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	// Other environments and clients are not held up
+	other := auth
+	other.ClientID = "other"
+	for _, lock := range []struct {
+		environment string
+		auth        HTTPAuth
+	}{{"prod", auth}, {"dev", other}} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		release, err := a.lockOAuth2Flow(ctx, lock.environment, lock.auth)
+		cancel()
+		if err != nil {
+			t.Fatalf("lock for %q/%q waited on another flow: %v", lock.environment, lock.auth.ClientID, err)
+		}
+		release()
+	}
+
+	// The same token waits until the running flow ends or ctx does
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.lockOAuth2Flow(ctx, "dev", auth); err != context.DeadlineExceeded {
+		t.Fatalf("second flow for the same token: err = %v, want it to wait", err)
+	}
+}
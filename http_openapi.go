@@ -0,0 +1,1101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"gopkg.in/yaml.v3"
+)
+
+// ========== OpenAPI Import ==========
+
+// openAPIMethods are the operations of a path item, in the order they are
+// imported
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openAPIServerVariablePattern matches {name} in server URLs and paths
+var openAPIServerVariablePattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// openAPISpec is an OpenAPI 3.x or Swagger 2.0 document being imported.
+// The document is kept as decoded JSON, as specs vary too much for fixed
+// types.
+type openAPISpec struct {
+	doc     map[string]interface{}
+	version string // "2.0", "3.0" or "3.1"
+	c       *collectionImport
+	// credentials are the variables the security schemes read, by name,
+	// true for secrets
+	credentials map[string]bool
+}
+
+// ImportOpenAPISpec converts an OpenAPI 3.x or Swagger 2.0 spec, in JSON
+// or YAML, into a folder of .http files in parentPath, or in the http
+// folder if parentPath is empty: one file per operation, in a folder per
+// tag. Each server becomes an environment with a baseUrl variable.
+// References to other files cannot be followed and are reported.
+func (a *App) ImportOpenAPISpec(content string, parentPath string) FileSystemResponse {
+	return a.importOpenAPI([]byte(content), "", parentPath)
+}
+
+// ImportOpenAPIFile picks a spec with the native open dialog and imports it
+// like ImportOpenAPISpec, following references to files next to it. Data
+// is nil if the dialog was cancelled.
+func (a *App) ImportOpenAPIFile(parentPath string) FileSystemResponse {
+	file, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Import OpenAPI or Swagger Spec",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "OpenAPI Specs (*.json, *.yaml, *.yml)", Pattern: "*.json;*.yaml;*.yml"},
+		},
+	})
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	if file == "" {
+		return FileSystemResponse{Success: true}
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to read file: %v", err)}
+	}
+	return a.importOpenAPI(content, file, parentPath)
+}
+
+// importOpenAPI imports a spec read from specPath, empty if unknown
+func (a *App) importOpenAPI(data []byte, specPath string, parentPath string) FileSystemResponse {
+	collection, err := parseOpenAPISpec(data, specPath)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	result, err := a.saveCollectionImport(collection, parentPath)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: result}
+}
+
+// decodeOpenAPIDocument reads JSON or YAML into the types encoding/json
+// produces, with numbers as json.Number
+func decodeOpenAPIDocument(data []byte) (interface{}, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		// YAML allows keys that are not strings, such as status codes
+		var err error
+		if data, err = json.Marshal(normalizeYAML(doc)); err != nil {
+			return nil, err
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// normalizeYAML turns mappings with non-string keys into string-keyed maps
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = normalizeYAML(child)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(child)
+		}
+		return m
+	case []interface{}:
+		for i, child := range v {
+			v[i] = normalizeYAML(child)
+		}
+	}
+	return value
+}
+
+// parseOpenAPISpec reads a spec into a folder named after its title
+func parseOpenAPISpec(data []byte, specPath string) (*collectionImport, error) {
+	decoded, err := decodeOpenAPIDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("The spec is not valid JSON or YAML: %v", err)
+	}
+	doc, _ := decoded.(map[string]interface{})
+	s := &openAPISpec{
+		doc:         doc,
+		c:           &collectionImport{format: "openapi"},
+		credentials: map[string]bool{},
+	}
+	switch version := fmt.Sprint(doc["openapi"]); {
+	case strings.HasPrefix(version, "3.1"):
+		s.version = "3.1"
+	case strings.HasPrefix(version, "3."):
+		s.version = "3.0"
+	case fmt.Sprint(doc["swagger"]) == "2.0":
+		s.version = "2.0"
+	default:
+		return nil, fmt.Errorf("Not an OpenAPI 3 or Swagger 2.0 spec")
+	}
+
+	// Inline the documents that external references point to
+	dir := ""
+	if specPath != "" {
+		dir = filepath.Dir(specPath)
+	}
+	bundled, ok := s.bundle(doc, dir, map[string]interface{}{}, nil).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid document: the spec must be an object")
+	}
+	s.doc = bundled
+
+	title := stringField(mapField(s.doc, "info"), "title")
+	if title == "" {
+		title = "OpenAPI"
+	}
+	root := &importedFolder{Name: title}
+	tags := map[string]*importedFolder{}
+
+	paths := mapField(s.doc, "paths")
+	for _, path := range sortedKeys(paths) {
+		item := s.resolve(paths[path], nil)
+		for _, method := range openAPIMethods {
+			op := mapField(item, method)
+			if op == nil {
+				continue
+			}
+			folder := s.operationFolder(path, method, item, op)
+			if tagList := listField(op, "tags"); len(tagList) > 0 {
+				tag := fmt.Sprint(tagList[0])
+				if tags[tag] == nil {
+					tags[tag] = &importedFolder{Name: tag}
+					root.Folders = append(root.Folders, tags[tag])
+				}
+				tags[tag].Folders = append(tags[tag].Folders, folder)
+			} else {
+				root.Folders = append(root.Folders, folder)
+			}
+		}
+	}
+
+	s.c.folders = []*importedFolder{root}
+	s.c.environments = s.environments(title)
+	return s.c, nil
+}
+
+// mapField, listField and stringField read the members of decoded JSON
+func mapField(node map[string]interface{}, key string) map[string]interface{} {
+	m, _ := node[key].(map[string]interface{})
+	return m
+}
+
+func listField(node map[string]interface{}, key string) []interface{} {
+	list, _ := node[key].([]interface{})
+	return list
+}
+
+func stringField(node map[string]interface{}, key string) string {
+	s, _ := node[key].(string)
+	return s
+}
+
+// jsonPointer finds the value at a JSON pointer such as
+// "/components/schemas/Pet"
+func jsonPointer(doc interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return doc, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+	current := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		if unescaped, err := url.PathUnescape(token); err == nil {
+			token = unescaped
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if current, ok = node[token]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			var i int
+			if _, err := fmt.Sscan(token, &i); err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			current = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// bundle replaces references to other files with their targets. Inside
+// those files, references to their own content are inlined as well, as
+// they cannot point into the main document; a reference back into one
+// being inlined becomes an empty schema.
+func (s *openAPISpec) bundle(node interface{}, dir string, files map[string]interface{}, inlining []string) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok && (!strings.HasPrefix(ref, "#") || len(inlining) > 0) {
+			file, pointer, _ := strings.Cut(ref, "#")
+			if file == "" {
+				file = inlining[len(inlining)-1]
+			} else if dir == "" {
+				s.c.warn("Reference %s was not followed: import the spec from its file to follow references to other files", ref)
+				return map[string]interface{}{}
+			} else if !filepath.IsAbs(file) {
+				file = filepath.Join(dir, filepath.FromSlash(file))
+			}
+			key := file + "#" + pointer
+			for _, seen := range inlining {
+				if seen == key {
+					s.c.warn("Reference %s is recursive and was left out", ref)
+					return map[string]interface{}{}
+				}
+			}
+
+			doc, ok := files[file]
+			if !ok {
+				data, err := os.ReadFile(file)
+				if err == nil {
+					doc, err = decodeOpenAPIDocument(data)
+				}
+				if err != nil {
+					s.c.warn("Reference %s was not followed: %v", ref, err)
+					return map[string]interface{}{}
+				}
+				files[file] = doc
+			}
+			target, ok := jsonPointer(doc, pointer)
+			if !ok {
+				s.c.warn("Reference %s points to nothing", ref)
+				return map[string]interface{}{}
+			}
+			// Track the file itself too, so its "#..." references resolve
+			return s.bundle(target, filepath.Dir(file), files, append(inlining, key, file))
+		}
+		m := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			m[key] = s.bundle(child, dir, files, inlining)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, child := range v {
+			list[i] = s.bundle(child, dir, files, inlining)
+		}
+		return list
+	}
+	return node
+}
+
+// resolve follows the $ref of a node within the document. Refs already on
+// the stack are recursive and resolve to nil.
+func (s *openAPISpec) resolve(node interface{}, stack []string) map[string]interface{} {
+	m, _ := node.(map[string]interface{})
+	for hops := 0; m != nil && hops < 32; hops++ {
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#") {
+			return m
+		}
+		for _, seen := range stack {
+			if seen == ref {
+				return nil
+			}
+		}
+		target, _ := jsonPointer(s.doc, ref[1:])
+		m, _ = target.(map[string]interface{})
+	}
+	return m
+}
+
+// refOf returns the $ref of a node, if any
+func refOf(node interface{}) string {
+	m, _ := node.(map[string]interface{})
+	ref, _ := m["$ref"].(string)
+	return ref
+}
+
+// openAPIParameter is a parameter of an operation
+type openAPIParameter struct {
+	name     string
+	in       string // path, query, header, cookie; Swagger 2.0 adds body and formData
+	required bool
+	node     map[string]interface{}
+}
+
+// parameters merges the path item's parameters with the operation's,
+// which override those with the same name and location
+func (s *openAPISpec) parameters(item map[string]interface{}, op map[string]interface{}) []openAPIParameter {
+	var params []openAPIParameter
+	index := map[string]int{}
+	for _, list := range [][]interface{}{listField(item, "parameters"), listField(op, "parameters")} {
+		for _, raw := range list {
+			node := s.resolve(raw, nil)
+			if node == nil {
+				continue
+			}
+			param := openAPIParameter{
+				name:     stringField(node, "name"),
+				in:       stringField(node, "in"),
+				required: node["required"] == true,
+				node:     node,
+			}
+			key := param.in + ":" + param.name
+			if i, ok := index[key]; ok {
+				params[i] = param
+			} else {
+				index[key] = len(params)
+				params = append(params, param)
+			}
+		}
+	}
+	return params
+}
+
+// schema returns a parameter's schema; Swagger 2.0 puts the schema
+// keywords on the parameter itself
+func (p openAPIParameter) schema() map[string]interface{} {
+	if schema := mapField(p.node, "schema"); schema != nil {
+		return schema
+	}
+	return p.node
+}
+
+// operationFolder converts an operation into a folder holding its request,
+// so that it becomes a .http file of its own with its parameters as
+// variables
+func (s *openAPISpec) operationFolder(path string, method string, item map[string]interface{}, op map[string]interface{}) *importedFolder {
+	name := stringField(op, "operationId")
+	if name == "" {
+		name = method + " " + path
+	}
+	folder := &importedFolder{Name: name}
+	request := HTTPRequest{Method: strings.ToUpper(method), Headers: map[string]string{}}
+
+	var query []string
+	var optional []string
+	var form []openAPIParameter
+	var bodySchema map[string]interface{}
+	for _, param := range s.parameters(item, op) {
+		variable := openAPIVariableName(param.name)
+		switch param.in {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.name+"}", "{{"+variable+"}}")
+		case "query":
+			if !param.required && !s.hasExample(param) {
+				optional = append(optional, param.name)
+				continue
+			}
+			query = append(query, url.QueryEscape(param.name)+"={{"+variable+"}}")
+		case "header":
+			switch strings.ToLower(param.name) {
+			case "accept", "content-type", "authorization":
+				continue
+			}
+			request.Headers[param.name] = "{{" + variable + "}}"
+		case "body":
+			bodySchema = param.schema()
+			continue
+		case "formData":
+			form = append(form, param)
+			continue
+		default:
+			s.c.warn("Operation %q: %s parameter %q was not imported", name, param.in, param.name)
+			continue
+		}
+		folder.Variables = append(folder.Variables, HTTPVariable{Key: variable, Value: s.parameterValue(param)})
+	}
+
+	request.URL = "{{baseUrl}}" + path
+	if len(query) > 0 {
+		request.URL += "?" + strings.Join(query, "&")
+	}
+	if len(optional) > 0 {
+		s.c.warn("Operation %q: optional query parameters without examples were left out: %s", name, strings.Join(optional, ", "))
+	}
+
+	if s.version == "2.0" {
+		s.swaggerBody(&request, op, bodySchema, form, name)
+	} else if body := s.resolve(op["requestBody"], nil); body != nil {
+		s.openAPIBody(&request, body, name)
+	}
+	s.applySecurity(&request, op, name)
+
+	// Expect a documented success status, with the schema of that status
+	responses := mapField(op, "responses")
+	var success []string
+	for _, code := range sortedKeys(responses) {
+		if strings.HasPrefix(code, "2") {
+			success = append(success, code)
+		}
+	}
+	if len(success) == 1 {
+		request.Assertions = append(request.Assertions, HTTPAssertion{Type: "status", Operator: "==", Expected: strings.ToLower(success[0])})
+	} else if len(success) > 1 {
+		request.Assertions = append(request.Assertions, HTTPAssertion{Type: "status", Operator: "==", Expected: "2xx"})
+	}
+	for _, code := range success {
+		schema := s.responseSchema(s.resolve(responses[code], nil))
+		if schema == nil {
+			continue
+		}
+		assertion := HTTPAssertion{Type: "schema", Expected: s.inlineSchema(schema)}
+		if len(success) > 1 {
+			assertion.Target = strings.ToLower(code)
+		}
+		request.Assertions = append(request.Assertions, assertion)
+	}
+
+	folder.Requests = []HTTPFileRequest{{Name: name, Request: request}}
+	return folder
+}
+
+// openAPIVariableName makes a parameter name usable as a file variable
+func openAPIVariableName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 128 && (r == '_' || r == '-' || r == '.' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// hasExample reports whether a parameter documents a value
+func (s *openAPISpec) hasExample(param openAPIParameter) bool {
+	schema := s.resolve(param.schema(), nil)
+	for _, node := range []map[string]interface{}{param.node, schema} {
+		if node == nil {
+			continue
+		}
+		for _, key := range []string{"example", "examples", "x-example", "default", "enum"} {
+			if node[key] != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parameterValue returns the example value of a parameter as text
+func (s *openAPISpec) parameterValue(param openAPIParameter) string {
+	var value interface{}
+	if v, ok := param.node["example"]; ok {
+		value = v
+	} else if v, ok := param.node["x-example"]; ok {
+		value = v
+	} else if examples := mapField(param.node, "examples"); len(examples) > 0 {
+		value = s.resolve(examples[sortedKeys(examples)[0]], nil)["value"]
+	} else {
+		value = s.example(param.schema(), nil)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		// Arrays use the default form style: comma separated
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = jsonValueString(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return jsonValueString(value)
+}
+
+// openAPIMediaTypes orders the media types a request body is generated for
+var openAPIMediaTypes = []string{"application/json", "application/x-www-form-urlencoded", "multipart/form-data"}
+
+// pickMediaType chooses the media type to use from a content map
+func pickMediaType(content map[string]interface{}) string {
+	for _, preferred := range openAPIMediaTypes {
+		if _, ok := content[preferred]; ok {
+			return preferred
+		}
+	}
+	keys := sortedKeys(content)
+	for _, mediaType := range keys {
+		if strings.HasSuffix(mediaType, "+json") {
+			return mediaType
+		}
+	}
+	if len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// isJSONMediaType reports whether a media type holds JSON
+func isJSONMediaType(mediaType string) bool {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.Split(mediaType, ";")[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "*/*"
+}
+
+// openAPIBody generates the body of an OpenAPI 3 request from its
+// examples, or from its schema
+func (s *openAPISpec) openAPIBody(request *HTTPRequest, body map[string]interface{}, name string) {
+	content := mapField(body, "content")
+	mediaType := pickMediaType(content)
+	if mediaType == "" {
+		return
+	}
+	media := mapField(content, mediaType)
+
+	var example interface{}
+	if v, ok := media["example"]; ok {
+		example = v
+	} else if examples := mapField(media, "examples"); len(examples) > 0 {
+		example = s.resolve(examples[sortedKeys(examples)[0]], nil)["value"]
+	} else {
+		example = s.example(media["schema"], nil)
+	}
+	s.setBody(request, mediaType, example, s.resolve(media["schema"], nil), name)
+}
+
+// swaggerBody generates the body of a Swagger 2.0 request from its body
+// parameter or its form parameters
+func (s *openAPISpec) swaggerBody(request *HTTPRequest, op map[string]interface{}, schema map[string]interface{}, form []openAPIParameter, name string) {
+	consumes := listField(op, "consumes")
+	if consumes == nil {
+		consumes = listField(s.doc, "consumes")
+	}
+	has := func(mediaType string) bool {
+		for _, c := range consumes {
+			if strings.EqualFold(fmt.Sprint(c), mediaType) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if schema != nil {
+		mediaType := "application/json"
+		if len(consumes) > 0 && !has(mediaType) {
+			mediaType = fmt.Sprint(consumes[0])
+		}
+		s.setBody(request, mediaType, s.example(schema, nil), s.resolve(schema, nil), name)
+		return
+	}
+	if len(form) == 0 {
+		return
+	}
+
+	body := &HTTPBody{Type: "form"}
+	if has("multipart/form-data") {
+		body.Type = "multipart"
+	}
+	for _, param := range form {
+		field := HTTPBodyField{Name: param.name}
+		if stringField(param.node, "type") == "file" {
+			body.Type = "multipart"
+			field.File = "./" + safeFileName(param.name)
+			s.c.warn("Operation %q: choose the file to upload as %q", name, param.name)
+		} else {
+			field.Value = s.parameterValue(param)
+		}
+		body.Fields = append(body.Fields, field)
+	}
+	request.StructuredBody = body
+}
+
+// setBody sets a generated body. Objects become form fields for form
+// media types; binary fields are uploaded from a file to choose.
+func (s *openAPISpec) setBody(request *HTTPRequest, mediaType string, example interface{}, schema map[string]interface{}, name string) {
+	switch {
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data":
+		body := &HTTPBody{Type: "form"}
+		if mediaType == "multipart/form-data" {
+			body.Type = "multipart"
+		}
+		values, _ := example.(map[string]interface{})
+		properties := mapField(schema, "properties")
+		for _, field := range sortedKeys(values) {
+			part := HTTPBodyField{Name: field, Value: formValue(values[field])}
+			if property := s.resolve(properties[field], nil); property != nil && stringField(property, "format") == "binary" {
+				part.Value, part.File = "", "./"+safeFileName(field)
+				s.c.warn("Operation %q: choose the file to upload as %q", name, field)
+			}
+			body.Fields = append(body.Fields, part)
+		}
+		request.StructuredBody = body
+	case isJSONMediaType(mediaType):
+		if example != nil {
+			data, _ := json.MarshalIndent(example, "", "  ")
+			request.Body = string(data)
+		}
+		request.Headers["Content-Type"] = mediaType
+	default:
+		if text, ok := example.(string); ok {
+			request.Body = text
+		} else {
+			s.c.warn("Operation %q: no example body could be generated for %s", name, mediaType)
+		}
+		request.Headers["Content-Type"] = mediaType
+	}
+}
+
+// formValue renders a form field's example
+func formValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return jsonValueString(value)
+}
+
+// openAPIStringFormats are example values for string formats
+var openAPIStringFormats = map[string]string{
+	"date-time": "2024-01-01T00:00:00Z", "date": "2024-01-01", "time": "12:00:00",
+	"email": "user@example.com", "uuid": "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"uri": "https://example.com", "url": "https://example.com", "hostname": "example.com",
+	"ipv4": "192.0.2.1", "ipv6": "2001:db8::1", "byte": "c3RyaW5n", "password": "password",
+	"binary": "",
+}
+
+// example synthesizes a value for a schema from its examples, defaults
+// and types. Stack holds the refs being expanded, so that recursive
+// schemas stop.
+func (s *openAPISpec) example(node interface{}, stack []string) interface{} {
+	schema := s.resolve(node, stack)
+	if schema == nil || len(stack) > 16 {
+		return nil
+	}
+	if ref := refOf(node); ref != "" {
+		stack = append(stack, ref)
+	}
+
+	for _, key := range []string{"example", "default", "const"} {
+		if value, ok := schema[key]; ok {
+			return value
+		}
+	}
+	for _, key := range []string{"examples", "enum"} {
+		if list := listField(schema, key); len(list) > 0 {
+			return list[0]
+		}
+	}
+	if allOf := listField(schema, "allOf"); len(allOf) > 0 {
+		merged := map[string]interface{}{}
+		for _, part := range allOf {
+			if object, ok := s.example(part, stack).(map[string]interface{}); ok {
+				for key, value := range object {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if list := listField(schema, key); len(list) > 0 {
+			return s.example(list[0], stack)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		object := map[string]interface{}{}
+		properties := mapField(schema, "properties")
+		for _, name := range sortedKeys(properties) {
+			property := s.resolve(properties[name], stack)
+			if property == nil || property["readOnly"] == true {
+				continue
+			}
+			if value := s.example(properties[name], stack); value != nil {
+				object[name] = value
+			}
+		}
+		return object
+	case "array":
+		if item := s.example(schema["items"], stack); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "string":
+		if value, ok := openAPIStringFormats[stringField(schema, "format")]; ok {
+			return value
+		}
+		return "string"
+	case "integer", "number":
+		if minimum, ok := schema["minimum"].(json.Number); ok {
+			return minimum
+		}
+		return json.Number("0")
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+// schemaType returns the type of a schema, the first non-null one of a
+// list, or the one its keywords imply
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, item := range t {
+			if item != "null" {
+				return fmt.Sprint(item)
+			}
+		}
+	}
+	if schema["properties"] != nil {
+		return "object"
+	}
+	if schema["items"] != nil {
+		return "array"
+	}
+	return ""
+}
+
+// responseSchema returns the JSON schema of a response, nil if it has
+// none or is not JSON
+func (s *openAPISpec) responseSchema(response map[string]interface{}) interface{} {
+	if response == nil {
+		return nil
+	}
+	if s.version == "2.0" {
+		return response["schema"]
+	}
+	content := mapField(response, "content")
+	for _, mediaType := range sortedKeys(content) {
+		if isJSONMediaType(mediaType) {
+			if schema := mapField(content, mediaType)["schema"]; schema != nil {
+				return schema
+			}
+		}
+	}
+	return nil
+}
+
+// inlineSchema renders a response schema as JSON Schema, with the
+// spec's schemas it refers to as definitions, so that the assertion does
+// not depend on where its .http file is. Schemas are converted to draft 4
+// for OpenAPI 3.0 and Swagger 2.0, whose schemas build on it, and to
+// 2020-12 for OpenAPI 3.1.
+func (s *openAPISpec) inlineSchema(node interface{}) string {
+	specDefinitions := mapField(s.doc, "definitions")
+	if s.version != "2.0" {
+		specDefinitions = mapField(mapField(s.doc, "components"), "schemas")
+	}
+
+	definitions := map[string]interface{}{}
+	var collect func(node interface{})
+	collect = func(node interface{}) {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, "#/definitions/") {
+				token := strings.TrimPrefix(ref, "#/definitions/")
+				name := strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+				if _, done := definitions[name]; !done {
+					if target, ok := jsonPointer(specDefinitions, "/"+token); ok {
+						converted := s.jsonSchema(target, nil)
+						definitions[name] = converted
+						collect(converted)
+					}
+				}
+			}
+			for _, child := range v {
+				collect(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				collect(child)
+			}
+		}
+	}
+
+	converted := s.jsonSchema(node, nil)
+	collect(converted)
+	schema := map[string]interface{}{"$schema": "http://json-schema.org/draft-04/schema#"}
+	if s.version == "3.1" {
+		schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	}
+	if m, ok := converted.(map[string]interface{}); ok && m["$ref"] == nil {
+		for key, value := range m {
+			schema[key] = value
+		}
+	} else {
+		// $ref replaces its siblings in draft 4, so it goes below them
+		schema["allOf"] = []interface{}{converted}
+	}
+	if len(definitions) > 0 {
+		schema["definitions"] = definitions
+	}
+	data, _ := json.Marshal(schema)
+	return string(data)
+}
+
+// openAPIOnlyKeywords are schema keywords that JSON Schema does not have
+var openAPIOnlyKeywords = map[string]bool{
+	"nullable": true, "x-nullable": true, "discriminator": true, "xml": true,
+	"externalDocs": true, "example": true, "deprecated": true,
+}
+
+// jsonSchema converts an OpenAPI schema to JSON Schema. References to the
+// spec's schemas point into the definitions; other references are
+// inlined. Nullable schemas also accept null.
+func (s *openAPISpec) jsonSchema(node interface{}, stack []string) interface{} {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+
+	var converted map[string]interface{}
+	if ref, ok := schema["$ref"].(string); ok {
+		prefix := "#/components/schemas/"
+		if s.version == "2.0" {
+			prefix = "#/definitions/"
+		}
+		if strings.HasPrefix(ref, prefix) {
+			converted = map[string]interface{}{"$ref": "#/definitions/" + strings.TrimPrefix(ref, prefix)}
+		} else if target := s.resolve(schema, stack); target != nil {
+			converted, _ = s.jsonSchema(target, append(stack, ref)).(map[string]interface{})
+		} else {
+			converted = map[string]interface{}{}
+		}
+	} else {
+		converted = make(map[string]interface{}, len(schema))
+		for key, value := range schema {
+			if openAPIOnlyKeywords[key] || strings.HasPrefix(key, "x-") {
+				continue
+			}
+			switch key {
+			case "properties", "patternProperties", "definitions", "$defs", "dependentSchemas":
+				if children, ok := value.(map[string]interface{}); ok {
+					m := make(map[string]interface{}, len(children))
+					for name, child := range children {
+						m[name] = s.jsonSchema(child, stack)
+					}
+					value = m
+				}
+			case "items", "additionalProperties", "not", "contains", "if", "then", "else", "propertyNames":
+				value = s.jsonSchema(value, stack)
+			case "allOf", "anyOf", "oneOf", "prefixItems":
+				if children, ok := value.([]interface{}); ok {
+					list := make([]interface{}, len(children))
+					for i, child := range children {
+						list[i] = s.jsonSchema(child, stack)
+					}
+					value = list
+				}
+			case "type":
+				if value == "file" {
+					continue
+				}
+			}
+			converted[key] = value
+		}
+	}
+
+	if s.version != "3.1" && (schema["nullable"] == true || schema["x-nullable"] == true) {
+		switch t := converted["type"].(type) {
+		case string:
+			converted["type"] = []interface{}{t, "null"}
+			if enum := listField(converted, "enum"); enum != nil {
+				converted["enum"] = append(enum, nil)
+			}
+		default:
+			return map[string]interface{}{"anyOf": []interface{}{converted, map[string]interface{}{"type": "null"}}}
+		}
+	}
+	return converted
+}
+
+// applySecurity authenticates a request with the first security
+// requirement of its operation, or of the spec. Credentials are read from
+// variables, which are added to the environments.
+func (s *openAPISpec) applySecurity(request *HTTPRequest, op map[string]interface{}, name string) {
+	requirements, ok := op["security"].([]interface{})
+	if !ok {
+		requirements = listField(s.doc, "security")
+	}
+	if len(requirements) == 0 {
+		return
+	}
+	requirement, _ := requirements[0].(map[string]interface{})
+
+	schemes := mapField(s.doc, "securityDefinitions")
+	if s.version != "2.0" {
+		schemes = mapField(mapField(s.doc, "components"), "securitySchemes")
+	}
+	for _, schemeName := range sortedKeys(requirement) {
+		scheme := s.resolve(schemes[schemeName], nil)
+		if scheme == nil {
+			continue
+		}
+		var scopes []string
+		for _, scope := range listField(requirement, schemeName) {
+			scopes = append(scopes, fmt.Sprint(scope))
+		}
+
+		switch schemeType, httpScheme := stringField(scheme, "type"), strings.ToLower(stringField(scheme, "scheme")); {
+		case schemeType == "basic" || (schemeType == "http" && httpScheme == "basic"):
+			request.Auth = &HTTPAuth{Type: "basic", Username: "{{username}}", Password: "{{password}}"}
+			s.credentials["username"], s.credentials["password"] = false, true
+		case schemeType == "http" && httpScheme == "bearer":
+			request.Auth = &HTTPAuth{Type: "bearer", Token: "{{token}}"}
+			s.credentials["token"] = true
+		case schemeType == "http" && httpScheme == "digest":
+			request.Auth = &HTTPAuth{Type: "digest", Username: "{{username}}", Password: "{{password}}"}
+			s.credentials["username"], s.credentials["password"] = false, true
+		case schemeType == "apiKey":
+			variable := openAPIVariableName(schemeName)
+			s.credentials[variable] = true
+			value := "{{" + variable + "}}"
+			switch stringField(scheme, "in") {
+			case "query":
+				request.URL = appendQueryParam(request.URL, stringField(scheme, "name"), value)
+			case "cookie":
+				setDefaultHeader(request, "Cookie", stringField(scheme, "name")+"="+value)
+			default:
+				setDefaultHeader(request, stringField(scheme, "name"), value)
+			}
+		case schemeType == "oauth2":
+			if auth := s.oauth2Auth(scheme, scopes); auth != nil {
+				request.Auth = auth
+				s.credentials["clientId"], s.credentials["clientSecret"] = false, true
+				if auth.GrantType == "password" {
+					s.credentials["username"], s.credentials["password"] = false, true
+				}
+			} else {
+				s.c.warn("Operation %q: the OAuth 2.0 flows of %q are not supported", name, schemeName)
+			}
+		default:
+			s.c.warn("Operation %q: %s security scheme %q is not supported", name, schemeType, schemeName)
+		}
+	}
+}
+
+// oauth2Auth converts the first supported flow of an OAuth 2.0 scheme
+func (s *openAPISpec) oauth2Auth(scheme map[string]interface{}, scopes []string) *HTTPAuth {
+	auth := &HTTPAuth{Type: "oauth2", ClientID: "{{clientId}}", ClientSecret: "{{clientSecret}}", Scope: strings.Join(scopes, " ")}
+	if s.version == "2.0" {
+		grants := map[string]string{"application": "client_credentials", "accessCode": "authorization_code", "password": "password"}
+		grant, ok := grants[stringField(scheme, "flow")]
+		if !ok {
+			return nil
+		}
+		auth.GrantType, auth.TokenURL, auth.AuthURL = grant, stringField(scheme, "tokenUrl"), stringField(scheme, "authorizationUrl")
+	} else {
+		flows := mapField(scheme, "flows")
+		for _, flow := range []struct{ name, grant string }{
+			{"clientCredentials", "client_credentials"}, {"authorizationCode", "authorization_code"}, {"password", "password"},
+		} {
+			if f := mapField(flows, flow.name); f != nil {
+				auth.GrantType, auth.TokenURL, auth.AuthURL = flow.grant, stringField(f, "tokenUrl"), stringField(f, "authorizationUrl")
+				break
+			}
+		}
+		if auth.GrantType == "" {
+			return nil
+		}
+	}
+	if auth.GrantType == "password" {
+		auth.Username, auth.Password = "{{username}}", "{{password}}"
+	}
+	return auth
+}
+
+// environments makes an environment of each server, with its URL as
+// baseUrl, its variables and the credentials to fill in
+func (s *openAPISpec) environments(title string) []HTTPEnvironment {
+	type server struct {
+		name, url string
+		variables []HTTPVariable
+	}
+	var servers []server
+
+	if s.version == "2.0" {
+		host := stringField(s.doc, "host")
+		if host == "" {
+			host = "localhost"
+		}
+		scheme := "https"
+		if schemes := listField(s.doc, "schemes"); len(schemes) > 0 {
+			scheme = fmt.Sprint(schemes[0])
+			for _, candidate := range schemes {
+				if candidate == "https" {
+					scheme = "https"
+				}
+			}
+		}
+		servers = append(servers, server{url: scheme + "://" + host + strings.TrimRight(stringField(s.doc, "basePath"), "/")})
+	} else {
+		for _, raw := range listField(s.doc, "servers") {
+			node, _ := raw.(map[string]interface{})
+			serverURL := strings.TrimRight(stringField(node, "url"), "/")
+			if strings.HasPrefix(serverURL, "/") || serverURL == "" {
+				s.c.warn("Server URL %q is relative; set baseUrl to the host serving it", stringField(node, "url"))
+				serverURL = "http://localhost" + serverURL
+			}
+			variables := mapField(node, "variables")
+			entry := server{name: stringField(node, "description")}
+			entry.url = openAPIServerVariablePattern.ReplaceAllStringFunc(serverURL, func(m string) string {
+				variable := m[1 : len(m)-1]
+				if variables[variable] == nil {
+					return m
+				}
+				return "{{" + openAPIVariableName(variable) + "}}"
+			})
+			for _, variable := range sortedKeys(variables) {
+				entry.variables = append(entry.variables, HTTPVariable{
+					Key:   openAPIVariableName(variable),
+					Value: fmt.Sprint(mapField(variables, variable)["default"]),
+				})
+			}
+			servers = append(servers, entry)
+		}
+		if len(servers) == 0 {
+			servers = append(servers, server{url: "http://localhost"})
+		}
+	}
+
+	environments := make([]HTTPEnvironment, 0, len(servers))
+	names := map[string]bool{}
+	for _, entry := range servers {
+		name := title
+		if len(servers) > 1 {
+			label := entry.name
+			if label == "" {
+				label = entry.url
+			}
+			name = title + " - " + label
+		}
+		if names[name] {
+			continue
+		}
+		names[name] = true
+
+		variables := append([]HTTPVariable{{Key: "baseUrl", Value: entry.url}}, entry.variables...)
+		credentials := make([]string, 0, len(s.credentials))
+		for key := range s.credentials {
+			credentials = append(credentials, key)
+		}
+		sort.Strings(credentials)
+		for _, key := range credentials {
+			variables = append(variables, HTTPVariable{Key: key, Secret: s.credentials[key]})
+		}
+		environments = append(environments, HTTPEnvironment{Name: name, Variables: variables})
+	}
+	return environments
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ========== HTTP Request Bodies ==========
+
+// HTTPBody is a structured request body, sent instead of HTTPRequest.Body
+// when set. Type is "raw", "json", "form" (URL-encoded) or "multipart".
+type HTTPBody struct {
+	Type string `json:"type"`
+	// Text is the content of raw and json bodies
+	Text string `json:"text,omitempty"`
+	// ContentType is sent with a raw body unless the request sets one
+	ContentType string          `json:"contentType,omitempty"`
+	Fields      []HTTPBodyField `json:"fields,omitempty"`
+}
+
+// HTTPBodyField is a form field or a multipart part. A part with File set
+// uploads that file, streamed from disk as the request is sent.
+type HTTPBodyField struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	File        string `json:"file,omitempty"`
+	FileName    string `json:"fileName,omitempty"` // defaults to the file's base name
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// resolveBody expands variables in every text of a body
+func (r *variableResolver) resolveBody(body *HTTPBody) (*HTTPBody, error) {
+	resolved := *body
+	var err error
+	if resolved.Text, err = r.resolve(body.Text); err != nil {
+		return nil, err
+	}
+	resolved.Fields = make([]HTTPBodyField, len(body.Fields))
+	for i, field := range body.Fields {
+		for _, s := range []*string{&field.Name, &field.Value, &field.File, &field.FileName, &field.ContentType} {
+			if *s, err = r.resolve(*s); err != nil {
+				return nil, err
+			}
+		}
+		resolved.Fields[i] = field
+	}
+	return &resolved, nil
+}
+
+// resolveBodyFiles maps the file paths of a request's multipart parts
+// through resolvePath
+func resolveBodyFiles(request HTTPRequest, resolvePath func(string) (string, error)) (HTTPRequest, error) {
+	if request.StructuredBody == nil || request.StructuredBody.Type != "multipart" {
+		return request, nil
+	}
+	body := *request.StructuredBody
+	body.Fields = append([]HTTPBodyField(nil), body.Fields...)
+	for i, field := range body.Fields {
+		if field.File == "" {
+			continue
+		}
+		path, err := resolvePath(expandHome(field.File))
+		if err != nil {
+			return request, fmt.Errorf("file part %q: %v", field.Name, err)
+		}
+		body.Fields[i].File = path
+	}
+	request.StructuredBody = &body
+	return request, nil
+}
+
+// resolveUploadPaths makes relative upload paths of a request sent from
+// the editor relative to the http folder
+func (a *App) resolveUploadPaths(request HTTPRequest) (HTTPRequest, error) {
+	httpDir := filepath.Join(a.storagePath, "http")
+	return resolveBodyFiles(request, func(path string) (string, error) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(httpDir, path)
+		}
+		return path, nil
+	})
+}
+
+// flattenBody turns raw, json and form bodies into Body text, adding a
+// Content-Type header if the request has none. Multipart bodies are left
+// structured so their files can be streamed.
+func flattenBody(request HTTPRequest) (HTTPRequest, error) {
+	body := request.StructuredBody
+	if body == nil || body.Type == "multipart" {
+		return request, nil
+	}
+
+	contentType := ""
+	switch body.Type {
+	case "", "raw":
+		request.Body = body.Text
+		contentType = body.ContentType
+	case "json":
+		if strings.TrimSpace(body.Text) != "" && !json.Valid([]byte(body.Text)) {
+			return request, fmt.Errorf("body is not valid JSON")
+		}
+		request.Body = body.Text
+		contentType = "application/json"
+	case "form":
+		pairs := make([]string, 0, len(body.Fields))
+		for _, field := range body.Fields {
+			pairs = append(pairs, url.QueryEscape(field.Name)+"="+url.QueryEscape(field.Value))
+		}
+		request.Body = strings.Join(pairs, "&")
+		contentType = "application/x-www-form-urlencoded"
+	default:
+		return request, fmt.Errorf("unknown body type %q", body.Type)
+	}
+
+	headers := make(map[string]string, len(request.Headers)+1)
+	for key, value := range request.Headers {
+		headers[key] = value
+	}
+	if _, ok := lookupHeader(headers, "Content-Type"); !ok && contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	request.Headers = headers
+	request.StructuredBody = nil
+	return request, nil
+}
+
+// requestPayload is a request body that can be opened more than once, so
+// redirects that repeat the body work
+type requestPayload struct {
+	open        func() (io.ReadCloser, error)
+	length      int64
+	contentType string // set for multipart bodies
+}
+
+// newRequestPayload prepares the body of a flattened request. Multipart
+// bodies are laid out ahead of time so their length is known, but files
+// are only opened as the body is read.
+func newRequestPayload(request HTTPRequest) (*requestPayload, error) {
+	body := request.StructuredBody
+	if body == nil {
+		text := request.Body
+		return &requestPayload{
+			open:   func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(text)), nil },
+			length: int64(len(text)),
+		}, nil
+	}
+
+	// Write the text of the body with a multipart.Writer, cutting it
+	// wherever a file's content goes
+	var segments []interface{} // []byte or file path
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	var length int64
+	for _, field := range body.Fields {
+		header := make(textproto.MIMEHeader)
+		if field.File == "" {
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(field.Name)))
+			if field.ContentType != "" {
+				header.Set("Content-Type", field.ContentType)
+			}
+			part, _ := writer.CreatePart(header)
+			io.WriteString(part, field.Value)
+			continue
+		}
+
+		info, err := os.Stat(field.File)
+		if err != nil {
+			return nil, fmt.Errorf("file part %q: %v", field.Name, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("file part %q: %s is a directory", field.Name, field.File)
+		}
+		fileName := field.FileName
+		if fileName == "" {
+			fileName = filepath.Base(field.File)
+		}
+		contentType := field.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(fileName))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(field.Name), escapeQuotes(fileName)))
+		header.Set("Content-Type", contentType)
+		writer.CreatePart(header)
+
+		segments = append(segments, append([]byte(nil), buf.Bytes()...), field.File)
+		length += int64(buf.Len()) + info.Size()
+		buf.Reset()
+	}
+	writer.Close()
+	segments = append(segments, append([]byte(nil), buf.Bytes()...))
+	length += int64(buf.Len())
+
+	return &requestPayload{
+		open: func() (io.ReadCloser, error) {
+			return &multipartReader{segments: segments}, nil
+		},
+		length:      length,
+		contentType: writer.FormDataContentType(),
+	}, nil
+}
+
+// attach sets the payload as the body of req
+func (p *requestPayload) attach(req *http.Request) error {
+	if p.contentType != "" {
+		req.Header.Set("Content-Type", p.contentType)
+	}
+	req.ContentLength = p.length
+	if p.length == 0 {
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		return nil
+	}
+
+	body, err := p.open()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	req.GetBody = p.open
+	return nil
+}
+
+// multipartReader reads segments of text and files in turn, opening each
+// file when it is reached
+type multipartReader struct {
+	segments []interface{}
+	current  io.Reader
+	file     *os.File
+}
+
+func (m *multipartReader) Read(p []byte) (int, error) {
+	for {
+		if m.current == nil {
+			if len(m.segments) == 0 {
+				return 0, io.EOF
+			}
+			switch segment := m.segments[0].(type) {
+			case []byte:
+				m.current = bytes.NewReader(segment)
+			case string:
+				file, err := os.Open(segment)
+				if err != nil {
+					return 0, err
+				}
+				m.file, m.current = file, file
+			}
+			m.segments = m.segments[1:]
+		}
+
+		n, err := m.current.Read(p)
+		if err == io.EOF {
+			m.closeFile()
+			m.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// Close releases the file being read, if any
+func (m *multipartReader) Close() error {
+	m.closeFile()
+	m.segments = nil
+	return nil
+}
+
+func (m *multipartReader) closeFile() {
+	if m.file != nil {
+		m.file.Close()
+		m.file = nil
+	}
+}
+
+// escapeQuotes escapes a Content-Disposition parameter the way
+// mime/multipart does
+func escapeQuotes(s string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(s)
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ========== HTTP Collection Runner ==========
+
+// Events emitted while a collection runs
+const (
+	collectionStartEvent    = "http:collection:start"
+	collectionProgressEvent = "http:collection:progress"
+	collectionDoneEvent     = "http:collection:done"
+)
+
+// CollectionRunRequest selects the folder and environment to run
+type CollectionRunRequest struct {
+	// Folder inside the http storage tree, absolute or relative to the
+	// storage directory like file IDs ("http/api"); empty runs the whole
+	// tree
+	Folder        string `json:"folder"`
+	Environment   string `json:"environment"`
+	StopOnFailure bool   `json:"stopOnFailure"`
+}
+
+// CollectionRequestResult is the outcome of one request in a run
+type CollectionRequestResult struct {
+	File       string                `json:"file"` // relative to storage, like FileItem.ID
+	Name       string                `json:"name"`
+	Method     string                `json:"method"`
+	URL        string                `json:"url"`
+	StatusCode int                   `json:"statusCode"`
+	Duration   int64                 `json:"duration"` // milliseconds
+	Passed     []HTTPAssertionResult `json:"passed"`
+	Failed     []HTTPAssertionResult `json:"failed"`
+	Error      string                `json:"error,omitempty"`
+	Success    bool                  `json:"success"`
+}
+
+// CollectionRunSummary aggregates a run
+type CollectionRunSummary struct {
+	Folder      string                    `json:"folder"`
+	Environment string                    `json:"environment"`
+	StartedAt   time.Time                 `json:"startedAt"`
+	Duration    int64                     `json:"duration"` // milliseconds
+	Total       int                       `json:"total"`
+	Passed      int                       `json:"passed"`
+	Failed      int                       `json:"failed"`
+	Errors      int                       `json:"errors"`
+	Stopped     bool                      `json:"stopped,omitempty"`
+	Results     []CollectionRequestResult `json:"results"`
+	Error       string                    `json:"error,omitempty"`
+}
+
+// CollectionRunProgress is the payload of progress events
+type CollectionRunProgress struct {
+	Index  int                     `json:"index"`
+	Total  int                     `json:"total"`
+	Result CollectionRequestResult `json:"result"`
+}
+
+// RunHTTPCollection runs every request of every .http file below a folder,
+// files in lexical path order and requests in file order. Each file keeps
+// its own session, as when it is run by hand. Secret values are masked in
+// the results, and so in the reports.
+func (a *App) RunHTTPCollection(request CollectionRunRequest) CollectionRunSummary {
+	summary := CollectionRunSummary{
+		Folder:      request.Folder,
+		Environment: request.Environment,
+		StartedAt:   time.Now(),
+		Results:     []CollectionRequestResult{},
+	}
+
+	files, err := a.collectionFiles(request.Folder)
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+	secrets, err := a.secretReplacer()
+	if err != nil {
+		summary.Error = fmt.Sprintf("Failed to load environments: %v", err)
+		return summary
+	}
+	for _, file := range files {
+		summary.Total += len(file.Requests)
+	}
+	a.emitEvent(collectionStartEvent, summary)
+
+	index := 0
+	for _, file := range files {
+		relPath, _ := filepath.Rel(a.storagePath, file.Path)
+		resolver, err := a.newFileVariableResolver(file, request.Environment)
+		if err != nil {
+			summary.Error = fmt.Sprintf("Environment error: %v", err)
+			break
+		}
+
+		for _, fileRequest := range file.Requests {
+			resolved, response := a.runHTTPFileRequest(file, fileRequest, resolver)
+			result := CollectionRequestResult{
+				File:       relPath,
+				Name:       fileRequest.Name,
+				Method:     resolved.Method,
+				URL:        secrets.Replace(resolved.URL),
+				StatusCode: response.StatusCode,
+				Duration:   response.Duration,
+				Passed:     response.Passed,
+				Failed:     response.Failed,
+				Error:      secrets.Replace(response.Error),
+				Success:    response.Error == "" && len(response.Failed) == 0,
+			}
+			switch {
+			case result.Error != "":
+				summary.Errors++
+			case !result.Success:
+				summary.Failed++
+			default:
+				summary.Passed++
+			}
+			summary.Results = append(summary.Results, result)
+
+			index++
+			a.emitEvent(collectionProgressEvent, CollectionRunProgress{Index: index, Total: summary.Total, Result: result})
+
+			if !result.Success && request.StopOnFailure {
+				summary.Stopped = true
+				break
+			}
+		}
+		if summary.Stopped {
+			break
+		}
+	}
+
+	summary.Duration = time.Since(summary.StartedAt).Milliseconds()
+	a.emitEvent(collectionDoneEvent, summary)
+	return summary
+}
+
+// collectionFiles parses the .http and .rest files below a folder,
+// skipping hidden files and folders
+func (a *App) collectionFiles(folder string) ([]*HTTPFile, error) {
+	root := filepath.Join(a.storagePath, "http")
+	if folder != "" {
+		if !filepath.IsAbs(folder) {
+			folder = filepath.Join(a.storagePath, folder)
+		}
+		root = folder
+	}
+	root, err := a.resolveStoragePath(root)
+	if err != nil {
+		return nil, err
+	}
+	httpRoot, _ := filepath.Abs(filepath.Join(a.storagePath, "http"))
+	if rel, err := filepath.Rel(httpRoot, root); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("Folder %q is not in the http folder", folder)
+	}
+
+	var files []*HTTPFile
+	// WalkDir visits entries in lexical order, which keeps runs stable
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if d.IsDir() || (ext != ".http" && ext != ".rest") {
+			return nil
+		}
+		file, err := a.loadHTTPFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read collection: %v", err)
+	}
+	return files, nil
+}
+
+// ExportCollectionReport renders a run summary as "junit", "json" or "html"
+func (a *App) ExportCollectionReport(summary CollectionRunSummary, format string) JSONFormatResponse {
+	data, err := renderCollectionReport(summary, format)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+	return JSONFormatResponse{Result: string(data)}
+}
+
+// SaveCollectionReport renders a run summary and saves it through the
+// native save dialog. It returns the chosen path, empty if cancelled.
+func (a *App) SaveCollectionReport(summary CollectionRunSummary, format string) (string, error) {
+	data, err := renderCollectionReport(summary, format)
+	if err != nil {
+		return "", err
+	}
+
+	ext := map[string]string{"junit": "xml", "xml": "xml", "json": "json", "html": "html"}[strings.ToLower(format)]
+	file, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Save Test Report",
+		DefaultFilename: fmt.Sprintf("http-report-%s.%s", summary.StartedAt.Format("2006-01-02-150405"), ext),
+		Filters: []runtime.FileFilter{
+			{DisplayName: fmt.Sprintf("%s Files (*.%s)", strings.ToUpper(ext), ext), Pattern: "*." + ext},
+		},
+	})
+	if err != nil || file == "" {
+		return "", err
+	}
+	return file, os.WriteFile(file, data, 0644)
+}
+
+// renderCollectionReport formats a summary for export
+func renderCollectionReport(summary CollectionRunSummary, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "junit", "xml":
+		return junitReport(summary)
+	case "json":
+		return json.MarshalIndent(summary, "", "  ")
+	case "html":
+		return htmlReport(summary)
+	}
+	return nil, fmt.Errorf("unsupported report format %q", format)
+}
+
+// JUnit XML, as read by CI servers
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitReport groups results into one test suite per file
+func junitReport(summary CollectionRunSummary) ([]byte, error) {
+	report := junitTestSuites{
+		Name:     "http",
+		Tests:    len(summary.Results),
+		Failures: summary.Failed,
+		Errors:   summary.Errors,
+		Time:     junitSeconds(summary.Duration),
+	}
+	if summary.Folder != "" {
+		report.Name = summary.Folder
+	}
+
+	suites := map[string]int{}
+	durations := map[string]int64{}
+	for _, result := range summary.Results {
+		idx, ok := suites[result.File]
+		if !ok {
+			idx = len(report.Suites)
+			suites[result.File] = idx
+			report.Suites = append(report.Suites, junitTestSuite{
+				Name:      result.File,
+				Timestamp: summary.StartedAt.Format("2006-01-02T15:04:05"),
+			})
+		}
+		suite := &report.Suites[idx]
+
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: strings.TrimSuffix(filepath.ToSlash(result.File), filepath.Ext(result.File)),
+			Time:      junitSeconds(result.Duration),
+		}
+		if result.Error != "" {
+			testCase.Error = &junitMessage{Message: result.Error, Text: result.Error}
+			suite.Errors++
+		} else if len(result.Failed) > 0 {
+			var lines []string
+			for _, failed := range result.Failed {
+				lines = append(lines, fmt.Sprintf("%s: %s", failed.Description, failed.Message))
+			}
+			testCase.Failure = &junitMessage{
+				Message: fmt.Sprintf("%d assertion(s) failed", len(result.Failed)),
+				Text:    strings.Join(lines, "\n"),
+			}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+		durations[result.File] += result.Duration
+	}
+	for i := range report.Suites {
+		report.Suites[i].Time = junitSeconds(durations[report.Suites[i].Name])
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// junitSeconds formats milliseconds as JUnit's decimal seconds
+func junitSeconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+// htmlReportTemplate is a standalone page with inline styles
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>HTTP Test Report</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, sans-serif; margin: 24px; color: #1d1d1f; }
+h1 { font-size: 20px; margin-bottom: 4px; }
+.meta { color: #6e6e73; margin-bottom: 16px; }
+.stats span { display: inline-block; margin-right: 16px; font-weight: 600; }
+.pass { color: #1a7f37; } .fail { color: #cf222e; } .error { color: #9a6700; }
+table { border-collapse: collapse; width: 100%; margin-top: 16px; font-size: 13px; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #e5e5ea; vertical-align: top; }
+th { background: #f5f5f7; }
+td.url { font-family: Menlo, monospace; word-break: break-all; }
+ul { margin: 0; padding-left: 16px; }
+</style>
+</head>
+<body>
+<h1>HTTP Test Report</h1>
+<div class="meta">{{if .Folder}}{{.Folder}} · {{end}}{{if .Environment}}{{.Environment}} · {{end}}{{.StartedAt.Format "2006-01-02 15:04:05"}} · {{.Duration}} ms</div>
+<div class="stats">
+<span>{{.Total}} requests</span>
+<span class="pass">{{.Passed}} passed</span>
+<span class="fail">{{.Failed}} failed</span>
+<span class="error">{{.Errors}} errors</span>
+</div>
+<table>
+<tr><th>File</th><th>Request</th><th>URL</th><th>Status</th><th>Time</th><th>Result</th></tr>
+{{range .Results}}<tr>
+<td>{{.File}}</td>
+<td>{{.Name}}</td>
+<td class="url">{{.Method}} {{.URL}}</td>
+<td>{{if .StatusCode}}{{.StatusCode}}{{end}}</td>
+<td>{{.Duration}} ms</td>
+<td>{{if .Error}}<span class="error">{{.Error}}</span>{{else if .Success}}<span class="pass">passed ({{len .Passed}})</span>{{else}}<span class="fail">failed</span>
+<ul>{{range .Failed}}<li>{{.Description}}: {{.Message}}</li>{{end}}</ul>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// htmlReport renders the standalone HTML report
+func htmlReport(summary CollectionRunSummary) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, summary); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ========== Streaming Responses ==========
+
+// httpStreamEvent carries HTTPStreamMessage values to the frontend
+const httpStreamEvent = "http:stream"
+
+// defaultSSERetry is the reconnection delay until the server sets one
+const defaultSSERetry = 3 * time.Second
+
+// maxSSEFailures ends an event stream once this many reconnection
+// attempts in a row fail to connect
+const maxSSEFailures = 5
+
+// streamChunkSize is the most read from a stream at once
+const streamChunkSize = 32 * 1024
+
+// HTTPServerSentEvent is an event parsed from a text/event-stream body.
+// ID is the last event ID when the event arrived, and Retry the
+// reconnection delay the event set, in milliseconds.
+type HTTPServerSentEvent struct {
+	ID    string `json:"id,omitempty"`
+	Event string `json:"event"`
+	Data  string `json:"data"`
+	Retry int    `json:"retry,omitempty"`
+}
+
+// HTTPStreamMessage is pushed to the frontend for a streamed request.
+// Kind is "open" when a connection's headers arrive, "chunk" for body
+// data, "event" for a server-sent event and "reconnecting" before an
+// event stream is resumed.
+type HTTPStreamMessage struct {
+	ID         string `json:"id"` // the request's ID
+	Seq        int    `json:"seq"`
+	Kind       string `json:"kind"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Chunk      string `json:"chunk,omitempty"`
+	// Binary chunks are sent in ChunkBase64 instead of Chunk
+	ChunkBase64 string               `json:"chunkBase64,omitempty"`
+	Event       *HTTPServerSentEvent `json:"event,omitempty"`
+	Attempt     int                  `json:"attempt,omitempty"`
+	Delay       int64                `json:"delay,omitempty"` // milliseconds before reconnecting
+	Error       string               `json:"error,omitempty"`
+	Time        int64                `json:"time"` // milliseconds since the request started
+}
+
+// StopHTTPStream ends a streamed request. Unlike CancelHTTPRequest, the
+// response is not an error: it holds what was received, with Stopped set.
+func (a *App) StopHTTPStream(id string) FileSystemResponse {
+	a.inFlightMu.Lock()
+	stop, ok := a.streams[id]
+	a.inFlightMu.Unlock()
+	if !ok {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Stream %q is not running", id)}
+	}
+	stop()
+	return FileSystemResponse{Success: true}
+}
+
+// streamHTTPRequest sends a request with Stream set. Event streams are
+// resumed with Last-Event-ID when the connection ends, until the server
+// answers 204 or another status, StopHTTPStream is called, or
+// maxSSEFailures reconnections in a row fail. The response is that of the
+// last connection, with the body of every connection.
+func (a *App) streamHTTPRequest(ctx context.Context, request HTTPRequest, reporter *httpStatusReporter) HTTPResponse {
+	stopCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	a.inFlightMu.Lock()
+	if a.streams == nil {
+		a.streams = make(map[string]context.CancelFunc)
+	}
+	a.streams[request.ID] = stop
+	a.inFlightMu.Unlock()
+	defer func() {
+		a.inFlightMu.Lock()
+		delete(a.streams, request.ID)
+		a.inFlightMu.Unlock()
+	}()
+
+	stream := &httpStream{
+		app:         a,
+		id:          request.ID,
+		start:       reporter.start,
+		lastEventID: request.LastEventID,
+		retry:       defaultSSERetry,
+	}
+	var response HTTPResponse
+	reconnects, failures := 0, 0
+	for {
+		if stream.lastEventID != "" {
+			request.Headers = withHeader(request.Headers, "Last-Event-ID", stream.lastEventID)
+		}
+		stream.sse = false
+		attempt := a.doHTTPRequest(stopCtx, request, reporter, stream)
+		if reconnects == 0 || stream.sse {
+			response = attempt
+		}
+
+		if stopCtx.Err() != nil || (!stream.sse && reconnects == 0) {
+			break
+		}
+		if !stream.sse {
+			// The server declined to resume the stream, or could not be
+			// reached
+			if attempt.StatusCode == http.StatusNoContent {
+				response.Error = ""
+				break
+			}
+			if attempt.StatusCode != 0 {
+				response.Error = fmt.Sprintf("Failed to resume the stream: %s", attempt.Status)
+				break
+			}
+			if failures++; failures >= maxSSEFailures {
+				response.Error = attempt.Error
+				break
+			}
+		} else {
+			failures = 0
+		}
+
+		reconnects++
+		stream.emit(HTTPStreamMessage{
+			Kind:    "reconnecting",
+			Attempt: reconnects,
+			Delay:   stream.retry.Milliseconds(),
+			Error:   attempt.Error,
+		})
+		select {
+		case <-time.After(stream.retry):
+		case <-stopCtx.Done():
+		}
+		if stopCtx.Err() != nil {
+			break
+		}
+	}
+
+	if ctx.Err() == nil && stopCtx.Err() != nil {
+		response.Stopped = true
+		response.Cancelled = false
+		response.Error = ""
+	}
+	body := stream.body.Bytes()
+	response.Body, response.BodyBase64, response.Binary = "", "", false
+	response.Truncated = stream.truncated
+	response.Size = int64(len(body))
+	setResponseBody(&response, response.Headers["Content-Type"], body)
+	response.Reconnects = reconnects
+	response.LastEventID = stream.lastEventID
+	response.Duration = time.Since(stream.start).Milliseconds()
+	return response
+}
+
+// withHeader returns a copy of headers with name set to value, replacing
+// it in any case
+func withHeader(headers map[string]string, name string, value string) map[string]string {
+	copied := make(map[string]string, len(headers)+1)
+	for key, v := range headers {
+		if !strings.EqualFold(key, name) {
+			copied[key] = v
+		}
+	}
+	copied[name] = value
+	return copied
+}
+
+// httpStream passes the body of a streamed request to the frontend and
+// keeps it, across the connections of an event stream
+type httpStream struct {
+	app   *App
+	id    string
+	start time.Time
+	seq   int
+
+	body      bytes.Buffer
+	truncated bool
+
+	// sse is set while the connection is an event stream
+	sse         bool
+	lastEventID string
+	retry       time.Duration
+}
+
+// emit numbers a message and sends it to the frontend
+func (s *httpStream) emit(message HTTPStreamMessage) {
+	s.seq++
+	message.ID = s.id
+	message.Seq = s.seq
+	message.Time = time.Since(s.start).Milliseconds()
+	s.app.emitEvent(httpStreamEvent, message)
+}
+
+// Write keeps up to maxBodySize bytes of the body
+func (s *httpStream) Write(p []byte) (int, error) {
+	if room := maxBodySize - s.body.Len(); len(p) > room {
+		s.body.Write(p[:room])
+		s.truncated = true
+	} else {
+		s.body.Write(p)
+	}
+	return len(p), nil
+}
+
+// read passes a connection's body on as it arrives: as server-sent events
+// for a successful text/event-stream response, otherwise as chunks
+func (s *httpStream) read(resp *http.Response, body io.Reader) error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	s.sse = resp.StatusCode == http.StatusOK && strings.EqualFold(mediaType, "text/event-stream")
+	s.emit(HTTPStreamMessage{Kind: "open", StatusCode: resp.StatusCode})
+
+	body = io.TeeReader(body, s)
+	if s.sse {
+		return s.readEvents(body)
+	}
+	return s.readChunks(body)
+}
+
+// readChunks emits data as it is read. A character split between reads
+// is held for the next chunk, so text chunks stay valid UTF-8.
+func (s *httpStream) readChunks(body io.Reader) error {
+	buf := make([]byte, streamChunkSize)
+	var pending []byte
+	for {
+		n, err := body.Read(buf)
+		data := append(pending, buf[:n]...)
+		pending = nil
+		if err == nil {
+			if cut := incompleteRuneStart(data); cut < len(data) {
+				pending = append([]byte(nil), data[cut:]...)
+				data = data[:cut]
+			}
+		}
+		if len(data) > 0 {
+			if utf8.Valid(data) {
+				s.emit(HTTPStreamMessage{Kind: "chunk", Chunk: string(data)})
+			} else {
+				s.emit(HTTPStreamMessage{Kind: "chunk", ChunkBase64: base64.StdEncoding.EncodeToString(data)})
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// incompleteRuneStart returns where an incomplete UTF-8 character at the
+// end of data starts, or len(data) if there is none
+func incompleteRuneStart(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// readEvents parses an event stream as the HTML standard describes and
+// emits each event as it is dispatched. An event the connection cuts off
+// is dropped, and so is its ID: the last event ID only changes when an
+// event is dispatched, so a reconnection asks for the dropped event again.
+func (s *httpStream) readEvents(body io.Reader) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, streamChunkSize), maxBodySize)
+	scanner.Split(scanSSELines)
+
+	var event HTTPServerSentEvent
+	var data strings.Builder
+	id := s.lastEventID
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if line == "" {
+			// A blank line dispatches the event, if it has data. Its ID
+			// counts even if it has none.
+			s.lastEventID = id
+			if data.Len() > 0 {
+				dispatched := event
+				dispatched.ID = s.lastEventID
+				dispatched.Data = strings.TrimSuffix(data.String(), "\n")
+				if dispatched.Event == "" {
+					dispatched.Event = "message"
+				}
+				s.emit(HTTPStreamMessage{Kind: "event", Event: &dispatched})
+			}
+			event = HTTPServerSentEvent{}
+			data.Reset()
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "": // a comment, often sent to keep the connection open
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				id = value
+			}
+		case "retry":
+			if value != "" && strings.Trim(value, "0123456789") == "" {
+				if ms, err := strconv.Atoi(value); err == nil {
+					s.retry = time.Duration(ms) * time.Millisecond
+					event.Retry = ms
+				}
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// scanSSELines splits an event stream into lines ended by CRLF, LF or CR
+func scanSSELines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		switch {
+		case i+1 < len(data) && data[i+1] == '\n':
+			return i + 2, data[:i], nil
+		case i+1 < len(data) || atEOF:
+			return i + 1, data[:i], nil
+		}
+		// Wait to see whether a LF follows the CR
+		return 0, nil, nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStreamResumesFromLastDispatchedEvent(t *testing.T) {
+	var mu sync.Mutex
+	var resumedFrom []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		resumedFrom = append(resumedFrom, r.Header.Get("Last-Event-ID"))
+		attempt := len(resumedFrom)
+		mu.Unlock()
+
+		if attempt > 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		// The connection drops in the middle of the second event
+		fmt.Fprint(w, "retry: 10\nid: 1\ndata: first\n\nid: 2\ndata: cut off")
+	}))
+	defer server.Close()
+
+	a := &App{storagePath: t.TempDir()}
+	response := a.executeHTTPRequest(HTTPRequest{Method: "GET", URL: server.URL, Stream: true})
+	if response.Error != "" {
+		t.Fatalf("stream failed: %s", response.Error)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(resumedFrom) != 2 || resumedFrom[0] != "" || resumedFrom[1] != "1" {
+		t.Fatalf("Last-Event-ID per connection = %q, want [\"\" \"1\"]", resumedFrom)
+	}
+	if response.Reconnects != 1 || response.LastEventID != "1" {
+		t.Errorf("reconnects = %d, last event ID = %q, want 1 and \"1\"", response.Reconnects, response.LastEventID)
+	}
+	if !strings.Contains(response.Body, "data: first") {
+		t.Errorf("body = %q, want the events received", response.Body)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// ========== HTTP Timing ==========
+
+// HTTPTiming breaks a request down into phases, in milliseconds. When
+// redirects are followed the phases describe the last hop and Total
+// covers the whole exchange.
+type HTTPTiming struct {
+	DNSLookup       float64 `json:"dnsLookup"`
+	TCPConnect      float64 `json:"tcpConnect"`
+	TLSHandshake    float64 `json:"tlsHandshake"`
+	TimeToFirstByte float64 `json:"timeToFirstByte"` // from connection ready to first response byte
+	ContentTransfer float64 `json:"contentTransfer"` // from first byte to end of body
+	Total           float64 `json:"total"`
+
+	RemoteAddr       string `json:"remoteAddr,omitempty"`
+	Protocol         string `json:"protocol,omitempty"`
+	TLSVersion       string `json:"tlsVersion,omitempty"`
+	CipherSuite      string `json:"cipherSuite,omitempty"`
+	ConnectionReused bool   `json:"connectionReused"`
+}
+
+// requestTrace collects httptrace events. Dials may run on other
+// goroutines, hence the mutex.
+type requestTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	firstByte    time.Time
+	remoteAddr   string
+	reused       bool
+}
+
+// newRequestTrace starts timing at start
+func newRequestTrace(start time.Time) *requestTrace {
+	return &requestTrace{start: start}
+}
+
+// clientTrace returns the hooks to attach to a request context
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	record := func(field *time.Time, keepFirst bool) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if keepFirst && !field.IsZero() {
+			return
+		}
+		*field = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			// Each redirect hop starts over
+			t.mu.Lock()
+			t.dnsStart, t.dnsDone, t.connectStart, t.connectDone = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+			t.tlsStart, t.tlsDone, t.gotConn, t.firstByte = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+			t.mu.Unlock()
+		},
+		DNSStart:     func(httptrace.DNSStartInfo) { record(&t.dnsStart, true) },
+		DNSDone:      func(httptrace.DNSDoneInfo) { record(&t.dnsDone, false) },
+		ConnectStart: func(string, string) { record(&t.connectStart, true) },
+		ConnectDone:  func(string, string, error) { record(&t.connectDone, false) },
+		TLSHandshakeStart: func() {
+			record(&t.tlsStart, true)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(&t.tlsDone, false)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = time.Now()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		GotFirstResponseByte: func() { record(&t.firstByte, false) },
+	}
+}
+
+// timing computes the phases up to end. resp may be nil if the request
+// failed.
+func (t *requestTrace) timing(end time.Time, resp *http.Response) *HTTPTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timing := &HTTPTiming{
+		DNSLookup:        phaseMillis(t.dnsStart, t.dnsDone),
+		TCPConnect:       phaseMillis(t.connectStart, t.connectDone),
+		TLSHandshake:     phaseMillis(t.tlsStart, t.tlsDone),
+		TimeToFirstByte:  phaseMillis(t.gotConn, t.firstByte),
+		ContentTransfer:  phaseMillis(t.firstByte, end),
+		Total:            phaseMillis(t.start, end),
+		RemoteAddr:       t.remoteAddr,
+		ConnectionReused: t.reused,
+	}
+	if resp != nil {
+		timing.Protocol = resp.Proto
+		if resp.TLS != nil {
+			timing.TLSVersion = tls.VersionName(resp.TLS.Version)
+			timing.CipherSuite = tls.CipherSuiteName(resp.TLS.CipherSuite)
+		}
+	}
+	return timing
+}
+
+// phaseMillis returns the time between two events in milliseconds, or 0
+// if either didn't happen
+func phaseMillis(from time.Time, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	// Keep microsecond precision; local requests take well under 1ms
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ========== HTTP Transport Settings ==========
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultReadTimeout    = 30 * time.Second
+	defaultMaxRedirects   = 10
+)
+
+// HTTPTransportSettings configures how requests are sent. Global settings
+// live in the app config; a request's own settings override the fields it
+// sets. Pointer fields distinguish "off" from "not set".
+type HTTPTransportSettings struct {
+	ConnectTimeout int `json:"connectTimeout,omitempty"` // milliseconds, dial and TLS handshake
+	// ReadTimeout, in milliseconds, bounds the wait for the response
+	// headers and then for each read of the body, so long downloads go on
+	// while data keeps coming
+	ReadTimeout        int    `json:"readTimeout,omitempty"`
+	FollowRedirects    *bool  `json:"followRedirects,omitempty"`
+	MaxRedirects       int    `json:"maxRedirects,omitempty"`
+	InsecureSkipVerify *bool  `json:"insecureSkipVerify,omitempty"`
+	CACertPath         string `json:"caCertPath,omitempty"`     // PEM bundle added to the system roots
+	ClientCertPath     string `json:"clientCertPath,omitempty"` // PEM certificate for mTLS
+	ClientKeyPath      string `json:"clientKeyPath,omitempty"`  // PEM key, defaults to ClientCertPath
+	// Proxy is an http://, https:// or socks5:// URL; "direct" bypasses
+	// the system proxy
+	Proxy string `json:"proxy,omitempty"`
+	// HTTPVersion is "1.1", "2" or empty to negotiate. "2" fails against
+	// servers that do not offer HTTP/2 over TLS; cleartext HTTP/2 (h2c) is
+	// not supported, so it needs https:// URLs, and it cannot go through
+	// an https:// proxy.
+	HTTPVersion      string `json:"httpVersion,omitempty"`
+	DisableKeepAlive *bool  `json:"disableKeepAlive,omitempty"`
+}
+
+// HTTPRedirect is one hop of a followed redirect chain
+type HTTPRedirect struct {
+	StatusCode int    `json:"statusCode"`
+	URL        string `json:"url"`
+	Location   string `json:"location"`
+}
+
+// GetHTTPSettings returns the global transport settings
+func (a *App) GetHTTPSettings() HTTPTransportSettings {
+	return a.loadConfig().HTTPSettings
+}
+
+// SaveHTTPSettings validates and stores the global transport settings
+func (a *App) SaveHTTPSettings(settings HTTPTransportSettings) FileSystemResponse {
+	if _, err := newHTTPTransport(settings); err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+
+	config := a.loadConfig()
+	config.HTTPSettings = settings
+	if err := a.saveConfig(config); err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Failed to save settings: %v", err)}
+	}
+
+	// Drop cached transports so new TLS files and proxies take effect
+	a.transportMu.Lock()
+	for _, cached := range a.transports {
+		cached.transport.CloseIdleConnections()
+	}
+	a.transports = nil
+	a.transportMu.Unlock()
+	return FileSystemResponse{Success: true}
+}
+
+// merge returns s with the fields set in override replaced
+func (s HTTPTransportSettings) merge(override *HTTPTransportSettings) HTTPTransportSettings {
+	if override == nil {
+		return s
+	}
+	if override.ConnectTimeout > 0 {
+		s.ConnectTimeout = override.ConnectTimeout
+	}
+	if override.ReadTimeout > 0 {
+		s.ReadTimeout = override.ReadTimeout
+	}
+	if override.FollowRedirects != nil {
+		s.FollowRedirects = override.FollowRedirects
+	}
+	if override.MaxRedirects > 0 {
+		s.MaxRedirects = override.MaxRedirects
+	}
+	if override.InsecureSkipVerify != nil {
+		s.InsecureSkipVerify = override.InsecureSkipVerify
+	}
+	if override.CACertPath != "" {
+		s.CACertPath = override.CACertPath
+	}
+	if override.ClientCertPath != "" {
+		s.ClientCertPath = override.ClientCertPath
+		s.ClientKeyPath = override.ClientKeyPath
+	}
+	if override.Proxy != "" {
+		s.Proxy = override.Proxy
+	}
+	if override.HTTPVersion != "" {
+		s.HTTPVersion = override.HTTPVersion
+	}
+	if override.DisableKeepAlive != nil {
+		s.DisableKeepAlive = override.DisableKeepAlive
+	}
+	return s
+}
+
+// forcesHTTP2 reports whether requests must use HTTP/2
+func (s HTTPTransportSettings) forcesHTTP2() bool {
+	return s.HTTPVersion == "2" || s.HTTPVersion == "2.0"
+}
+
+// readTimeout returns the longest wait for the server to send something
+func (s HTTPTransportSettings) readTimeout() time.Duration {
+	if s.ReadTimeout > 0 {
+		return time.Duration(s.ReadTimeout) * time.Millisecond
+	}
+	return defaultReadTimeout
+}
+
+// errReadTimeout is the cause of a request cancelled by its read deadline
+var errReadTimeout = errors.New("read timeout")
+
+// readDeadline cancels a request when the server sends nothing for the
+// read timeout: no response headers, or no body data once they arrived
+type readDeadline struct {
+	timeout time.Duration
+	timer   *time.Timer
+	stopped bool
+	cancel  context.CancelCauseFunc
+}
+
+// withReadDeadline returns a context cancelled with errReadTimeout once
+// the deadline is started, unless it keeps being extended
+func withReadDeadline(ctx context.Context, timeout time.Duration) (context.Context, *readDeadline) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return ctx, &readDeadline{timeout: timeout, cancel: cancel}
+}
+
+// start begins waiting for the server, when the request is sent
+func (d *readDeadline) start() {
+	d.timer = time.AfterFunc(d.timeout, func() { d.cancel(errReadTimeout) })
+}
+
+// stop ends the deadline, for streams once their headers arrived
+func (d *readDeadline) stop() {
+	d.stopped = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+// release stops the deadline and frees its context
+func (d *readDeadline) release() {
+	d.stop()
+	d.cancel(context.Canceled)
+}
+
+// reader extends the deadline each time data is read from r
+func (d *readDeadline) reader(r io.Reader) io.Reader {
+	return readerFunc(func(p []byte) (int, error) {
+		n, err := r.Read(p)
+		if n > 0 && !d.stopped {
+			d.timer.Reset(d.timeout)
+		}
+		return n, err
+	})
+}
+
+// readerFunc adapts a function to io.Reader
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+// checkRedirect records each hop into chain and enforces the redirect
+// policy
+func (s HTTPTransportSettings) checkRedirect(chain *[]HTTPRedirect) func(*http.Request, []*http.Request) error {
+	maxRedirects := s.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	follow := s.FollowRedirects == nil || *s.FollowRedirects
+
+	return func(req *http.Request, via []*http.Request) error {
+		if !follow {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if req.Response != nil {
+			*chain = append(*chain, HTTPRedirect{
+				StatusCode: req.Response.StatusCode,
+				URL:        via[len(via)-1].URL.String(),
+				Location:   req.URL.String(),
+			})
+		}
+		return nil
+	}
+}
+
+// cachedTransport is a shared transport and the state of the TLS files it
+// was built from
+type cachedTransport struct {
+	transport *http.Transport
+	files     string
+}
+
+// transport returns a shared transport for the settings so connections
+// are kept alive between requests. It is rebuilt when the CA bundle or
+// client certificate changes on disk.
+func (a *App) transport(settings HTTPTransportSettings) (*http.Transport, error) {
+	// Timeouts and redirects are applied by the client, not the transport
+	key := settings
+	key.ReadTimeout, key.FollowRedirects, key.MaxRedirects = 0, nil, 0
+	data, _ := json.Marshal(key)
+	files := settings.tlsFileStamps()
+
+	a.transportMu.Lock()
+	defer a.transportMu.Unlock()
+	cached, ok := a.transports[string(data)]
+	if ok && cached.files == files {
+		return cached.transport, nil
+	}
+
+	transport, err := newHTTPTransport(settings)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		cached.transport.CloseIdleConnections()
+	}
+	if a.transports == nil {
+		a.transports = make(map[string]cachedTransport)
+	}
+	a.transports[string(data)] = cachedTransport{transport: transport, files: files}
+	return transport, nil
+}
+
+// tlsFileStamps describes the TLS files by modification time and size
+func (s HTTPTransportSettings) tlsFileStamps() string {
+	var stamps []string
+	for _, path := range []string{s.CACertPath, s.ClientCertPath, s.ClientKeyPath} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(expandHome(path)); err == nil {
+			stamps = append(stamps, fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size()))
+		} else {
+			stamps = append(stamps, "missing")
+		}
+	}
+	return strings.Join(stamps, ",")
+}
+
+// newHTTPTransport builds a transport from the settings
+func newHTTPTransport(settings HTTPTransportSettings) (*http.Transport, error) {
+	connectTimeout := defaultConnectTimeout
+	if settings.ConnectTimeout > 0 {
+		connectTimeout = time.Duration(settings.ConnectTimeout) * time.Millisecond
+	}
+	dialer := &net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   connectTimeout,
+		ExpectContinueTimeout: time.Second,
+		DisableKeepAlives:     settings.DisableKeepAlive != nil && *settings.DisableKeepAlive,
+		// Responses are decompressed by doHTTPRequest
+		DisableCompression: true,
+	}
+
+	switch proxy := strings.TrimSpace(settings.Proxy); proxy {
+	case "":
+	case "direct", "none":
+		transport.Proxy = nil
+	default:
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", proxy)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		case "socks5h":
+			// Go resolves hostnames through SOCKS5 proxies either way
+			proxyURL.Scheme = "socks5"
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := settings.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	switch settings.HTTPVersion {
+	case "":
+	case "2", "2.0":
+		// Offer only h2, and refuse servers that pick HTTP/1.1 anyway. Go
+		// uses the same TLS settings for the connection to an https://
+		// proxy, which would be refused too, so those are rejected first.
+		proxy := transport.Proxy
+		if proxy != nil {
+			transport.Proxy = func(req *http.Request) (*url.URL, error) {
+				proxyURL, err := proxy(req)
+				if err == nil && proxyURL != nil && proxyURL.Scheme == "https" {
+					return nil, errHTTP2Proxy
+				}
+				return proxyURL, err
+			}
+			if proxyURL, err := url.Parse(strings.TrimSpace(settings.Proxy)); err == nil && proxyURL.Scheme == "https" {
+				return nil, errHTTP2Proxy
+			}
+		}
+		transport.TLSClientConfig.NextProtos = []string{"h2"}
+		transport.TLSClientConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if state.NegotiatedProtocol != "h2" {
+				return errors.New("the server does not support HTTP/2")
+			}
+			return nil
+		}
+	case "1.1", "1":
+		// A non-nil empty map disables HTTP/2 negotiation
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	default:
+		return nil, fmt.Errorf("unsupported HTTP version %q", settings.HTTPVersion)
+	}
+	return transport, nil
+}
+
+// errHTTP2Proxy is returned when HTTP/2 is forced through an https:// proxy
+var errHTTP2Proxy = errors.New("HTTP/2 cannot be forced through an https:// proxy; use an http:// proxy or let the version be negotiated")
+
+// tlsConfig loads the CA bundle and client certificate
+func (s HTTPTransportSettings) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: s.InsecureSkipVerify != nil && *s.InsecureSkipVerify,
+	}
+
+	if s.CACertPath != "" {
+		data, err := os.ReadFile(expandHome(s.CACertPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.New("CA bundle contains no PEM certificates")
+		}
+		config.RootCAs = pool
+	}
+
+	if s.ClientCertPath != "" {
+		keyPath := s.ClientKeyPath
+		if keyPath == "" {
+			keyPath = s.ClientCertPath
+		}
+		cert, err := tls.LoadX509KeyPair(expandHome(s.ClientCertPath), expandHome(keyPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// expandHome expands a leading ~ to the home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[1:])
+	}
+	return path
+}
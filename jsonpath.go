@@ -0,0 +1,449 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ========== JSONPath ==========
+
+// evalJSONPath evaluates a JSONPath expression against decoded JSON and
+// returns every matching value. Supported syntax: $, .name, ['name'],
+// [n] (negative counts from the end), [a:b], [*], .*, ..name, unions such
+// as [0,2] and filters like [?(@.status == 'active')] or [?(@.id)].
+func evalJSONPath(doc interface{}, path string) ([]interface{}, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || path == "$" {
+		return []interface{}{doc}, nil
+	}
+	if !strings.HasPrefix(path, "$") {
+		if !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+			path = "." + path
+		}
+		path = "$" + path
+	}
+
+	current := []interface{}{doc}
+	i := 1
+	for i < len(path) {
+		var err error
+		switch {
+		case strings.HasPrefix(path[i:], ".."):
+			i += 2
+			var name string
+			name, i = readJSONPathName(path, i)
+			var descendants []interface{}
+			for _, node := range current {
+				collectDescendants(node, &descendants)
+			}
+			if name == "" && i < len(path) && path[i] == '[' {
+				current = descendants
+				continue
+			}
+			current = selectChild(descendants, name)
+		case path[i] == '.':
+			var name string
+			name, i = readJSONPathName(path, i+1)
+			if name == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty name at %d", path, i)
+			}
+			current = selectChild(current, name)
+		case path[i] == '[':
+			end := matchingBracket(path, i)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: unclosed [", path)
+			}
+			current, err = selectBracket(current, path[i+1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: %v", path, err)
+			}
+			i = end + 1
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q at %d", path, path[i], i)
+		}
+	}
+	return current, nil
+}
+
+// evalJSONPathString evaluates path against a JSON document
+func evalJSONPathString(body string, path string) ([]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("body is not JSON: %v", err)
+	}
+	return evalJSONPath(doc, path)
+}
+
+// jsonValueString renders a JSONPath match: strings as-is, everything
+// else as compact JSON
+func jsonValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// readJSONPathName reads a dotted member name starting at i
+func readJSONPathName(path string, i int) (string, int) {
+	start := i
+	for i < len(path) && path[i] != '.' && path[i] != '[' {
+		i++
+	}
+	return path[start:i], i
+}
+
+// matchingBracket finds the ] closing the [ at start, skipping quotes
+func matchingBracket(path string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// selectChild selects a named member (or every member for "*") of each node
+func selectChild(nodes []interface{}, name string) []interface{} {
+	var result []interface{}
+	for _, node := range nodes {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			if name == "*" {
+				for _, key := range sortedKeys(v) {
+					result = append(result, v[key])
+				}
+			} else if child, ok := v[name]; ok {
+				result = append(result, child)
+			}
+		case []interface{}:
+			if name == "*" {
+				result = append(result, v...)
+			} else if name == "length" {
+				result = append(result, json.Number(strconv.Itoa(len(v))))
+			}
+		}
+	}
+	return result
+}
+
+// selectBracket applies a [...] selector to each node
+func selectBracket(nodes []interface{}, selector string) ([]interface{}, error) {
+	selector = strings.TrimSpace(selector)
+
+	if selector == "*" {
+		return selectChild(nodes, "*"), nil
+	}
+	if strings.HasPrefix(selector, "?") {
+		return filterNodes(nodes, selector)
+	}
+
+	var result []interface{}
+	for _, part := range splitUnion(selector) {
+		part = strings.TrimSpace(part)
+		switch {
+		case len(part) >= 2 && (part[0] == '\'' || part[0] == '"'):
+			result = append(result, selectChild(nodes, part[1:len(part)-1])...)
+		case strings.Contains(part, ":"):
+			bounds := strings.SplitN(part, ":", 2)
+			for _, node := range nodes {
+				arr, ok := node.([]interface{})
+				if !ok {
+					continue
+				}
+				start, end := 0, len(arr)
+				if s := strings.TrimSpace(bounds[0]); s != "" {
+					n, err := strconv.Atoi(s)
+					if err != nil {
+						return nil, fmt.Errorf("invalid slice %q", part)
+					}
+					start = normalizeIndex(n, len(arr))
+				}
+				if e := strings.TrimSpace(strings.SplitN(bounds[1], ":", 2)[0]); e != "" {
+					n, err := strconv.Atoi(e)
+					if err != nil {
+						return nil, fmt.Errorf("invalid slice %q", part)
+					}
+					end = normalizeIndex(n, len(arr))
+				}
+				for i := start; i < end && i < len(arr); i++ {
+					result = append(result, arr[i])
+				}
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector [%s]", part)
+			}
+			for _, node := range nodes {
+				if arr, ok := node.([]interface{}); ok {
+					idx := n
+					if idx < 0 {
+						idx += len(arr)
+					}
+					if idx >= 0 && idx < len(arr) {
+						result = append(result, arr[idx])
+					}
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// normalizeIndex clamps a possibly negative slice bound into [0, length]
+func normalizeIndex(n int, length int) int {
+	if n < 0 {
+		n += length
+	}
+	if n < 0 {
+		return 0
+	}
+	if n > length {
+		return length
+	}
+	return n
+}
+
+// splitUnion splits "a,'b,c',2" on commas outside quotes
+func splitUnion(selector string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ',':
+			parts = append(parts, selector[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+// filterNodes applies ?(@.path op literal) or ?(@.path) to array elements
+// and object members
+func filterNodes(nodes []interface{}, selector string) ([]interface{}, error) {
+	expr := strings.TrimSpace(strings.TrimPrefix(selector, "?"))
+	if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+		return nil, fmt.Errorf("invalid filter %q", selector)
+	}
+	expr = strings.TrimSpace(expr[1 : len(expr)-1])
+
+	left, op, right := expr, "", ""
+	if idx, candidate := findFilterOperator(expr); idx > 0 {
+		left, op, right = strings.TrimSpace(expr[:idx]), candidate, strings.TrimSpace(expr[idx+len(candidate):])
+	}
+	if !strings.HasPrefix(left, "@") {
+		return nil, fmt.Errorf("filter must start with @: %q", expr)
+	}
+
+	var candidates []interface{}
+	for _, node := range nodes {
+		switch v := node.(type) {
+		case []interface{}:
+			candidates = append(candidates, v...)
+		case map[string]interface{}:
+			for _, key := range sortedKeys(v) {
+				candidates = append(candidates, v[key])
+			}
+		}
+	}
+
+	var result []interface{}
+	for _, candidate := range candidates {
+		matches, err := evalJSONPath(candidate, "$"+left[1:])
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if op == "" {
+			result = append(result, candidate)
+			continue
+		}
+		ok, err := compareJSONValues(matches[0], op, parseJSONLiteral(right))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, candidate)
+		}
+	}
+	return result, nil
+}
+
+// filterOperators are the comparisons a filter supports, two-character
+// operators first so "<=" is not read as "<"
+var filterOperators = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+// findFilterOperator returns the position of the first comparison in a
+// filter expression outside quoted literals, or -1 if there is none
+func findFilterOperator(expr string) (int, string) {
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		default:
+			for _, candidate := range filterOperators {
+				if strings.HasPrefix(expr[i:], candidate) {
+					return i, candidate
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+// parseJSONLiteral reads a filter literal: quoted string, number, bool or null
+func parseJSONLiteral(text string) interface{} {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return text[1 : len(text)-1]
+	}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return text
+	}
+	return value
+}
+
+// compareJSONValues compares two JSON values; numbers numerically and
+// everything else by their string form
+func compareJSONValues(actual interface{}, op string, expected interface{}) (bool, error) {
+	if op == "=~" {
+		return matchRegex(jsonValueString(expected), jsonValueString(actual))
+	}
+
+	a, aNum := jsonNumber(actual)
+	b, bNum := jsonNumber(expected)
+	if aNum && bNum {
+		switch op {
+		case "==":
+			return a == b, nil
+		case "!=":
+			return a != b, nil
+		case "<":
+			return a < b, nil
+		case "<=":
+			return a <= b, nil
+		case ">":
+			return a > b, nil
+		case ">=":
+			return a >= b, nil
+		}
+	}
+
+	as, bs := jsonValueString(actual), jsonValueString(expected)
+	switch op {
+	case "==":
+		return as == bs, nil
+	case "!=":
+		return as != bs, nil
+	case "<":
+		return as < bs, nil
+	case "<=":
+		return as <= bs, nil
+	case ">":
+		return as > bs, nil
+	case ">=":
+		return as >= bs, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// matchRegex reports whether s contains a match of pattern
+func matchRegex(pattern string, s string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid regex %q: %v", pattern, err)
+	}
+	return re.MatchString(s), nil
+}
+
+// jsonNumber converts numeric JSON values to float64
+func jsonNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// collectDescendants appends node and everything below it
+func collectDescendants(node interface{}, into *[]interface{}) {
+	*into = append(*into, node)
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			collectDescendants(v[key], into)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectDescendants(child, into)
+		}
+	}
+}
+
+// sortedKeys returns map keys in a stable order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,665 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// ========== JWT Tools ==========
+
+// JWTTimeClaim is a registered time claim rendered for humans
+type JWTTimeClaim struct {
+	Claim    string `json:"claim"`
+	Unix     int64  `json:"unix"`
+	Time     string `json:"time"`
+	Relative string `json:"relative"`
+}
+
+// JWTDecodeResponse is the decoded form of a JWS or JWE token
+type JWTDecodeResponse struct {
+	Type      string         `json:"type"` // "JWS" or "JWE"
+	Algorithm string         `json:"algorithm"`
+	KeyID     string         `json:"keyId,omitempty"`
+	Header    string         `json:"header"`
+	Payload   string         `json:"payload"`
+	Signature string         `json:"signature,omitempty"`
+	Times     []JWTTimeClaim `json:"times,omitempty"`
+	Status    string         `json:"status"` // "valid", "expired", "not yet valid", "no expiry" or "encrypted"
+	Error     string         `json:"error"`
+}
+
+// JWTVerifyRequest carries the token and the key material to check it with.
+// Exactly one of Secret, PublicKey, JWKS or JWKSPath is expected.
+type JWTVerifyRequest struct {
+	Token        string `json:"token"`
+	Secret       string `json:"secret,omitempty"`
+	SecretBase64 bool   `json:"secretBase64,omitempty"`
+	PublicKey    string `json:"publicKey,omitempty"` // PEM public key, certificate or private key
+	JWKS         string `json:"jwks,omitempty"`      // JWKS document content
+	JWKSPath     string `json:"jwksPath,omitempty"`  // JWKS file inside the storage directory
+}
+
+// JWTVerifyResponse reports the outcome of a signature check
+type JWTVerifyResponse struct {
+	Valid     bool   `json:"valid"`
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId,omitempty"`
+	Error     string `json:"error"`
+}
+
+// JWTSignRequest describes a token to be signed. Header may be empty, in
+// which case {"alg": Algorithm, "typ": "JWT"} is used.
+type JWTSignRequest struct {
+	Algorithm    string `json:"algorithm"`
+	Header       string `json:"header,omitempty"`
+	Payload      string `json:"payload"`
+	Secret       string `json:"secret,omitempty"`
+	SecretBase64 bool   `json:"secretBase64,omitempty"`
+	PrivateKey   string `json:"privateKey,omitempty"` // PEM private key
+}
+
+// DecodeJWT splits a token into its segments and decodes header and payload
+func (a *App) DecodeJWT(token string) JWTDecodeResponse {
+	parts, err := splitJWT(token)
+	if err != nil {
+		return JWTDecodeResponse{Error: err.Error()}
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return JWTDecodeResponse{Error: fmt.Sprintf("Invalid header encoding: %v", err)}
+	}
+	var header map[string]interface{}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return JWTDecodeResponse{Error: fmt.Sprintf("Invalid header JSON: %v", err)}
+	}
+
+	result := JWTDecodeResponse{
+		Header: indentJSONBytes(headerBytes),
+	}
+	result.Algorithm, _ = header["alg"].(string)
+	result.KeyID, _ = header["kid"].(string)
+
+	// JWE compact serialization has five segments; the payload is encrypted
+	if len(parts) == 5 {
+		result.Type = "JWE"
+		result.Status = "encrypted"
+		if enc, ok := header["enc"].(string); ok {
+			result.Payload = fmt.Sprintf("Encrypted with %s (%d bytes of ciphertext)", enc, base64.RawURLEncoding.DecodedLen(len(parts[3])))
+		}
+		return result
+	}
+
+	result.Type = "JWS"
+	result.Signature = parts[2]
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return JWTDecodeResponse{Error: fmt.Sprintf("Invalid payload encoding: %v", err)}
+	}
+
+	var claims map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payloadBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		// Non-JSON payloads are legal for JWS, show them as text
+		result.Payload = string(payloadBytes)
+		result.Status = "no expiry"
+		return result
+	}
+	result.Payload = indentJSONBytes(payloadBytes)
+	result.Times, result.Status = jwtTimeClaims(claims, time.Now())
+
+	return result
+}
+
+// VerifyJWT checks the token signature against a secret, a PEM key or a JWKS
+func (a *App) VerifyJWT(request JWTVerifyRequest) JWTVerifyResponse {
+	parts, err := splitJWT(request.Token)
+	if err != nil {
+		return JWTVerifyResponse{Error: err.Error()}
+	}
+	if len(parts) != 3 {
+		return JWTVerifyResponse{Error: "Only JWS tokens can be verified"}
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return JWTVerifyResponse{Error: fmt.Sprintf("Invalid header encoding: %v", err)}
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return JWTVerifyResponse{Error: fmt.Sprintf("Invalid header JSON: %v", err)}
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return JWTVerifyResponse{Algorithm: header.Alg, Error: fmt.Sprintf("Invalid signature encoding: %v", err)}
+	}
+
+	keys, err := a.jwtVerificationKeys(request, header.Kid)
+	if err != nil {
+		return JWTVerifyResponse{Algorithm: header.Alg, KeyID: header.Kid, Error: err.Error()}
+	}
+
+	signingInput := []byte(parts[0] + "." + parts[1])
+	var lastErr error
+	for _, key := range keys {
+		if lastErr = jwtVerify(header.Alg, signingInput, signature, key); lastErr == nil {
+			return JWTVerifyResponse{Valid: true, Algorithm: header.Alg, KeyID: header.Kid}
+		}
+	}
+
+	return JWTVerifyResponse{
+		Valid:     false,
+		Algorithm: header.Alg,
+		KeyID:     header.Kid,
+		Error:     fmt.Sprintf("Signature verification failed: %v", lastErr),
+	}
+}
+
+// SignJWT signs the given claims and returns the compact token
+func (a *App) SignJWT(request JWTSignRequest) JSONFormatResponse {
+	alg := strings.TrimSpace(request.Algorithm)
+	if alg == "" {
+		return JSONFormatResponse{Error: "Algorithm is required"}
+	}
+
+	header := map[string]interface{}{}
+	if strings.TrimSpace(request.Header) != "" {
+		if err := json.Unmarshal([]byte(request.Header), &header); err != nil {
+			return JSONFormatResponse{Error: fmt.Sprintf("Invalid header JSON: %v", err)}
+		}
+	}
+	header["alg"] = alg
+	if _, ok := header["typ"]; !ok {
+		header["typ"] = "JWT"
+	}
+
+	// Compact the payload so whitespace from the editor doesn't end up in the token
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, []byte(request.Payload)); err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("Invalid payload JSON: %v", err)}
+	}
+
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return JSONFormatResponse{Error: fmt.Sprintf("Header marshal error: %v", err)}
+	}
+
+	var key interface{}
+	if strings.HasPrefix(alg, "HS") {
+		secret, err := jwtSecret(request.Secret, request.SecretBase64)
+		if err != nil {
+			return JSONFormatResponse{Error: err.Error()}
+		}
+		key = secret
+	} else {
+		key, err = parsePrivateKeyPEM([]byte(request.PrivateKey))
+		if err != nil {
+			return JSONFormatResponse{Error: err.Error()}
+		}
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerBytes) + "." + base64.RawURLEncoding.EncodeToString(payload.Bytes())
+	signature, err := jwtSign(alg, []byte(signingInput), key)
+	if err != nil {
+		return JSONFormatResponse{Error: err.Error()}
+	}
+
+	return JSONFormatResponse{
+		Result: signingInput + "." + base64.RawURLEncoding.EncodeToString(signature),
+	}
+}
+
+// splitJWT trims a token (including a "Bearer " prefix) and splits its segments
+func splitJWT(token string) ([]string, error) {
+	token = strings.TrimSpace(token)
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token == "" {
+		return nil, errors.New("Token is required")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 && len(parts) != 5 {
+		return nil, fmt.Errorf("Invalid token: expected 3 (JWS) or 5 (JWE) segments, got %d", len(parts))
+	}
+	return parts, nil
+}
+
+// indentJSONBytes pretty prints JSON, falling back to the raw text
+func indentJSONBytes(data []byte) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return out.String()
+}
+
+// jwtTimeClaims renders exp/iat/nbf and works out the validity status
+func jwtTimeClaims(claims map[string]interface{}, now time.Time) ([]JWTTimeClaim, string) {
+	var times []JWTTimeClaim
+	status := "no expiry"
+	hasExp := false
+
+	for _, name := range []string{"iat", "nbf", "exp"} {
+		number, ok := claims[name].(json.Number)
+		if !ok {
+			continue
+		}
+		seconds, err := number.Float64()
+		if err != nil {
+			continue
+		}
+		t := time.Unix(int64(seconds), 0)
+		times = append(times, JWTTimeClaim{
+			Claim:    name,
+			Unix:     int64(seconds),
+			Time:     t.Local().Format("2006-01-02 15:04:05 MST"),
+			Relative: relativeTime(t, now),
+		})
+
+		switch name {
+		case "exp":
+			hasExp = true
+			if !now.Before(t) {
+				return times, "expired"
+			}
+		case "nbf":
+			if now.Before(t) {
+				status = "not yet valid"
+			}
+		}
+	}
+
+	if status == "no expiry" && hasExp {
+		status = "valid"
+	}
+	return times, status
+}
+
+// relativeTime describes t relative to now, e.g. "in 5m0s" or "3 days ago"
+func relativeTime(t, now time.Time) string {
+	d := t.Sub(now).Round(time.Second)
+	future := d >= 0
+	if !future {
+		d = -d
+	}
+
+	text := d.String()
+	if d >= 48*time.Hour {
+		text = fmt.Sprintf("%d days", int(d.Hours()/24))
+	}
+	if future {
+		return "in " + text
+	}
+	return text + " ago"
+}
+
+// jwtSecret returns the HMAC key, optionally Base64 decoded
+func jwtSecret(secret string, isBase64 bool) ([]byte, error) {
+	if secret == "" {
+		return nil, errors.New("Secret is required for HMAC algorithms")
+	}
+	if !isBase64 {
+		return []byte(secret), nil
+	}
+	secret = strings.TrimSpace(secret)
+	if decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(secret, "=")); err == nil {
+		return decoded, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Base64 secret: %v", err)
+	}
+	return decoded, nil
+}
+
+// jwtVerificationKeys collects candidate keys from the request
+func (a *App) jwtVerificationKeys(request JWTVerifyRequest, kid string) ([]interface{}, error) {
+	switch {
+	case request.Secret != "":
+		secret, err := jwtSecret(request.Secret, request.SecretBase64)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{secret}, nil
+
+	case strings.TrimSpace(request.PublicKey) != "":
+		key, err := parsePublicKeyPEM([]byte(request.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{key}, nil
+
+	case request.JWKS != "" || request.JWKSPath != "":
+		data := []byte(request.JWKS)
+		if request.JWKSPath != "" {
+			absPath, err := a.resolveStoragePath(request.JWKSPath)
+			if err != nil {
+				return nil, err
+			}
+			data, err = os.ReadFile(absPath)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read JWKS file: %v", err)
+			}
+		}
+		return parseJWKS(data, kid)
+	}
+
+	return nil, errors.New("A secret, public key or JWKS is required")
+}
+
+// jwk is a single JSON Web Key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// parseJWKS returns the keys of a JWKS (or a single JWK) matching kid.
+// If kid is empty, or no key carries it, every key is returned.
+func parseJWKS(data []byte, kid string) ([]interface{}, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("Invalid JWKS: %v", err)
+	}
+	if len(set.Keys) == 0 {
+		var single jwk
+		if err := json.Unmarshal(data, &single); err == nil && single.Kty != "" {
+			set.Keys = []jwk{single}
+		}
+	}
+
+	var matched, all []interface{}
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("Invalid JWK %q: %v", k.Kid, err)
+		}
+		all = append(all, key)
+		if kid != "" && k.Kid == kid {
+			matched = append(matched, key)
+		}
+	}
+
+	if len(all) == 0 {
+		return nil, errors.New("JWKS contains no keys")
+	}
+	if len(matched) > 0 {
+		return matched, nil
+	}
+	return all, nil
+}
+
+// publicKey converts a JWK into a crypto key
+func (k jwk) publicKey() (interface{}, error) {
+	decode := func(s string) ([]byte, error) {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		return decode(k.K)
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// parsePublicKeyPEM accepts a public key, certificate or private key in PEM
+func parsePublicKeyPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(data))
+	if block == nil {
+		return nil, errors.New("Invalid PEM: no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+
+	private, err := parsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("Unsupported private key type")
+	}
+	return signer.Public(), nil
+}
+
+// parsePrivateKeyPEM parses PKCS#8, PKCS#1 and SEC 1 private keys
+func parsePrivateKeyPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(data))
+	if block == nil {
+		return nil, errors.New("Invalid PEM: no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("Unsupported PEM block type %q", block.Type)
+}
+
+// jwtHash maps the size suffix of an algorithm name to its hash
+func jwtHash(alg string) (crypto.Hash, error) {
+	if len(alg) < 5 {
+		return 0, fmt.Errorf("Unsupported algorithm %q", alg)
+	}
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, nil
+	case "384":
+		return crypto.SHA384, nil
+	case "512":
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("Unsupported algorithm %q", alg)
+}
+
+// digest hashes data with h
+func digest(h crypto.Hash, data []byte) []byte {
+	switch h {
+	case crypto.SHA384:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// jwtSign produces the raw JWS signature for alg
+func jwtSign(alg string, input []byte, key interface{}) ([]byte, error) {
+	if alg == "EdDSA" {
+		k, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("EdDSA requires an Ed25519 private key")
+		}
+		return ed25519.Sign(k, input), nil
+	}
+
+	h, err := jwtHash(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return nil, errors.New("HMAC algorithms require a secret")
+		}
+		mac := hmac.New(h.New, secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case "RS", "PS":
+		k, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an RSA private key", alg)
+		}
+		if alg[:2] == "RS" {
+			return rsa.SignPKCS1v15(rand.Reader, k, h, digest(h, input))
+		}
+		return rsa.SignPSS(rand.Reader, k, h, digest(h, input), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		k, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an EC private key", alg)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest(h, input))
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses the fixed-size R || S concatenation rather than ASN.1
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+	}
+	return nil, fmt.Errorf("Unsupported algorithm %q", alg)
+}
+
+// jwtVerify checks a raw JWS signature for alg
+func jwtVerify(alg string, input, signature []byte, key interface{}) error {
+	if alg == "EdDSA" {
+		k, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errors.New("EdDSA requires an Ed25519 public key")
+		}
+		if !ed25519.Verify(k, input, signature) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	}
+
+	h, err := jwtHash(alg)
+	if err != nil {
+		return err
+	}
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errors.New("HMAC algorithms require a secret")
+		}
+		mac := hmac.New(h.New, secret)
+		mac.Write(input)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	case "RS", "PS":
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an RSA public key", alg)
+		}
+		if alg[:2] == "RS" {
+			return rsa.VerifyPKCS1v15(k, h, digest(h, input), signature)
+		}
+		return rsa.VerifyPSS(k, h, digest(h, input), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+	case "ES":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an EC public key", alg)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature length %d for %s", len(signature), alg)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest(h, input), r, s) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	}
+	return fmt.Errorf("Unsupported algorithm %q", alg)
+}
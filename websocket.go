@@ -0,0 +1,670 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// ========== WebSocket Client ==========
+
+// webSocketEvent carries WebSocketLogEntry values to the frontend
+const webSocketEvent = "websocket:message"
+
+// maxWebSocketLog is the number of log entries kept per connection
+const maxWebSocketLog = 1000
+
+// webSocketCloseTimeout is how long CloseWebSocket waits for the server
+// to answer a close frame before dropping the connection
+const webSocketCloseTimeout = 5 * time.Second
+
+// WebSocketConnectRequest opens a connection. URL may use ws, wss, http
+// or https, and {{name}} placeholders in the URL, headers and messages
+// are resolved from Environment, the active environment if empty.
+type WebSocketConnectRequest struct {
+	// ID names the connection; one is generated if empty
+	ID           string            `json:"id,omitempty"`
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Subprotocols []string          `json:"subprotocols,omitempty"`
+	Environment  string            `json:"environment,omitempty"`
+	// Settings overrides the proxy, TLS and timeout settings of the HTTP
+	// tool for this connection
+	Settings *HTTPTransportSettings `json:"settings,omitempty"`
+}
+
+// WebSocketConnection describes an open or closed connection. Headers are
+// those of the server's handshake response.
+type WebSocketConnection struct {
+	ID          string            `json:"id"`
+	URL         string            `json:"url"`
+	Subprotocol string            `json:"subprotocol,omitempty"` // the one the server chose
+	Headers     map[string]string `json:"headers"`
+	Environment string            `json:"environment,omitempty"`
+	Connected   time.Time         `json:"connected"`
+	Open        bool              `json:"open"`
+}
+
+// WebSocketOutgoing is a frame to send. Type is "text" (the default),
+// "binary", "ping" or "close"; binary data is entered as Base64 or hex per
+// Encoding, and Data is the reason of a close frame.
+type WebSocketOutgoing struct {
+	Type     string `json:"type,omitempty"`
+	Data     string `json:"data"`
+	Encoding string `json:"encoding,omitempty"` // "base64" (default) or "hex"
+	Code     int    `json:"code,omitempty"`     // close code, 1000 if zero
+	// Delay waits this many milliseconds before sending, in scripts
+	Delay int `json:"delay,omitempty"`
+}
+
+// WebSocketLogEntry is a line of a connection's event log, pushed to the
+// frontend as it happens. Direction is "sent" or "received" for frames,
+// and empty for the "open", "closed" and "error" connection events. Type
+// is "text", "binary", "ping", "pong" or "close" for frames. Binary data
+// is Base64; a close frame has its code and its reason as Data.
+type WebSocketLogEntry struct {
+	ConnectionID string    `json:"connectionId"`
+	Seq          int       `json:"seq"`
+	Time         time.Time `json:"time"`
+	Direction    string    `json:"direction,omitempty"`
+	Type         string    `json:"type"`
+	Data         string    `json:"data,omitempty"`
+	Size         int       `json:"size"`
+	Code         int       `json:"code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// WebSocketScript is a saved series of messages to replay. It keeps the
+// connection settings too, so a replay can open its own connection.
+type WebSocketScript struct {
+	URL          string              `json:"url,omitempty"`
+	Headers      map[string]string   `json:"headers,omitempty"`
+	Subprotocols []string            `json:"subprotocols,omitempty"`
+	Messages     []WebSocketOutgoing `json:"messages"`
+}
+
+// webSocketConn is a connection and its log
+type webSocketConn struct {
+	app  *App
+	info WebSocketConnection
+	conn *websocket.Conn
+	// request is the connection as asked for, before its variables were
+	// resolved, so saved scripts keep the {{name}} placeholders
+	request WebSocketConnectRequest
+
+	writeMu sync.Mutex // serializes data frames
+
+	mu        sync.Mutex // guards the fields below
+	log       []WebSocketLogEntry
+	seq       int
+	closeSent bool
+	// sent keeps the messages sent as they were entered, with the pause
+	// before each as its Delay
+	sent     []WebSocketOutgoing
+	lastSent time.Time
+
+	done chan struct{} // closed when the connection ends
+}
+
+// ConnectWebSocket opens a connection and starts logging its messages
+func (a *App) ConnectWebSocket(request WebSocketConnectRequest) FileSystemResponse {
+	resolver, err := a.newVariableResolver(request.Environment, "")
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: fmt.Sprintf("Environment error: %v", err)}
+	}
+	c, err := a.openWebSocket(request, resolver)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: c.connection()}
+}
+
+// openWebSocket resolves a request's variables, performs the handshake
+// with the HTTP tool's proxy, TLS settings and cookies, and registers the
+// connection
+func (a *App) openWebSocket(request WebSocketConnectRequest, resolver *variableResolver) (*webSocketConn, error) {
+	if request.ID == "" {
+		request.ID = uuid.NewString()
+	}
+	a.webSocketMu.Lock()
+	_, exists := a.webSockets[request.ID]
+	a.webSocketMu.Unlock()
+	if exists {
+		return nil, fmt.Errorf("Connection %q already exists", request.ID)
+	}
+
+	rawURL, err := resolver.resolve(strings.TrimSpace(request.URL))
+	if err != nil {
+		return nil, fmt.Errorf("Variable error: %v", err)
+	}
+	switch {
+	case rawURL == "":
+		return nil, errors.New("URL is required")
+	case strings.HasPrefix(rawURL, "http://"):
+		rawURL = "ws://" + strings.TrimPrefix(rawURL, "http://")
+	case strings.HasPrefix(rawURL, "https://"):
+		rawURL = "wss://" + strings.TrimPrefix(rawURL, "https://")
+	}
+
+	header := http.Header{}
+	subprotocols := append([]string{}, request.Subprotocols...)
+	for key, value := range request.Headers {
+		if value, err = resolver.resolve(value); err != nil {
+			return nil, fmt.Errorf("Variable error: %v", err)
+		}
+		// The dialer sets this header from the subprotocols
+		if strings.EqualFold(key, "Sec-WebSocket-Protocol") {
+			for _, protocol := range strings.Split(value, ",") {
+				subprotocols = append(subprotocols, strings.TrimSpace(protocol))
+			}
+			continue
+		}
+		header.Set(key, value)
+	}
+
+	// The handshake is HTTP/1.1 only, whatever HTTPVersion says
+	settings := a.loadConfig().HTTPSettings.merge(request.Settings)
+	settings.HTTPVersion = ""
+	transport, err := newHTTPTransport(settings)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid transport settings: %v", err)
+	}
+	dialer := &websocket.Dialer{
+		Proxy:             transport.Proxy,
+		NetDialContext:    transport.DialContext,
+		TLSClientConfig:   transport.TLSClientConfig,
+		HandshakeTimeout:  settings.readTimeout(),
+		Subprotocols:      subprotocols,
+		Jar:               a.cookieJar(resolver.environment),
+		EnableCompression: true,
+	}
+
+	conn, resp, err := dialer.Dial(rawURL, header)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+			return nil, fmt.Errorf("Handshake failed: the server answered %s", resp.Status)
+		}
+		return nil, fmt.Errorf("Failed to connect: %v", err)
+	}
+
+	headers := make(map[string]string)
+	for key, values := range resp.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+	c := &webSocketConn{
+		app:     a,
+		conn:    conn,
+		request: request,
+		info: WebSocketConnection{
+			ID:          request.ID,
+			URL:         rawURL,
+			Subprotocol: conn.Subprotocol(),
+			Headers:     headers,
+			Environment: resolver.environment,
+			Connected:   time.Now(),
+			Open:        true,
+		},
+		done: make(chan struct{}),
+	}
+	conn.SetPingHandler(c.handlePing)
+	conn.SetPongHandler(c.handlePong)
+	conn.SetCloseHandler(c.handleClose)
+
+	a.webSocketMu.Lock()
+	if _, exists := a.webSockets[request.ID]; exists {
+		a.webSocketMu.Unlock()
+		conn.Close()
+		return nil, fmt.Errorf("Connection %q already exists", request.ID)
+	}
+	if a.webSockets == nil {
+		a.webSockets = make(map[string]*webSocketConn)
+	}
+	a.webSockets[request.ID] = c
+	a.webSocketMu.Unlock()
+
+	c.record(WebSocketLogEntry{Type: "open", Data: rawURL})
+	go c.readLoop()
+	return c, nil
+}
+
+// SendWebSocketMessage sends a text, binary, ping or close frame. Text
+// and ping data may use {{name}} placeholders.
+func (a *App) SendWebSocketMessage(id string, message WebSocketOutgoing) FileSystemResponse {
+	c, err := a.webSocket(id)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	if err := c.send(message); err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// PingWebSocket sends a ping; the server's pong appears in the log
+func (a *App) PingWebSocket(id string, data string) FileSystemResponse {
+	return a.SendWebSocketMessage(id, WebSocketOutgoing{Type: "ping", Data: data})
+}
+
+// CloseWebSocket sends a close frame with the code, 1000 if zero, and
+// waits for the server to close the connection, dropping it otherwise
+func (a *App) CloseWebSocket(id string, code int, reason string) FileSystemResponse {
+	c, err := a.webSocket(id)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	if err := c.send(WebSocketOutgoing{Type: "close", Code: code, Data: reason}); err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	select {
+	case <-c.done:
+	case <-time.After(webSocketCloseTimeout):
+		c.conn.Close()
+		<-c.done
+	}
+	return FileSystemResponse{Success: true}
+}
+
+// ListWebSocketConnections returns the connections, open or closed, by
+// connection time
+func (a *App) ListWebSocketConnections() []WebSocketConnection {
+	a.webSocketMu.Lock()
+	defer a.webSocketMu.Unlock()
+
+	connections := make([]WebSocketConnection, 0, len(a.webSockets))
+	for _, c := range a.webSockets {
+		connections = append(connections, c.connection())
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].Connected.Before(connections[j].Connected)
+	})
+	return connections
+}
+
+// GetWebSocketLog returns the logged events of a connection, the oldest
+// first, up to maxWebSocketLog
+func (a *App) GetWebSocketLog(id string) FileSystemResponse {
+	c, err := a.webSocket(id)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return FileSystemResponse{Success: true, Data: append([]WebSocketLogEntry{}, c.log...)}
+}
+
+// RemoveWebSocketConnection drops a connection and its log, closing it
+// first if it is open
+func (a *App) RemoveWebSocketConnection(id string) FileSystemResponse {
+	c, err := a.webSocket(id)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	if c.connection().Open {
+		a.CloseWebSocket(id, websocket.CloseGoingAway, "")
+	}
+	a.webSocketMu.Lock()
+	delete(a.webSockets, id)
+	a.webSocketMu.Unlock()
+	return FileSystemResponse{Success: true}
+}
+
+// webSocket finds a connection by ID
+func (a *App) webSocket(id string) (*webSocketConn, error) {
+	a.webSocketMu.Lock()
+	defer a.webSocketMu.Unlock()
+	c, ok := a.webSockets[id]
+	if !ok {
+		return nil, fmt.Errorf("Connection %q does not exist", id)
+	}
+	return c, nil
+}
+
+// connection returns the current state of the connection
+func (c *webSocketConn) connection() WebSocketConnection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.info
+}
+
+// record adds an entry to the log and pushes it to the frontend
+func (c *webSocketConn) record(entry WebSocketLogEntry) {
+	c.mu.Lock()
+	c.seq++
+	entry.ConnectionID = c.info.ID
+	entry.Seq = c.seq
+	entry.Time = time.Now()
+	c.log = append(c.log, entry)
+	if len(c.log) > maxWebSocketLog {
+		c.log = c.log[len(c.log)-maxWebSocketLog:]
+	}
+	c.mu.Unlock()
+	c.app.emitEvent(webSocketEvent, entry)
+}
+
+// frameEntry describes a data or control frame for the log
+func frameEntry(direction string, kind string, data []byte) WebSocketLogEntry {
+	entry := WebSocketLogEntry{Direction: direction, Type: kind, Size: len(data)}
+	if kind == "binary" || !utf8.Valid(data) {
+		entry.Data = base64.StdEncoding.EncodeToString(data)
+	} else {
+		entry.Data = string(data)
+	}
+	return entry
+}
+
+// send resolves and writes a frame
+func (c *webSocketConn) send(message WebSocketOutgoing) error {
+	if !c.connection().Open {
+		return fmt.Errorf("Connection %q is closed", c.info.ID)
+	}
+
+	kind := strings.ToLower(message.Type)
+	var data []byte
+	switch kind {
+	case "", "text", "ping", "close":
+		resolver, err := c.app.newVariableResolver(c.info.Environment, "")
+		if err != nil {
+			return fmt.Errorf("Environment error: %v", err)
+		}
+		text, err := resolver.resolve(message.Data)
+		if err != nil {
+			return fmt.Errorf("Variable error: %v", err)
+		}
+		data = []byte(text)
+	case "binary":
+		var err error
+		if data, err = decodeBinaryText(message.Data, message.Encoding); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown message type %q", message.Type)
+	}
+
+	var err error
+	entry := frameEntry("sent", kind, data)
+	switch kind {
+	case "", "text":
+		entry.Type = "text"
+		err = c.write(websocket.TextMessage, data)
+	case "binary":
+		err = c.write(websocket.BinaryMessage, data)
+	case "ping":
+		if len(data) > 125 {
+			return errors.New("Ping data is limited to 125 bytes")
+		}
+		err = c.conn.WriteControl(websocket.PingMessage, data, time.Now().Add(webSocketCloseTimeout))
+	case "close":
+		code := message.Code
+		if code == 0 {
+			code = websocket.CloseNormalClosure
+		}
+		if !validCloseCode(code) {
+			return fmt.Errorf("Close code %d cannot be sent", code)
+		}
+		if len(data) > 123 {
+			return errors.New("The close reason is limited to 123 bytes")
+		}
+		c.mu.Lock()
+		c.closeSent = true
+		c.mu.Unlock()
+		entry.Code = code
+		err = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, string(data)), time.Now().Add(webSocketCloseTimeout))
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to send: %v", err)
+	}
+	c.record(entry)
+
+	now := time.Now()
+	message.Type, message.Code, message.Delay = entry.Type, entry.Code, 0
+	c.mu.Lock()
+	if !c.lastSent.IsZero() {
+		message.Delay = int(now.Sub(c.lastSent).Milliseconds())
+	}
+	c.lastSent = now
+	c.sent = append(c.sent, message)
+	if len(c.sent) > maxWebSocketLog {
+		c.sent = c.sent[len(c.sent)-maxWebSocketLog:]
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// write sends a data frame; only one may be written at a time
+func (c *webSocketConn) write(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// validCloseCode reports whether a close code may be sent, per RFC 6455
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code >= 1000 && code <= 1014:
+		return code != 1004 && code != websocket.CloseNoStatusReceived && code != websocket.CloseAbnormalClosure
+	}
+	return false
+}
+
+// handlePing logs a ping and answers it
+func (c *webSocketConn) handlePing(data string) error {
+	c.record(frameEntry("received", "ping", []byte(data)))
+	err := c.conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(webSocketCloseTimeout))
+	if err == nil {
+		c.record(frameEntry("sent", "pong", []byte(data)))
+	}
+	return nil
+}
+
+// handlePong logs a pong
+func (c *webSocketConn) handlePong(data string) error {
+	c.record(frameEntry("received", "pong", []byte(data)))
+	return nil
+}
+
+// handleClose logs the server's close frame and, unless the client
+// started the closing handshake, answers it with the same code
+func (c *webSocketConn) handleClose(code int, text string) error {
+	c.record(WebSocketLogEntry{Direction: "received", Type: "close", Code: code, Data: text, Size: len(text)})
+
+	c.mu.Lock()
+	answer := !c.closeSent
+	c.closeSent = true
+	c.mu.Unlock()
+	if answer {
+		reply := []byte{}
+		if code != websocket.CloseNoStatusReceived {
+			reply = websocket.FormatCloseMessage(code, "")
+		}
+		if c.conn.WriteControl(websocket.CloseMessage, reply, time.Now().Add(webSocketCloseTimeout)) == nil {
+			c.record(WebSocketLogEntry{Direction: "sent", Type: "close", Code: code})
+		}
+	}
+	return nil
+}
+
+// readLoop logs received messages until the connection ends
+func (c *webSocketConn) readLoop() {
+	defer close(c.done)
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.mu.Lock()
+			closing := c.closeSent
+			c.mu.Unlock()
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) && !closing {
+				c.record(WebSocketLogEntry{Type: "error", Error: err.Error()})
+			}
+			break
+		}
+		kind := "text"
+		if messageType == websocket.BinaryMessage {
+			kind = "binary"
+		}
+		c.record(frameEntry("received", kind, data))
+	}
+
+	c.conn.Close()
+	c.mu.Lock()
+	c.info.Open = false
+	c.mu.Unlock()
+	c.record(WebSocketLogEntry{Type: "closed"})
+}
+
+// ========== WebSocket Scripts ==========
+
+// SaveWebSocketScript writes a script as a JSON file in parentPath, or in
+// the websocket folder if parentPath is empty, without overwriting
+func (a *App) SaveWebSocketScript(parentPath string, fileName string, script WebSocketScript) FileSystemResponse {
+	item, err := a.createWebSocketScript(parentPath, fileName, script)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: item}
+}
+
+// SaveWebSocketLogScript saves the messages sent on a connection as a
+// script, with the pauses between them as delays. The URL, headers and
+// messages are saved as they were entered, so {{name}} placeholders are
+// kept rather than the values they resolved to.
+func (a *App) SaveWebSocketLogScript(id string, parentPath string, fileName string) FileSystemResponse {
+	c, err := a.webSocket(id)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+
+	script := WebSocketScript{
+		URL:          strings.TrimSpace(c.request.URL),
+		Headers:      c.request.Headers,
+		Subprotocols: c.request.Subprotocols,
+	}
+	c.mu.Lock()
+	script.Messages = append([]WebSocketOutgoing{}, c.sent...)
+	c.mu.Unlock()
+
+	if fileName == "" {
+		fileName = "session"
+	}
+	item, err := a.createWebSocketScript(parentPath, fileName, script)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: item}
+}
+
+// LoadWebSocketScript reads a script from storage
+func (a *App) LoadWebSocketScript(filePath string) FileSystemResponse {
+	script, err := a.loadWebSocketScript(filePath)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+	return FileSystemResponse{Success: true, Data: script}
+}
+
+// ReplayWebSocketScript sends the messages of a script in the background,
+// waiting each one's delay first. With an empty id a connection is opened
+// from the script's settings and the environment; otherwise the messages
+// use the connection's environment. Data is the connection;
+// the messages, and an error that stops the replay, appear in its log.
+func (a *App) ReplayWebSocketScript(filePath string, id string, environment string) FileSystemResponse {
+	script, err := a.loadWebSocketScript(filePath)
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+
+	var c *webSocketConn
+	if id != "" {
+		c, err = a.webSocket(id)
+	} else {
+		var resolver *variableResolver
+		if resolver, err = a.newVariableResolver(environment, ""); err != nil {
+			return FileSystemResponse{Success: false, Error: fmt.Sprintf("Environment error: %v", err)}
+		}
+		c, err = a.openWebSocket(WebSocketConnectRequest{
+			URL:          script.URL,
+			Headers:      script.Headers,
+			Subprotocols: script.Subprotocols,
+		}, resolver)
+	}
+	if err != nil {
+		return FileSystemResponse{Success: false, Error: err.Error()}
+	}
+
+	go func() {
+		for _, message := range script.Messages {
+			if message.Delay > 0 {
+				select {
+				case <-time.After(time.Duration(message.Delay) * time.Millisecond):
+				case <-c.done:
+				}
+			}
+			if err := c.send(message); err != nil {
+				c.record(WebSocketLogEntry{Type: "error", Error: fmt.Sprintf("Replay stopped: %v", err)})
+				return
+			}
+		}
+	}()
+	return FileSystemResponse{Success: true, Data: c.connection()}
+}
+
+// loadWebSocketScript reads and parses a script file
+func (a *App) loadWebSocketScript(filePath string) (WebSocketScript, error) {
+	var script WebSocketScript
+	absPath, err := a.resolveStoragePath(filePath)
+	if err != nil {
+		return script, err
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return script, fmt.Errorf("Failed to read script: %v", err)
+	}
+	if err := json.Unmarshal(data, &script); err != nil {
+		return script, fmt.Errorf("Invalid script: %v", err)
+	}
+	return script, nil
+}
+
+// createWebSocketScript writes a script under a name that is not taken
+func (a *App) createWebSocketScript(parentPath string, fileName string, script WebSocketScript) (FileItem, error) {
+	if parentPath == "" {
+		parentPath = filepath.Join(a.storagePath, "websocket")
+	}
+	dirPath, err := a.resolveStoragePath(parentPath)
+	if err != nil {
+		return FileItem{}, err
+	}
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return FileItem{}, fmt.Errorf("Failed to create directory: %v", err)
+	}
+
+	fileName = safeFileName(strings.TrimSuffix(filepath.Base(fileName), ".json"))
+	if fileName == "" {
+		fileName = "script"
+	}
+	data, err := json.MarshalIndent(script, "", "  ")
+	if err != nil {
+		return FileItem{}, fmt.Errorf("Failed to encode script: %v", err)
+	}
+	filePath := uniquePath(filepath.Join(dirPath, fileName+".json"))
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return FileItem{}, fmt.Errorf("Failed to write file: %v", err)
+	}
+
+	relPath, _ := filepath.Rel(a.storagePath, filePath)
+	return FileItem{ID: relPath, Name: filepath.Base(filePath), Type: "file", Path: filePath}, nil
+}